@@ -32,6 +32,10 @@ func main() {
 
 	defer app.DB.SQL.Close()
 
+	// periodically check servers against the providers for orphans and drift
+	go handlerRepo.ReconcileRoutine(app.ReconcileInterval)
+	go handlerRepo.ExpiryRoutine(time.Minute)
+
 	// create http server
 	srv := &http.Server{
 		Addr:              "0.0.0.0:" + port,
@@ -31,6 +31,8 @@ func routes() http.Handler {
 		mux.Get("/home", handlers.Repo.Home)
 		mux.Get("/account", handlers.Repo.Account)
 		mux.Post("/account", handlers.Repo.EditAccount)
+		mux.Get("/certificates", handlers.Repo.Certificates)
+		mux.Post("/certificates", handlers.Repo.IssueCertificate)
 
 		// Projects
 		mux.Get("/projects", handlers.Repo.Projects)
@@ -39,14 +41,30 @@ func routes() http.Handler {
 		mux.Post("/projects/add", handlers.Repo.AddProjectsPost)
 		mux.Post("/projects/update", handlers.Repo.UpdateProjectsPost)
 		mux.Get("/projects/remove/{id}", handlers.Repo.RemoveProject)
+		mux.Get("/projects/{id}/infrastructure", handlers.Repo.ProjectInfrastructure)
+		mux.Post("/projects/{id}/infrastructure", handlers.Repo.ProjectInfrastructurePost)
+		mux.Get("/projects/{id}/plan", handlers.Repo.ProjectPlan)
+		mux.Post("/projects/{id}/apply", handlers.Repo.ProjectApply)
 
 		// Server routes
 		mux.Get("/servers", handlers.Repo.Servers)
 		mux.Get("/servers/add", handlers.Repo.ServersAdd)
 		mux.Post("/servers/add", handlers.Repo.ServersAddPost)
+		mux.Get("/servers/bulk", handlers.Repo.ServersBulk)
+		mux.Post("/servers/bulk", handlers.Repo.ServersBulkPost)
+		mux.Get("/servers/bulk/{id}", handlers.Repo.ViewBulkDeployment)
+		mux.Get("/servers/catalog/{provider}", handlers.Repo.ServersCatalog)
 		mux.Get("/servers/remove/{id}", handlers.Repo.ServersRemove)
 		mux.Get("/servers/removeall", handlers.Repo.DeleteAllServers)
 		mux.Get("/servers/provision/{id}", handlers.Repo.ProvisionServer)
+		mux.Post("/servers/power/{id}", handlers.Repo.ServerPower)
+		mux.Post("/servers/snapshot/{id}", handlers.Repo.ServerSnapshot)
+		mux.Post("/servers/rebuild/{id}", handlers.Repo.ServerRebuild)
+		mux.Post("/servers/expiry/{id}", handlers.Repo.ServerExpiry)
+		mux.Post("/servers/hostkey/{id}", handlers.Repo.RepinServerHostKey)
+		mux.Post("/servers/credentials/{id}", handlers.Repo.RotateServerCredentials)
+		mux.Post("/servers/ip/{id}", handlers.Repo.ServerIP)
+		mux.Post("/servers/firewall/{id}", handlers.Repo.ServerFirewall)
 		mux.Get("/servers/{id}", handlers.Repo.ViewServer)
 		mux.Post("/servers/update/{id}", handlers.Repo.UpdateServer)
 
@@ -58,6 +76,23 @@ func routes() http.Handler {
 		mux.Post("/scripts/update/{id}", handlers.Repo.UpdateScript)
 		mux.Get("/scripts/remove/{id}", handlers.Repo.RemoveScript)
 
+		// Blueprint routes
+		mux.Get("/blueprints", handlers.Repo.Blueprints)
+		mux.Get("/blueprints/add", handlers.Repo.BlueprintsAdd)
+		mux.Post("/blueprints/add", handlers.Repo.BlueprintsAddPost)
+		mux.Get("/blueprints/{id}", handlers.Repo.ViewBlueprint)
+		mux.Post("/blueprints/update/{id}", handlers.Repo.UpdateBlueprint)
+		mux.Get("/blueprints/remove/{id}", handlers.Repo.RemoveBlueprint)
+		mux.Post("/blueprints/instantiate/{id}", handlers.Repo.InstantiateBlueprint)
+
+		// Cloud-init routes
+		mux.Get("/cloudinit", handlers.Repo.CloudInit)
+		mux.Get("/cloudinit/add", handlers.Repo.CloudInitAdd)
+		mux.Post("/cloudinit/add", handlers.Repo.CloudInitAddPost)
+		mux.Get("/cloudinit/{id}", handlers.Repo.ViewCloudInit)
+		mux.Post("/cloudinit/update/{id}", handlers.Repo.UpdateCloudInit)
+		mux.Get("/cloudinit/remove/{id}", handlers.Repo.RemoveCloudInit)
+
 		// Domain routes
 		mux.Get("/domains", handlers.Repo.Domains)
 		mux.Get("/domains/add", handlers.Repo.DomainsAdd)
@@ -82,6 +117,15 @@ func routes() http.Handler {
 			mux.Get("/settings", handlers.Repo.Settings)
 			mux.Get("/settings/update-ssh", handlers.Repo.UpdateSSH)
 			mux.Post("/settings", handlers.Repo.SettingsEdit)
+			mux.Get("/reconcile", handlers.Repo.Reconcile)
+
+			// Root credential routes
+			mux.Get("/credentials", handlers.Repo.Credentials)
+			mux.Post("/credentials/rotate", handlers.Repo.RotateAllCredentials)
+
+			// Server import routes
+			mux.Get("/servers/import", handlers.Repo.ServersImport)
+			mux.Post("/servers/import", handlers.Repo.ServersImportPost)
 
 			// User routes
 			mux.Get("/users", handlers.Repo.Users)
@@ -8,7 +8,6 @@ import (
 	"encoding/pem"
 	"fmt"
 	"log"
-	random "math/rand"
 	"net/http"
 	"os"
 	"time"
@@ -20,6 +19,7 @@ import (
 	"github.com/alexedwards/scs/v2"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/nickzer0/GoBoxer/internal/config"
+	"github.com/nickzer0/GoBoxer/internal/credentials"
 	"github.com/nickzer0/GoBoxer/internal/deploy"
 	"github.com/nickzer0/GoBoxer/internal/domains"
 	"github.com/nickzer0/GoBoxer/internal/driver"
@@ -28,6 +28,7 @@ import (
 	"github.com/nickzer0/GoBoxer/internal/models"
 	"github.com/nickzer0/GoBoxer/internal/redirectors"
 	"github.com/nickzer0/GoBoxer/internal/server"
+	"github.com/nickzer0/GoBoxer/internal/sshca"
 	"github.com/spf13/viper"
 	"golang.org/x/net/websocket"
 )
@@ -87,6 +88,12 @@ func setup() (string, error) {
 		log.Fatalf("ansibleDebug is not set in the configuration")
 	}
 
+	// reconcileInterval is optional so existing config files keep working
+	reconcileInterval := 15 * time.Minute
+	if viper.IsSet("reconcileInterval") {
+		reconcileInterval = viper.GetDuration("reconcileInterval")
+	}
+
 	db, err := driver.ConnectSQL(dbDataFile)
 	if err != nil {
 		log.Fatal("could not initialize database:", err)
@@ -103,12 +110,13 @@ func setup() (string, error) {
 
 	// Configure app config
 	a := config.AppConfig{
-		DB:           db,
-		Session:      session,
-		InProduction: inProduction,
-		AnsibleDebug: ansibleDebug,
-		Domain:       domain,
-		Version:      goBoxerVersion,
+		DB:                db,
+		Session:           session,
+		InProduction:      inProduction,
+		AnsibleDebug:      ansibleDebug,
+		Domain:            domain,
+		Version:           goBoxerVersion,
+		ReconcileInterval: reconcileInterval,
 	}
 
 	app = a
@@ -151,7 +159,11 @@ func setup() (string, error) {
 		return httpPort, err
 	}
 
-	if err = CheckRootPassword(); err != nil {
+	if app.CredentialKey, err = credentials.LoadKey("credentials.key"); err != nil {
+		return httpPort, err
+	}
+
+	if err = CheckSSHCA(); err != nil {
 		return httpPort, err
 	}
 
@@ -219,29 +231,31 @@ func CheckSSHConfig() error {
 	return nil
 }
 
-// CheckRootPassword generates and updates root password in config.yaml
-func CheckRootPassword() error {
-	rootPassword, err := handlerRepo.DB.GetSecret("root_password")
+// CheckSSHCA generates the SSH certificate authority operators' certificates are signed with, if there is none.
+// Its private key is stored encrypted with the credential key.
+func CheckSSHCA() error {
+	publicKey, err := handlerRepo.DB.GetSecret("ssh_ca_public_key")
 	if err != nil && err != sql.ErrNoRows {
-		return fmt.Errorf("failed to fetch root password: %v", err)
+		return fmt.Errorf("failed to fetch SSH CA public key: %v", err)
+	}
+	if publicKey != "" {
+		return nil
 	}
 
-	if rootPassword == "" {
-		log.Println("No default Root password configured. Generating new one.")
-
-		const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-		randomString := make([]byte, 16)
-		// Generate random string based on charset
-		for i := range randomString {
-			randomString[i] = charset[random.Intn(len(charset))]
-		}
+	log.Println("No SSH CA detected. Generating a new one.")
+	ca, err := sshca.Generate()
+	if err != nil {
+		return err
+	}
+	privateKey, err := credentials.Encrypt(app.CredentialKey, ca.PrivateKey)
+	if err != nil {
+		return err
+	}
 
-		// Update root password in secrets table
-		if err := handlerRepo.DB.UpdateSecret("root_password", string(randomString)); err != nil {
-			return fmt.Errorf("failed to add root password: %v", err)
-		}
+	if err = handlerRepo.DB.UpdateSecret("ssh_ca_private_key", privateKey); err != nil {
+		return err
 	}
-	return nil
+	return handlerRepo.DB.UpdateSecret("ssh_ca_public_key", ca.PublicKey)
 }
 
 // AddDefaultAdminUser adds default admin user to database
@@ -260,10 +274,9 @@ func CheckAdminUserExists() error {
 	username := "admin"
 
 	// Generate random password
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	password := make([]byte, 16)
-	for i := range password {
-		password[i] = charset[random.Intn(len(charset))]
+	password, err := credentials.GeneratePassword()
+	if err != nil {
+		return err
 	}
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 12)
 	if err != nil {
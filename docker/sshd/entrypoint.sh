@@ -0,0 +1,11 @@
+#!/bin/sh
+set -e
+
+# GoBoxer passes the root SSH key in the environment when creating the container
+if [ -n "$SSH_PUBLIC_KEY" ]; then
+    echo "$SSH_PUBLIC_KEY" > /root/.ssh/authorized_keys
+    chmod 600 /root/.ssh/authorized_keys
+fi
+
+ssh-keygen -A
+exec /usr/sbin/sshd -D -e
@@ -0,0 +1,254 @@
+package blueprint
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nickzer0/GoBoxer/internal/server"
+	"gopkg.in/yaml.v3"
+)
+
+// maxCount is the most copies of a single server a blueprint can ask for
+const maxCount = 20
+
+// defaultTTL is the TTL of blueprint DNS records that do not set one
+const defaultTTL = 300
+
+// namePattern matches the names servers, records and redirectors are referred to by
+var namePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// Example is shown when a new blueprint is created
+const Example = `# Servers are created first, then DNS records pointing at them, then
+# redirectors fronting those records, then records pointing at redirectors.
+servers:
+  - name: teamserver
+    hostname: "{project}-ts"
+    provider: digitalocean
+    region: lon1
+    size: s-2vcpu-4gb
+    roles: [hardening]
+    cloud_init: baseline
+  - name: redir
+    count: 2
+    provider: linode
+    region: eu-west
+    size: g6-nanode-1
+    roles: [redirector]
+    firewall: web
+
+domain: example.com
+records:
+  - name: cdn-origin
+    server: redir-1
+  - name: mail
+    server: redir-2
+  - name: www
+    redirector: cdn
+
+redirectors:
+  - name: cdn
+    origin: cdn-origin
+`
+
+// Spec is a stack of servers, DNS records and redirectors to build in a project
+type Spec struct {
+	Servers     []Server     `yaml:"servers"`
+	Domain      string       `yaml:"domain"`
+	Records     []Record     `yaml:"records"`
+	Redirectors []Redirector `yaml:"redirectors"`
+}
+
+// Server is one or more identical servers. Copies are referred to as name-1, name-2 and so on.
+type Server struct {
+	Name     string   `yaml:"name"`
+	Hostname string   `yaml:"hostname"`
+	Count    int      `yaml:"count"`
+	Provider string   `yaml:"provider"`
+	Region   string   `yaml:"region"`
+	Size     string   `yaml:"size"`
+	Image    string   `yaml:"image"`
+	Roles    []string `yaml:"roles"`
+	// Firewall is the name of a firewall policy, empty for the project default or "none"
+	Firewall string `yaml:"firewall"`
+	// CloudInit is the name of a cloud-init template passed as user data, empty for none
+	CloudInit string `yaml:"cloud_init"`
+}
+
+// Record is a DNS record on the blueprint's domain pointing at a server, a redirector or fixed data
+type Record struct {
+	Name       string `yaml:"name"`
+	Type       string `yaml:"type"`
+	TTL        int    `yaml:"ttl"`
+	Server     string `yaml:"server"`
+	Redirector string `yaml:"redirector"`
+	Data       string `yaml:"data"`
+}
+
+// Redirector is a CloudFront distribution forwarding to one of the blueprint's records
+type Redirector struct {
+	Name   string `yaml:"name"`
+	Origin string `yaml:"origin"`
+}
+
+// Instance is a single server to create from a blueprint
+type Instance struct {
+	Key      string
+	Hostname string
+	Server
+}
+
+// Parse reads and validates a blueprint
+func Parse(spec string) (Spec, error) {
+	var parsed Spec
+
+	decoder := yaml.NewDecoder(bytes.NewBufferString(spec))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&parsed); err != nil {
+		return parsed, fmt.Errorf("error reading blueprint: %v", err)
+	}
+
+	return parsed, parsed.validate()
+}
+
+// Instances returns every server the blueprint creates, with hostnames for the given project
+func (s Spec) Instances(project int) []Instance {
+	var instances []Instance
+	for _, srv := range s.Servers {
+		hostname := srv.Hostname
+		if hostname == "" {
+			hostname = srv.Name
+		}
+		hostname = strings.ReplaceAll(hostname, "{project}", fmt.Sprint(project))
+
+		if srv.Count <= 1 {
+			instances = append(instances, Instance{Key: srv.Name, Hostname: strings.ReplaceAll(hostname, "{n}", "1"), Server: srv})
+			continue
+		}
+		if !strings.Contains(hostname, "{n}") {
+			hostname += "-{n}"
+		}
+		for n := 1; n <= srv.Count; n++ {
+			instances = append(instances, Instance{
+				Key:      fmt.Sprintf("%s-%d", srv.Name, n),
+				Hostname: strings.ReplaceAll(hostname, "{n}", fmt.Sprint(n)),
+				Server:   srv,
+			})
+		}
+	}
+	return instances
+}
+
+// RecordName returns the fully qualified name of a record on the blueprint's domain
+func (s Spec) RecordName(record Record) string {
+	if record.Name == "@" {
+		return s.Domain
+	}
+	return record.Name + "." + s.Domain
+}
+
+// Record returns the record with the given name
+func (s Spec) Record(name string) (Record, bool) {
+	for _, record := range s.Records {
+		if record.Name == name {
+			return record, true
+		}
+	}
+	return Record{}, false
+}
+
+// validate checks the blueprint is complete and that every reference in it resolves
+func (s *Spec) validate() error {
+	servers := make(map[string]bool)
+	for i := range s.Servers {
+		srv := &s.Servers[i]
+		if !namePattern.MatchString(srv.Name) {
+			return fmt.Errorf("server %q: names must be lowercase letters, numbers and hyphens", srv.Name)
+		}
+		if _, err := server.GetProvider(srv.Provider); err != nil {
+			return fmt.Errorf("server %s: unknown provider %q", srv.Name, srv.Provider)
+		}
+		if srv.Region == "" || srv.Size == "" {
+			return fmt.Errorf("server %s: region and size are required", srv.Name)
+		}
+		if srv.Count < 0 || srv.Count > maxCount {
+			return fmt.Errorf("server %s: count must be between 1 and %d", srv.Name, maxCount)
+		}
+		if srv.Count == 0 {
+			srv.Count = 1
+		}
+	}
+	for _, instance := range s.Instances(0) {
+		if servers[instance.Key] {
+			return fmt.Errorf("server %s is defined more than once", instance.Key)
+		}
+		servers[instance.Key] = true
+	}
+
+	redirectors := make(map[string]bool)
+	for _, redirector := range s.Redirectors {
+		if !namePattern.MatchString(redirector.Name) {
+			return fmt.Errorf("redirector %q: names must be lowercase letters, numbers and hyphens", redirector.Name)
+		}
+		if redirectors[redirector.Name] {
+			return fmt.Errorf("redirector %s is defined more than once", redirector.Name)
+		}
+		redirectors[redirector.Name] = true
+	}
+
+	if len(s.Records) > 0 && s.Domain == "" {
+		return fmt.Errorf("a domain is required for DNS records")
+	}
+	records := make(map[string]bool)
+	for i := range s.Records {
+		record := &s.Records[i]
+		if record.Name == "" {
+			return fmt.Errorf("DNS records need a name, use @ for the domain itself")
+		}
+		if records[record.Name] {
+			return fmt.Errorf("record %s is defined more than once", record.Name)
+		}
+		records[record.Name] = true
+
+		targets := 0
+		for _, target := range []string{record.Server, record.Redirector, record.Data} {
+			if target != "" {
+				targets++
+			}
+		}
+		if targets != 1 {
+			return fmt.Errorf("record %s: set exactly one of server, redirector or data", record.Name)
+		}
+		if record.Server != "" && !servers[record.Server] {
+			return fmt.Errorf("record %s: unknown server %s", record.Name, record.Server)
+		}
+		if record.Redirector != "" && !redirectors[record.Redirector] {
+			return fmt.Errorf("record %s: unknown redirector %s", record.Name, record.Redirector)
+		}
+
+		if record.Type == "" {
+			record.Type = "A"
+			if record.Redirector != "" {
+				record.Type = "CNAME"
+			}
+		}
+		record.Type = strings.ToUpper(record.Type)
+		if record.TTL == 0 {
+			record.TTL = defaultTTL
+		}
+	}
+
+	// Redirectors are created between the two passes of records, so their origin cannot be another redirector
+	for _, redirector := range s.Redirectors {
+		origin, ok := s.Record(redirector.Origin)
+		if !ok {
+			return fmt.Errorf("redirector %s: unknown origin record %s", redirector.Name, redirector.Origin)
+		}
+		if origin.Redirector != "" {
+			return fmt.Errorf("redirector %s: origin %s must point at a server or fixed data", redirector.Name, origin.Name)
+		}
+	}
+
+	return nil
+}
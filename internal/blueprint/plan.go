@@ -0,0 +1,281 @@
+package blueprint
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
+
+// Actions a plan takes to converge a project on its declared infrastructure
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionReplace = "replace"
+	ActionDestroy = "destroy"
+)
+
+// Kinds of resource a plan changes
+const (
+	KindServer     = "server"
+	KindRecord     = "record"
+	KindRedirector = "redirector"
+)
+
+// State is what a project currently has, from the database and the providers
+type State struct {
+	Servers []models.Server
+	// Missing holds the IDs of servers in the database which no longer exist at their provider
+	Missing     map[int]bool
+	Redirectors []models.Redirector
+	// Records are the live records on the declared domain
+	Records []models.DNS
+}
+
+// Change is a single step of a plan
+type Change struct {
+	Action string
+	Kind   string
+	// Key is the name of the resource in the declaration, empty for resources being destroyed
+	Key string
+	// Name is the hostname, record or redirector origin the change applies to
+	Name string
+	// ID is the database ID of the existing server or redirector
+	ID int
+	// Type is the type of DNS record changes
+	Type   string
+	Detail string
+}
+
+// Plan is the list of changes needed to converge a project on its declared infrastructure
+type Plan struct {
+	Changes []Change
+}
+
+// Empty reports whether the project already matches its declaration
+func (p Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// Count returns how many changes take the given action
+func (p Plan) Count(action string) int {
+	count := 0
+	for _, change := range p.Changes {
+		if change.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// Fingerprint identifies the changes in a plan, so an apply can check it is running the plan that was reviewed
+func (p Plan) Fingerprint() string {
+	hash := sha256.New()
+	for _, change := range p.Changes {
+		fmt.Fprintf(hash, "%s|%s|%s|%s|%d|%s|%s\n", change.Action, change.Kind, change.Key, change.Name, change.ID, change.Type, change.Detail)
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil))[:16]
+}
+
+// Diff plans the changes that take a project from its current state to the declaration. Desired holds the
+// server to create for each instance key, with its firewall policy resolved. Servers are matched on hostname,
+// redirectors on their origin and records on name and type. Records on the domain which are not declared are
+// only destroyed when they point at one of the project's servers or redirectors, as domains are shared.
+func (s Spec) Diff(project int, desired map[string]models.Server, current State) Plan {
+	var plan Plan
+
+	byName := make(map[string][]models.Server)
+	for _, vps := range current.Servers {
+		byName[vps.Name] = append(byName[vps.Name], vps)
+	}
+
+	// addresses holds the IP of each instance that is kept, instances being created have no address yet
+	addresses := make(map[string]string)
+	kept := make(map[int]bool)
+	for _, instance := range s.Instances(project) {
+		want := desired[instance.Key]
+		matches := byName[instance.Hostname]
+		if len(matches) == 0 {
+			plan.Changes = append(plan.Changes, Change{
+				Action: ActionCreate,
+				Kind:   KindServer,
+				Key:    instance.Key,
+				Name:   want.Name,
+				Detail: fmt.Sprintf("%s %s %s", want.Provider, want.Region, want.Size),
+			})
+			continue
+		}
+
+		have := matches[0]
+		byName[instance.Hostname] = matches[1:]
+		kept[have.ID] = true
+
+		if reason := replaceReason(want, have, current.Missing[have.ID]); reason != "" {
+			plan.Changes = append(plan.Changes, Change{
+				Action: ActionReplace,
+				Kind:   KindServer,
+				Key:    instance.Key,
+				Name:   have.Name,
+				ID:     have.ID,
+				Detail: reason,
+			})
+			continue
+		}
+		addresses[instance.Key] = have.IP
+
+		if want.Firewall != have.Firewall {
+			plan.Changes = append(plan.Changes, Change{
+				Action: ActionUpdate,
+				Kind:   KindServer,
+				Key:    instance.Key,
+				Name:   have.Name,
+				ID:     have.ID,
+				Detail: fmt.Sprintf("firewall policy %d → %d", have.Firewall, want.Firewall),
+			})
+		}
+	}
+
+	// Anything the project owns is kept track of so stale records pointing at it can be found
+	owned := make(map[string]bool)
+	for _, vps := range current.Servers {
+		owned[vps.IP] = true
+		if !kept[vps.ID] {
+			plan.Changes = append(plan.Changes, Change{
+				Action: ActionDestroy,
+				Kind:   KindServer,
+				Name:   vps.Name,
+				ID:     vps.ID,
+				Detail: fmt.Sprintf("%s %s", vps.Provider, vps.IP),
+			})
+		}
+	}
+
+	redirectorURLs := make(map[string]string)
+	keptRedirectors := make(map[int]bool)
+	for _, redirector := range s.Redirectors {
+		origin, _ := s.Record(redirector.Origin)
+		originName := s.RecordName(origin)
+
+		found := false
+		for _, have := range current.Redirectors {
+			if have.Domain == originName && !keptRedirectors[have.ID] {
+				keptRedirectors[have.ID] = true
+				redirectorURLs[redirector.Name] = have.URL
+				found = true
+				break
+			}
+		}
+		if !found {
+			plan.Changes = append(plan.Changes, Change{
+				Action: ActionCreate,
+				Kind:   KindRedirector,
+				Key:    redirector.Name,
+				Name:   originName,
+			})
+		}
+	}
+	for _, have := range current.Redirectors {
+		owned[trimDot(have.URL)] = true
+		if !keptRedirectors[have.ID] {
+			plan.Changes = append(plan.Changes, Change{
+				Action: ActionDestroy,
+				Kind:   KindRedirector,
+				Name:   have.Domain,
+				ID:     have.ID,
+				Detail: have.URL,
+			})
+		}
+	}
+
+	declared := make(map[string]bool)
+	for _, record := range s.Records {
+		name := s.RecordName(record)
+		declared[record.Type+" "+name] = true
+
+		// Data is unknown until apply when the server or redirector it points at is being created
+		data := record.Data
+		switch {
+		case record.Server != "":
+			data = addresses[record.Server]
+		case record.Redirector != "":
+			data = redirectorURLs[record.Redirector]
+		}
+
+		have, ok := liveRecord(current.Records, name, record.Type)
+		switch {
+		case !ok:
+			plan.Changes = append(plan.Changes, Change{
+				Action: ActionCreate,
+				Kind:   KindRecord,
+				Key:    record.Name,
+				Name:   name,
+				Type:   record.Type,
+				Detail: fmt.Sprintf("%s %s", record.Type, dataOrPending(data)),
+			})
+		case data == "" || trimDot(have.Data) != trimDot(data) || have.Ttl != record.TTL:
+			plan.Changes = append(plan.Changes, Change{
+				Action: ActionUpdate,
+				Kind:   KindRecord,
+				Key:    record.Name,
+				Name:   name,
+				Type:   record.Type,
+				Detail: fmt.Sprintf("%s %s → %s", record.Type, have.Data, dataOrPending(data)),
+			})
+		}
+	}
+
+	for _, have := range current.Records {
+		name := trimDot(have.Name)
+		if declared[have.Type+" "+name] || !owned[trimDot(have.Data)] {
+			continue
+		}
+		plan.Changes = append(plan.Changes, Change{
+			Action: ActionDestroy,
+			Kind:   KindRecord,
+			Name:   name,
+			Type:   have.Type,
+			Detail: fmt.Sprintf("%s %s", have.Type, have.Data),
+		})
+	}
+
+	return plan
+}
+
+// replaceReason returns why an existing server cannot be kept for an instance, or an empty string if it can
+func replaceReason(want, have models.Server, missing bool) string {
+	switch {
+	case missing:
+		return "missing at provider"
+	case want.Provider != have.Provider:
+		return fmt.Sprintf("provider %s → %s", have.Provider, want.Provider)
+	case want.Region != have.Region:
+		return fmt.Sprintf("region %s → %s", have.Region, want.Region)
+	case want.Size != have.Size:
+		return fmt.Sprintf("size %s → %s", have.Size, want.Size)
+	case want.Image != "" && want.Image != have.Image:
+		return fmt.Sprintf("image %s → %s", have.Image, want.Image)
+	}
+	return ""
+}
+
+// liveRecord finds a record by fully qualified name and type, Route 53 names end with a dot
+func liveRecord(records []models.DNS, name, recordType string) (models.DNS, bool) {
+	for _, record := range records {
+		if trimDot(record.Name) == name && record.Type == recordType {
+			return record, true
+		}
+	}
+	return models.DNS{}, false
+}
+
+func trimDot(name string) string {
+	return strings.TrimSuffix(name, ".")
+}
+
+func dataOrPending(data string) string {
+	if data == "" {
+		return "(known after apply)"
+	}
+	return data
+}
@@ -0,0 +1,188 @@
+package blueprint
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
+
+// testSpec is a teamserver and two redirector servers on example.com, fronted by a CloudFront redirector
+var testSpec = Spec{
+	Servers: []Server{
+		{Name: "ts", Hostname: "{project}-ts", Count: 1, Provider: "digitalocean", Region: "lon1", Size: "s-2vcpu-4gb", Image: "ubuntu-22-04-x64"},
+		{Name: "redir", Count: 2, Provider: "linode", Region: "eu-west", Size: "g6-nanode-1"},
+	},
+	Domain: "example.com",
+	Records: []Record{
+		{Name: "ts", Type: "A", TTL: 300, Server: "ts"},
+		{Name: "origin", Type: "A", TTL: 300, Server: "redir-1"},
+		{Name: "www", Type: "CNAME", TTL: 300, Redirector: "cdn"},
+	},
+	Redirectors: []Redirector{{Name: "cdn", Origin: "origin"}},
+}
+
+// testDesired is the server testSpec creates for each instance in project 7
+func testDesired() map[string]models.Server {
+	return map[string]models.Server{
+		"ts":      {Name: "7-ts", Provider: "digitalocean", Region: "lon1", Size: "s-2vcpu-4gb", Image: "ubuntu-22-04-x64", Firewall: 3},
+		"redir-1": {Name: "redir-1", Provider: "linode", Region: "eu-west", Size: "g6-nanode-1"},
+		"redir-2": {Name: "redir-2", Provider: "linode", Region: "eu-west", Size: "g6-nanode-1"},
+	}
+}
+
+// testState is project 7 exactly as testSpec declares it
+func testState() State {
+	return State{
+		Servers: []models.Server{
+			{ID: 1, Name: "7-ts", IP: "203.0.113.1", Provider: "digitalocean", Region: "lon1", Size: "s-2vcpu-4gb", Image: "ubuntu-22-04-x64", Firewall: 3},
+			{ID: 2, Name: "redir-1", IP: "203.0.113.2", Provider: "linode", Region: "eu-west", Size: "g6-nanode-1", Image: "linode/ubuntu22.04"},
+			{ID: 3, Name: "redir-2", IP: "203.0.113.3", Provider: "linode", Region: "eu-west", Size: "g6-nanode-1", Image: "linode/ubuntu22.04"},
+		},
+		Redirectors: []models.Redirector{{ID: 1, Domain: "origin.example.com", URL: "d111.cloudfront.net"}},
+		Records: []models.DNS{
+			{Name: "ts.example.com.", Type: "A", Ttl: 300, Data: "203.0.113.1"},
+			{Name: "origin.example.com.", Type: "A", Ttl: 300, Data: "203.0.113.2"},
+			{Name: "www.example.com.", Type: "CNAME", Ttl: 300, Data: "d111.cloudfront.net."},
+		},
+	}
+}
+
+// summary lists each change of a plan as "action kind name id", leaving out the free text detail
+func summary(plan Plan) []string {
+	var changes []string
+	for _, change := range plan.Changes {
+		changes = append(changes, fmt.Sprintf("%s %s %s %d", change.Action, change.Kind, change.Name, change.ID))
+	}
+	return changes
+}
+
+func TestSpecDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		desired func(map[string]models.Server)
+		current func(*State)
+		want    []string
+	}{
+		{
+			name: "project matches declaration",
+		},
+		{
+			name:    "everything created in an empty project",
+			current: func(s *State) { *s = State{} },
+			want: []string{
+				"create server 7-ts 0",
+				"create server redir-1 0",
+				"create server redir-2 0",
+				"create redirector origin.example.com 0",
+				"create record ts.example.com 0",
+				"create record origin.example.com 0",
+				"create record www.example.com 0",
+			},
+		},
+		{
+			name: "servers matched by hostname whatever their order",
+			current: func(s *State) {
+				s.Servers[0], s.Servers[2] = s.Servers[2], s.Servers[0]
+			},
+		},
+		{
+			name: "undeclared and duplicate servers destroyed",
+			current: func(s *State) {
+				s.Servers = append(s.Servers,
+					models.Server{ID: 4, Name: "redir-1", IP: "203.0.113.4", Provider: "linode", Region: "eu-west", Size: "g6-nanode-1"},
+					models.Server{ID: 5, Name: "old", IP: "203.0.113.5", Provider: "linode", Region: "eu-west", Size: "g6-nanode-1"},
+				)
+			},
+			want: []string{
+				"destroy server redir-1 4",
+				"destroy server old 5",
+			},
+		},
+		{
+			name:    "region change replaces the server and its record",
+			desired: func(d map[string]models.Server) { ts := d["ts"]; ts.Region = "ams3"; d["ts"] = ts },
+			want: []string{
+				"replace server 7-ts 1",
+				"update record ts.example.com 0",
+			},
+		},
+		{
+			name:    "size change replaces the server",
+			desired: func(d map[string]models.Server) { r := d["redir-2"]; r.Size = "g6-standard-1"; d["redir-2"] = r },
+			want:    []string{"replace server redir-2 3"},
+		},
+		{
+			name:    "image change replaces the server",
+			desired: func(d map[string]models.Server) { ts := d["ts"]; ts.Image = "debian-12-x64"; d["ts"] = ts },
+			want: []string{
+				"replace server 7-ts 1",
+				"update record ts.example.com 0",
+			},
+		},
+		{
+			name:    "unset image keeps the server",
+			desired: func(d map[string]models.Server) { ts := d["ts"]; ts.Image = ""; d["ts"] = ts },
+		},
+		{
+			name:    "server missing at provider replaced",
+			current: func(s *State) { s.Missing = map[int]bool{2: true} },
+			want: []string{
+				"replace server redir-1 2",
+				"update record origin.example.com 0",
+			},
+		},
+		{
+			name:    "firewall change updates the server in place",
+			desired: func(d map[string]models.Server) { ts := d["ts"]; ts.Firewall = 4; d["ts"] = ts },
+			want:    []string{"update server 7-ts 1"},
+		},
+		{
+			name:    "records follow the address of kept servers",
+			current: func(s *State) { s.Servers[1].IP = "203.0.113.20" },
+			want:    []string{"update record origin.example.com 0"},
+		},
+		{
+			name: "records pointing at project servers or redirectors destroyed",
+			current: func(s *State) {
+				s.Records = append(s.Records,
+					models.DNS{Name: "old.example.com.", Type: "A", Ttl: 300, Data: "203.0.113.3"},
+					models.DNS{Name: "cdn2.example.com.", Type: "CNAME", Ttl: 300, Data: "d111.cloudfront.net."},
+				)
+			},
+			want: []string{
+				"destroy record old.example.com 0",
+				"destroy record cdn2.example.com 0",
+			},
+		},
+		{
+			name: "records pointing elsewhere left alone",
+			current: func(s *State) {
+				s.Records = append(s.Records,
+					models.DNS{Name: "example.com.", Type: "MX", Ttl: 300, Data: "10 mail.example.net."},
+					models.DNS{Name: "shop.example.com.", Type: "A", Ttl: 300, Data: "198.51.100.10"},
+					models.DNS{Name: "blog.example.com.", Type: "CNAME", Ttl: 300, Data: "d222.cloudfront.net."},
+				)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desired := testDesired()
+			if tt.desired != nil {
+				tt.desired(desired)
+			}
+			current := testState()
+			if tt.current != nil {
+				tt.current(&current)
+			}
+
+			got := summary(testSpec.Diff(7, desired, current))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package cloudinit
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Example is shown when a new cloud-init template is created
+const Example = `#cloud-config
+# GoBoxer provisions servers over SSH as root, so do not set disable_root: true.
+# Variables are .Hostname, .Project, .ProjectName, .Provider, .Region and
+# .OperatorKeys, the SSH keys of the users assigned to the project.
+hostname: {{.Hostname}}
+ssh_pwauth: false
+package_update: true
+packages:
+  - fail2ban
+  - unattended-upgrades
+ssh_authorized_keys:
+{{- range .OperatorKeys}}
+  - {{.}}
+{{- end}}
+`
+
+// Vars are the values a template can use
+type Vars struct {
+	Hostname     string
+	Project      int
+	ProjectName  string
+	Provider     string
+	Region       string
+	OperatorKeys []string
+}
+
+// sampleVars are used to check a template renders before it is saved
+var sampleVars = Vars{
+	Hostname:     "example-host",
+	Project:      1,
+	ProjectName:  "Example",
+	Provider:     "digitalocean",
+	Region:       "lon1",
+	OperatorKeys: []string{"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIExampleKey operator"},
+}
+
+// Render fills in a template's variables, giving the user data passed to a new server
+func Render(content string, vars Vars) (string, error) {
+	tmpl, err := template.New("cloud-init").Option("missingkey=error").Parse(content)
+	if err != nil {
+		return "", fmt.Errorf("error reading template: %v", err)
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, vars); err != nil {
+		return "", fmt.Errorf("error rendering template: %v", err)
+	}
+	return rendered.String(), nil
+}
+
+// Validate checks a template renders, and that cloud-config templates render to valid YAML
+func Validate(content string) error {
+	if !strings.HasPrefix(content, "#cloud-config") && !strings.HasPrefix(content, "#!") {
+		return fmt.Errorf("user data must start with #cloud-config or a #! script line")
+	}
+
+	rendered, err := Render(content, sampleVars)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasPrefix(rendered, "#cloud-config") {
+		var config map[string]interface{}
+		if err := yaml.Unmarshal([]byte(rendered), &config); err != nil {
+			return fmt.Errorf("cloud-config is not valid YAML: %v", err)
+		}
+	}
+	return nil
+}
+
+// Combine joins user data documents into the multipart form cloud-init reads, for providers which need their
+// own cloud-config alongside a template. Empty documents are skipped and a single document is returned as is.
+func Combine(parts ...string) string {
+	var documents []string
+	for _, part := range parts {
+		if strings.TrimSpace(part) != "" {
+			documents = append(documents, part)
+		}
+	}
+	switch len(documents) {
+	case 0:
+		return ""
+	case 1:
+		return documents[0]
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, document := range documents {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", contentType(document)+`; charset="us-ascii"`)
+		// Lists such as packages and runcmd are appended to rather than replaced by later documents
+		header.Set("Merge-Type", "list(append)+dict(recurse_array)+str()")
+		part, _ := writer.CreatePart(header)
+		part.Write([]byte(document))
+	}
+	writer.Close()
+
+	return fmt.Sprintf("Content-Type: multipart/mixed; boundary=\"%s\"\nMIME-Version: 1.0\n\n%s", writer.Boundary(), body.String())
+}
+
+// contentType returns the MIME type cloud-init uses to recognise a document
+func contentType(document string) string {
+	if strings.HasPrefix(document, "#!") {
+		return "text/x-shellscript"
+	}
+	return "text/cloud-config"
+}
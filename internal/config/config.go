@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/alexedwards/scs/v2"
 	"github.com/nickzer0/GoBoxer/internal/driver"
 )
@@ -15,4 +17,8 @@ type AppConfig struct {
 	Domain        string
 	PreferenceMap map[string]string
 	Version       string
+	// ReconcileInterval is how often servers are reconciled with the providers, zero disables it
+	ReconcileInterval time.Duration
+	// CredentialKey encrypts the root credentials stored for each server
+	CredentialKey []byte
 }
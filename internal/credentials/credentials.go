@@ -0,0 +1,142 @@
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// KeySize is the length of the key credentials are encrypted with, for AES-256
+const KeySize = 32
+
+// passwordLength and passwordCharset describe generated root passwords
+const (
+	passwordLength  = 24
+	passwordCharset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+// Root is a server's root SSH key pair and password, in plain text
+type Root struct {
+	PrivateKey string
+	PublicKey  string
+	Password   string
+}
+
+// Generate creates a new root key pair and password for a server
+func Generate(comment string) (Root, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return Root{}, fmt.Errorf("error generating key: %v", err)
+	}
+
+	block, err := ssh.MarshalPrivateKey(privateKey, comment)
+	if err != nil {
+		return Root{}, fmt.Errorf("error encoding private key: %v", err)
+	}
+
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		return Root{}, fmt.Errorf("error encoding public key: %v", err)
+	}
+
+	password, err := GeneratePassword()
+	if err != nil {
+		return Root{}, err
+	}
+
+	return Root{
+		PrivateKey: string(pem.EncodeToMemory(block)),
+		PublicKey:  strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPublicKey))) + " " + comment,
+		Password:   password,
+	}, nil
+}
+
+// GeneratePassword returns a random root password
+func GeneratePassword() (string, error) {
+	password := make([]byte, passwordLength)
+	max := big.NewInt(int64(len(passwordCharset)))
+	for i := range password {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("error generating password: %v", err)
+		}
+		password[i] = passwordCharset[n.Int64()]
+	}
+	return string(password), nil
+}
+
+// LoadKey reads the key credentials are encrypted with, creating it the first time it is needed.
+// It is kept on disk rather than in the database so a copy of the database alone does not expose credentials.
+func LoadKey(path string) ([]byte, error) {
+	encoded, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		key := make([]byte, KeySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("error generating credential key: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)), 0400); err != nil {
+			return nil, fmt.Errorf("error writing credential key: %v", err)
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading credential key: %v", err)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil || len(key) != KeySize {
+		return nil, fmt.Errorf("credential key %s is not a base64 encoded %d byte key", path, KeySize)
+	}
+	return key, nil
+}
+
+// Encrypt seals a credential with AES-GCM, returning the nonce and ciphertext base64 encoded
+func Encrypt(key []byte, plaintext string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("error generating nonce: %v", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a credential sealed by Encrypt
+func Decrypt(key []byte, ciphertext string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("credential is not encrypted")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("error decrypting credential, the credential key may have changed: %v", err)
+	}
+	return string(plaintext), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credential key: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -5,27 +5,21 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/apenella/go-ansible/pkg/options"
 	"github.com/apenella/go-ansible/pkg/playbook"
 	"github.com/nickzer0/GoBoxer/internal/models"
+	"github.com/nickzer0/GoBoxer/internal/sshca"
 )
 
 func RunPlayBook(server models.Server) error {
-	rootPassword, err := Repo.GetSecretFromDatabase("root_password")
-	if err != nil {
-		return err
-	}
+	defer lockServer(server.ID)()
 
-	ansiblePlaybookConnectionOptions := &options.AnsibleConnectionOptions{
-		PrivateKey:    "id_rsa",
-		SSHCommonArgs: "-o StrictHostKeyChecking=no",
-	}
 	extraVar := map[string]interface{}{
-		"ansible_user":      "root",
-		"ansible_password":  rootPassword,
-		"host_key_checking": "False",
+		"ansible_user": "root",
 	}
 
 	ansiblePlaybookOptions := &playbook.AnsiblePlaybookOptions{
@@ -36,6 +30,7 @@ func RunPlayBook(server models.Server) error {
 	// Wait for SSH to become available with retry limit
 	const maxRetries = 10
 	var conn net.Conn
+	var err error
 	for attempt := 0; attempt < maxRetries; attempt++ {
 		conn, err = net.DialTimeout("tcp", net.JoinHostPort(server.IP, "22"), 3*time.Second)
 		if err == nil {
@@ -48,6 +43,15 @@ func RunPlayBook(server models.Server) error {
 		return fmt.Errorf("SSH not available after %d attempts: %v", maxRetries, err)
 	}
 
+	ansiblePlaybookConnectionOptions, rootPassword, cleanup, err := connectionOptions(server)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if rootPassword != "" {
+		extraVar["ansible_password"] = rootPassword
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
 	defer cancel()
 
@@ -69,23 +73,21 @@ func RunPlayBook(server models.Server) error {
 	return nil
 }
 
-// AddSSHUser adds SSH user to servers
-func AddSSHUser(server models.Server, user models.User) error {
-	rootPassword, err := Repo.GetSecretFromDatabase("root_password")
-	if err != nil {
-		return err
-	}
+// TrustCertificateAuthority makes a server accept certificates signed by the GoBoxer SSH CA for root,
+// limited to those issued for the server's project. Certificates expire on their own, while the accounts
+// holding operators' own keys are removed by ReconcileOperators when someone leaves the project.
+func TrustCertificateAuthority(server models.Server) error {
+	defer lockServer(server.ID)()
 
-	ansiblePlaybookConnectionOptions := &options.AnsibleConnectionOptions{
-		PrivateKey:    "id_rsa",
-		SSHCommonArgs: "-o StrictHostKeyChecking=no",
+	caKey, err := Repo.GetSecretFromDatabase("ssh_ca_public_key")
+	if err != nil || caKey == "" {
+		return fmt.Errorf("error getting SSH CA public key: %v", err)
 	}
 
 	extraVar := map[string]interface{}{
-		"ansible_user":      "root",
-		"host_key_checking": "False",
-		"ssh_key":           user.SSHKey,
-		"root_password":     rootPassword,
+		"ansible_user": "root",
+		"ca_key":       strings.TrimSpace(caKey),
+		"principals":   []string{sshca.Principal(server.Project)},
 	}
 
 	ansiblePlaybookOptions := &playbook.AnsiblePlaybookOptions{
@@ -109,6 +111,16 @@ func AddSSHUser(server models.Server, user models.User) error {
 		return fmt.Errorf("SSH not available after %d retries", maxRetries)
 	}
 
+	ansiblePlaybookConnectionOptions, rootPassword, cleanup, err := connectionOptions(server)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	defer cleanup()
+	if rootPassword != "" {
+		extraVar["ansible_password"] = rootPassword
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
 	defer cancel()
 
@@ -129,6 +141,37 @@ func AddSSHUser(server models.Server, user models.User) error {
 
 }
 
+// connectionOptions returns the Ansible connection options for a server, trusting only its pinned host keys
+// and logging in with its own root key, along with its root password. The cleanup function removes the
+// per-run known_hosts and key files.
+func connectionOptions(server models.Server) (*options.AnsibleConnectionOptions, string, func(), error) {
+	hostKey, err := pinnedHostKey(server)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	knownHosts, err := writeKnownHosts(server.IP, hostKey)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error writing known_hosts: %v", err)
+	}
+
+	access, err := rootAccess(server)
+	if err != nil {
+		os.Remove(knownHosts)
+		return nil, "", nil, err
+	}
+
+	connectionOptions := &options.AnsibleConnectionOptions{
+		PrivateKey:    access.KeyFile,
+		SSHCommonArgs: hostKeyArgs(knownHosts),
+	}
+	cleanup := func() {
+		os.Remove(knownHosts)
+		access.cleanup()
+	}
+	return connectionOptions, access.Password, cleanup, nil
+}
+
 // GetSecretFromDatabase helper function to return a secret from the database
 func (m *Repository) GetSecretFromDatabase(secret string) (string, error) {
 	return m.DB.GetSecret(secret)
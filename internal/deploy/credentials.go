@@ -0,0 +1,143 @@
+package deploy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apenella/go-ansible/pkg/playbook"
+	"github.com/nickzer0/GoBoxer/internal/credentials"
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
+
+// serverLocks stops playbooks running against a server while its root credentials are being rotated
+var serverLocks sync.Map
+
+// lockServer waits for other Ansible runs against a server to finish, returning the unlock function
+func lockServer(serverID int) func() {
+	lock, _ := serverLocks.LoadOrStore(serverID, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	return lock.(*sync.Mutex).Unlock
+}
+
+// access is how Ansible logs in to a server as root
+type access struct {
+	KeyFile  string
+	Password string
+	cleanup  func()
+}
+
+// rootAccess returns the server's own root key and password, written to a file for a single run.
+// Servers which have never had their credentials rotated are reached with the shared root key.
+func rootAccess(server models.Server) (access, error) {
+	credential, err := Repo.DB.GetServerCredential(server.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return access{KeyFile: "id_rsa", cleanup: func() {}}, nil
+	}
+	if err != nil {
+		return access{}, fmt.Errorf("error getting root credentials for %s: %v", server.Name, err)
+	}
+
+	privateKey, err := credentials.Decrypt(app.CredentialKey, credential.PrivateKey)
+	if err != nil {
+		return access{}, fmt.Errorf("root key for %s: %v", server.Name, err)
+	}
+	password, err := credentials.Decrypt(app.CredentialKey, credential.Password)
+	if err != nil {
+		return access{}, fmt.Errorf("root password for %s: %v", server.Name, err)
+	}
+
+	// CreateTemp makes the file readable by its owner only, which ssh insists on for keys
+	file, err := os.CreateTemp("", "goboxer-root-key-*")
+	if err != nil {
+		return access{}, err
+	}
+	defer file.Close()
+	if _, err := file.WriteString(privateKey); err != nil {
+		os.Remove(file.Name())
+		return access{}, err
+	}
+
+	return access{
+		KeyFile:  file.Name(),
+		Password: password,
+		cleanup:  func() { os.Remove(file.Name()) },
+	}, nil
+}
+
+// RotateRootCredentials gives a server a new root key and password of its own. The new key is authorized
+// and the password set before the previous key, or the shared root key on first rotation, is removed,
+// and the new credentials are only stored once the server has accepted them.
+func RotateRootCredentials(server models.Server) error {
+	defer lockServer(server.ID)()
+
+	previousKey, err := Repo.GetSecretFromDatabase("sshkey")
+	if err != nil {
+		return fmt.Errorf("error getting shared root key: %v", err)
+	}
+	if current, err := Repo.DB.GetServerCredential(server.ID); err == nil {
+		previousKey = current.PublicKey
+	}
+
+	root, err := credentials.Generate("goboxer-" + server.Name)
+	if err != nil {
+		return err
+	}
+
+	// Encrypt before anything changes on the server, so a failure here cannot lock us out
+	credential := models.ServerCredential{
+		ServerID:  server.ID,
+		PublicKey: root.PublicKey,
+	}
+	if credential.PrivateKey, err = credentials.Encrypt(app.CredentialKey, root.PrivateKey); err != nil {
+		return err
+	}
+	if credential.Password, err = credentials.Encrypt(app.CredentialKey, root.Password); err != nil {
+		return err
+	}
+
+	connectionOptions, rootPassword, cleanup, err := connectionOptions(server)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	extraVar := map[string]interface{}{
+		"ansible_user":      "root",
+		"root_key":          root.PublicKey,
+		"previous_root_key": strings.TrimSpace(previousKey),
+		"root_password":     root.Password,
+	}
+	if rootPassword != "" {
+		extraVar["ansible_password"] = rootPassword
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
+	defer cancel()
+
+	playbook := &playbook.AnsiblePlaybookCmd{
+		Playbooks:         []string{"scripts/default/Rotate.yml"},
+		ConnectionOptions: connectionOptions,
+		Options: &playbook.AnsiblePlaybookOptions{
+			Inventory: server.IP + ",",
+			ExtraVars: extraVar,
+		},
+		StdoutCallback: app.AnsibleDebug,
+	}
+	if err := playbook.Run(ctx); err != nil {
+		return fmt.Errorf("error rotating root credentials on %s: %v", server.Name, err)
+	}
+
+	credential.RotatedAt = time.Now()
+	if err := Repo.DB.UpdateServerCredential(credential); err != nil {
+		log.Printf("Root credentials of server %d were rotated but could not be stored: %v", server.ID, err)
+		return fmt.Errorf("root credentials of %s were rotated but could not be stored, it may need rebuilding: %v", server.Name, err)
+	}
+	return nil
+}
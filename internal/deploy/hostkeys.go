@@ -0,0 +1,154 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nickzer0/GoBoxer/internal/models"
+	"golang.org/x/crypto/ssh"
+)
+
+// hostKeyAlgorithms are the host key types pinned for a server, the handshake is repeated for each
+// as a server only presents the one key it negotiates
+var hostKeyAlgorithms = []string{ssh.KeyAlgoED25519, ssh.KeyAlgoECDSA256, ssh.KeyAlgoRSASHA256}
+
+// errHostKeyCaptured stops a handshake once the host key has been seen, no login is attempted
+var errHostKeyCaptured = errors.New("host key captured")
+
+// ScanHostKeys returns the host keys a server presents, in authorized_keys format
+func ScanHostKeys(ip string) ([]string, error) {
+	var keys []string
+	var lastErr error
+	for _, algorithm := range hostKeyAlgorithms {
+		key, err := presentedHostKey(ip, []string{algorithm})
+		if err != nil {
+			// Servers without a key of this type fail the handshake
+			lastErr = err
+			continue
+		}
+		keys = append(keys, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))))
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no host keys read from %s: %v", ip, lastErr)
+	}
+	return keys, nil
+}
+
+// presentedHostKey starts a handshake with a server and returns the host key it presents for the given algorithms
+func presentedHostKey(ip string, algorithms []string) (ssh.PublicKey, error) {
+	var presented ssh.PublicKey
+	config := &ssh.ClientConfig{
+		User:              "root",
+		HostKeyAlgorithms: algorithms,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			presented = key
+			return errHostKeyCaptured
+		},
+		Timeout: 10 * time.Second,
+	}
+
+	_, err := ssh.Dial("tcp", net.JoinHostPort(ip, "22"), config)
+	if presented == nil {
+		return nil, err
+	}
+	return presented, nil
+}
+
+// HostKeyFingerprints returns the SHA256 fingerprint of each pinned host key, for display
+func HostKeyFingerprints(hostKey string) []string {
+	var fingerprints []string
+	for _, line := range strings.Split(hostKey, "\n") {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			continue
+		}
+		fingerprints = append(fingerprints, fmt.Sprintf("%s %s", key.Type(), ssh.FingerprintSHA256(key)))
+	}
+	return fingerprints
+}
+
+// pinnedHostKey returns the host keys pinned for a server. Keys are captured and stored the first time
+// the server is contacted, after that the server must still present one of them.
+func pinnedHostKey(server models.Server) (string, error) {
+	stored, err := Repo.DB.GetServer(server.ID)
+	if err != nil {
+		return "", fmt.Errorf("error getting server %d: %v", server.ID, err)
+	}
+
+	if stored.HostKey == "" {
+		keys, err := ScanHostKeys(server.IP)
+		if err != nil {
+			return "", err
+		}
+		hostKey := strings.Join(keys, "\n")
+		if err := Repo.DB.UpdateServerHostKey(server.ID, hostKey); err != nil {
+			return "", fmt.Errorf("error storing host key for %s: %v", server.Name, err)
+		}
+		return hostKey, nil
+	}
+
+	if err := checkHostKey(server.IP, stored.HostKey); err != nil {
+		return "", fmt.Errorf("%s: %v", server.Name, err)
+	}
+	return stored.HostKey, nil
+}
+
+// checkHostKey fails when a server presents a host key other than the pinned ones,
+// so a mismatch is reported clearly rather than as a failed Ansible run
+func checkHostKey(ip, hostKey string) error {
+	pinned := make(map[string]bool)
+	var algorithms []string
+	for _, line := range strings.Split(hostKey, "\n") {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			continue
+		}
+		pinned[string(key.Marshal())] = true
+		if key.Type() == ssh.KeyAlgoRSA {
+			algorithms = append(algorithms, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSA)
+		} else {
+			algorithms = append(algorithms, key.Type())
+		}
+	}
+	if len(pinned) == 0 {
+		return fmt.Errorf("pinned host key is unreadable")
+	}
+
+	presented, err := presentedHostKey(ip, algorithms)
+	if err != nil {
+		return fmt.Errorf("error reading host key from %s: %v", ip, err)
+	}
+	if !pinned[string(presented.Marshal())] {
+		return fmt.Errorf("host key has changed (now %s), refusing to connect", ssh.FingerprintSHA256(presented))
+	}
+	return nil
+}
+
+// writeKnownHosts writes a known_hosts file trusting only a server's pinned host keys, for a single run
+func writeKnownHosts(ip, hostKey string) (string, error) {
+	file, err := os.CreateTemp("", "goboxer-known-hosts-*")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	for _, line := range strings.Split(hostKey, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			if _, err := fmt.Fprintf(file, "%s %s\n", ip, line); err != nil {
+				os.Remove(file.Name())
+				return "", err
+			}
+		}
+	}
+	return file.Name(), nil
+}
+
+// hostKeyArgs returns the SSH options which make a connection fail unless the server presents a pinned key
+func hostKeyArgs(knownHosts string) string {
+	return fmt.Sprintf("-o StrictHostKeyChecking=yes -o UserKnownHostsFile=%s -o GlobalKnownHostsFile=/dev/null", knownHosts)
+}
@@ -0,0 +1,117 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/apenella/go-ansible/pkg/playbook"
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
+
+// operatorAccountPrefix marks the accounts GoBoxer manages, so an operator can never be mapped onto
+// an existing system account such as root
+const operatorAccountPrefix = "gb-"
+
+// maxAccountLength is the longest account name useradd accepts
+const maxAccountLength = 32
+
+// OperatorAccount returns the name of a GoBoxer user's own account on servers. Usernames are cleaned up
+// to suit useradd, so the user's ID is added to keep two users from sharing an account.
+func OperatorAccount(userID int, username string) string {
+	suffix := fmt.Sprintf("-%d", userID)
+
+	var account strings.Builder
+	account.WriteString(operatorAccountPrefix)
+	for _, r := range strings.ToLower(username) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '-':
+			account.WriteRune(r)
+		default:
+			account.WriteRune('-')
+		}
+	}
+
+	name := account.String()
+	if len(name)+len(suffix) > maxAccountLength {
+		name = name[:maxAccountLength-len(suffix)]
+	}
+	return name + suffix
+}
+
+// projectOperators returns the account and keys of each member of a project with an SSH key set
+func projectOperators(projectNumber int) ([]map[string]interface{}, error) {
+	project, err := Repo.DB.GetProjectByNumber(projectNumber)
+	if err != nil {
+		return nil, fmt.Errorf("error getting project %d: %v", projectNumber, err)
+	}
+
+	users, err := Repo.DB.GetUsers()
+	if err != nil {
+		return nil, fmt.Errorf("error getting users: %v", err)
+	}
+
+	members := make(map[string]bool)
+	for _, username := range project.AssignedTo {
+		members[username] = true
+	}
+
+	operators := []map[string]interface{}{}
+	for _, user := range users {
+		keys := user.AuthorizedKeys()
+		if !members[user.Username] || len(keys) == 0 {
+			continue
+		}
+		operators = append(operators, map[string]interface{}{
+			"name":     OperatorAccount(user.ID, user.Username),
+			"ssh_keys": keys,
+		})
+	}
+	return operators, nil
+}
+
+// ReconcileOperators gives each member of the server's project an account of their own with exactly
+// their current SSH keys, and removes the accounts of anyone who has left the project. Membership is
+// read once the server is locked, so the last run always reflects the latest changes.
+func ReconcileOperators(server models.Server) error {
+	defer lockServer(server.ID)()
+
+	operators, err := projectOperators(server.Project)
+	if err != nil {
+		return err
+	}
+
+	connectionOptions, rootPassword, cleanup, err := connectionOptions(server)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	extraVar := map[string]interface{}{
+		"ansible_user":    "root",
+		"operators":       operators,
+		"operator_prefix": operatorAccountPrefix,
+		"operators_group": "goboxer",
+	}
+	if rootPassword != "" {
+		extraVar["ansible_password"] = rootPassword
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
+	defer cancel()
+
+	playbook := &playbook.AnsiblePlaybookCmd{
+		Playbooks:         []string{"scripts/default/Operators.yml"},
+		ConnectionOptions: connectionOptions,
+		Options: &playbook.AnsiblePlaybookOptions{
+			Inventory: server.IP + ",",
+			ExtraVars: extraVar,
+		},
+		StdoutCallback: app.AnsibleDebug,
+	}
+	if err := playbook.Run(ctx); err != nil {
+		return fmt.Errorf("error updating operator accounts on %s: %v", server.Name, err)
+	}
+	return nil
+}
@@ -263,3 +263,228 @@ func (m *Repository) RemoveAllDNSRecords(zoneID string) error {
 	_, err = svc.ChangeResourceRecordSets(changeParams)
 	return err
 }
+
+// ReplaceRecordDataInAWS points every record set of the given type in the domain's hosted zone which
+// contains oldData at newData instead, leaving the rest of each record set as it is, and updates the
+// matching records in the database. It returns the number of record sets changed.
+func (m *Repository) ReplaceRecordDataInAWS(domain models.Domains, recordType, oldData, newData string) (int, error) {
+	awsAccount, err := m.DB.GetSecret("awsaccount")
+	if err != nil {
+		return 0, fmt.Errorf("error getting AWS account: %v", err)
+	}
+	awsSecret, err := m.DB.GetSecret("awssecret")
+	if err != nil {
+		return 0, fmt.Errorf("error getting AWS secret: %v", err)
+	}
+
+	session, err := session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials(awsAccount, awsSecret, ""),
+		MaxRetries:  aws.Int(3),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error creating AWS session: %v", err)
+	}
+
+	zoneID, err := m.DB.GetAWSHostedZone(domain.Name)
+	if err != nil {
+		return 0, fmt.Errorf("error getting hosted zone ID: %v", err)
+	}
+
+	svc := route53.New(session)
+
+	var changes []*route53.Change
+	err = svc.ListResourceRecordSetsPages(&route53.ListResourceRecordSetsInput{HostedZoneId: aws.String(zoneID)},
+		func(page *route53.ListResourceRecordSetsOutput, lastPage bool) bool {
+			for _, rec := range page.ResourceRecordSets {
+				if aws.StringValue(rec.Type) != recordType {
+					continue
+				}
+
+				replaced := false
+				for _, value := range rec.ResourceRecords {
+					if aws.StringValue(value.Value) == oldData {
+						value.Value = aws.String(newData)
+						replaced = true
+					}
+				}
+				if replaced {
+					changes = append(changes, &route53.Change{
+						Action:            aws.String(route53.ChangeActionUpsert),
+						ResourceRecordSet: rec,
+					})
+				}
+			}
+			return !lastPage
+		})
+	if err != nil {
+		return 0, fmt.Errorf("error listing DNS records: %v", err)
+	}
+
+	if len(changes) > 0 {
+		_, err = svc.ChangeResourceRecordSets(&route53.ChangeResourceRecordSetsInput{
+			ChangeBatch:  &route53.ChangeBatch{Changes: changes},
+			HostedZoneId: aws.String(zoneID),
+		})
+		if err != nil {
+			return 0, fmt.Errorf("error updating DNS records: %v", err)
+		}
+	}
+
+	records, err := m.DB.GetDnsRecordsForDomain(domain.Name)
+	if err != nil {
+		return len(changes), fmt.Errorf("error getting DNS records from database: %v", err)
+	}
+
+	for _, record := range records {
+		if record.Type != recordType || record.Data != oldData {
+			continue
+		}
+		record.Data = newData
+		if err := m.DB.UpdateDnsRecord(record); err != nil {
+			return len(changes), fmt.Errorf("error updating DNS record in database: %v", err)
+		}
+	}
+
+	return len(changes), nil
+}
+
+// UpsertDNSRecordInAWS creates or replaces a single record set in the domain's hosted zone, leaving
+// the other records in the zone as they are, and records it in the database.
+func (m *Repository) UpsertDNSRecordInAWS(domain models.Domains, record models.DNS) error {
+	awsAccount, err := m.DB.GetSecret("awsaccount")
+	if err != nil {
+		return fmt.Errorf("error getting AWS account: %v", err)
+	}
+	awsSecret, err := m.DB.GetSecret("awssecret")
+	if err != nil {
+		return fmt.Errorf("error getting AWS secret: %v", err)
+	}
+
+	session, err := session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials(awsAccount, awsSecret, ""),
+		MaxRetries:  aws.Int(3),
+	})
+	if err != nil {
+		return fmt.Errorf("error creating AWS session: %v", err)
+	}
+
+	zoneID, err := m.DB.GetAWSHostedZone(domain.Name)
+	if err != nil {
+		return fmt.Errorf("error getting hosted zone ID: %v", err)
+	}
+
+	recordName := record.Name
+	if recordName == "" || recordName == "@" {
+		recordName = domain.Name
+	} else if !strings.HasSuffix(recordName, domain.Name) {
+		recordName = recordName + "." + domain.Name
+	}
+
+	svc := route53.New(session)
+	_, err = svc.ChangeResourceRecordSets(&route53.ChangeResourceRecordSetsInput{
+		ChangeBatch: &route53.ChangeBatch{
+			Changes: []*route53.Change{
+				{
+					Action: aws.String(route53.ChangeActionUpsert),
+					ResourceRecordSet: &route53.ResourceRecordSet{
+						Name:            aws.String(recordName),
+						ResourceRecords: []*route53.ResourceRecord{{Value: aws.String(record.Data)}},
+						TTL:             aws.Int64(int64(record.Ttl)),
+						Type:            aws.String(record.Type),
+					},
+				},
+			},
+		},
+		HostedZoneId: aws.String(zoneID),
+	})
+	if err != nil {
+		return fmt.Errorf("error updating DNS record: %v", err)
+	}
+
+	// The upsert replaced any earlier record set with this name and type
+	if err := m.forgetDNSRecords(domain, recordName, record.Type); err != nil {
+		return err
+	}
+	record.Domain = domain.Name
+	record.Name = recordName
+	if err := m.DB.AddOrIgnoreDnsRecord(record); err != nil {
+		return fmt.Errorf("error adding DNS record to database: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteDNSRecordFromAWS deletes every record set with the given name and type from the domain's
+// hosted zone, leaving the other records in the zone as they are, and removes them from the database.
+func (m *Repository) DeleteDNSRecordFromAWS(domain models.Domains, name, recordType string) error {
+	awsAccount, err := m.DB.GetSecret("awsaccount")
+	if err != nil {
+		return fmt.Errorf("error getting AWS account: %v", err)
+	}
+	awsSecret, err := m.DB.GetSecret("awssecret")
+	if err != nil {
+		return fmt.Errorf("error getting AWS secret: %v", err)
+	}
+
+	session, err := session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials(awsAccount, awsSecret, ""),
+		MaxRetries:  aws.Int(3),
+	})
+	if err != nil {
+		return fmt.Errorf("error creating AWS session: %v", err)
+	}
+
+	zoneID, err := m.DB.GetAWSHostedZone(domain.Name)
+	if err != nil {
+		return fmt.Errorf("error getting hosted zone ID: %v", err)
+	}
+
+	svc := route53.New(session)
+
+	// Deletes must match the record set exactly, including the identifier of weighted records
+	var changes []*route53.Change
+	err = svc.ListResourceRecordSetsPages(&route53.ListResourceRecordSetsInput{HostedZoneId: aws.String(zoneID)},
+		func(page *route53.ListResourceRecordSetsOutput, lastPage bool) bool {
+			for _, rec := range page.ResourceRecordSets {
+				if aws.StringValue(rec.Type) == recordType && strings.TrimSuffix(aws.StringValue(rec.Name), ".") == name {
+					changes = append(changes, &route53.Change{
+						Action:            aws.String(route53.ChangeActionDelete),
+						ResourceRecordSet: rec,
+					})
+				}
+			}
+			return !lastPage
+		})
+	if err != nil {
+		return fmt.Errorf("error listing DNS records: %v", err)
+	}
+
+	if len(changes) > 0 {
+		_, err = svc.ChangeResourceRecordSets(&route53.ChangeResourceRecordSetsInput{
+			ChangeBatch:  &route53.ChangeBatch{Changes: changes},
+			HostedZoneId: aws.String(zoneID),
+		})
+		if err != nil {
+			return fmt.Errorf("error deleting DNS record: %v", err)
+		}
+	}
+
+	return m.forgetDNSRecords(domain, name, recordType)
+}
+
+// forgetDNSRecords removes the database copies of the records with the given name and type
+func (m *Repository) forgetDNSRecords(domain models.Domains, name, recordType string) error {
+	records, err := m.DB.GetDnsRecordsForDomain(domain.Name)
+	if err != nil {
+		return fmt.Errorf("error getting DNS records from database: %v", err)
+	}
+	for _, record := range records {
+		if record.Type != recordType || strings.TrimSuffix(record.Name, ".") != strings.TrimSuffix(name, ".") {
+			continue
+		}
+		if err := m.DB.DeleteDnsRecord(record.ID); err != nil {
+			return fmt.Errorf("error deleting DNS record from database: %v", err)
+		}
+	}
+	return nil
+}
@@ -77,8 +77,9 @@ func (m *Repository) GoDaddyLookupDomain(domainName string) (models.Domains, err
 		domain.Status = "Taken"
 	}
 
+	// GoDaddy quotes prices in micro-units of the currency
 	price := float64(returnedDomain.Price)
-	convertedPrice := fmt.Sprintf(("%.2f"), price/10000000)
+	convertedPrice := fmt.Sprintf(("%.2f"), price/1000000)
 	domain.Price = convertedPrice
 
 	domain.Provider = "GoDaddy"
@@ -3,6 +3,7 @@ package handlers
 import (
 	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/CloudyKit/jet/v6"
 	"github.com/nickzer0/GoBoxer/internal/helpers"
@@ -59,7 +60,8 @@ func (m *Repository) EditAccount(w http.ResponseWriter, r *http.Request) {
 		user.HashedPassword = string(hashedPassword)
 	}
 
-	if sshKey != user.SSHKey {
+	keyChanged := sshKey != user.SSHKey
+	if keyChanged {
 		user.SSHKey = sshKey
 	}
 
@@ -69,6 +71,10 @@ func (m *Repository) EditAccount(w http.ResponseWriter, r *http.Request) {
 		printErrorPage(w, err)
 	}
 
+	if keyChanged && len(user.Projects) > 0 {
+		go m.ReconcileProjectsRoutine(projectNumbers(user.Projects), strconv.Itoa(id))
+	}
+
 	m.App.Session.Put(r.Context(), "ssh_key", sshKey)
 
 	m.App.Session.Put(r.Context(), "flash", "User updated!")
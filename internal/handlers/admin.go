@@ -24,6 +24,7 @@ func (m *Repository) Settings(w http.ResponseWriter, r *http.Request) {
 
 	vars := make(jet.VarMap)
 	vars.Set("provider_keys", providerKeys)
+	vars.Set("server_providers", server.Providers())
 
 	err = helpers.RenderPage(w, r, "settings", vars, nil)
 	if err != nil {
@@ -39,27 +40,27 @@ func (m *Repository) SettingsEdit(w http.ResponseWriter, r *http.Request) {
 		return // Ensure no further execution on error
 	}
 
+	// Server provider secrets use the secret name as the form field name,
+	// this includes the AWS credentials shared with Route53 and Cloudfront
+	for _, provider := range server.Providers() {
+		for _, secret := range provider.Secrets() {
+			m.ChangeAPIKey(secret.Name, r.Form.Get(secret.Name))
+		}
+	}
+
 	// Retrieve form values
-	digitalocean := r.Form.Get("digital_ocean")
-	linode := r.Form.Get("linode")
 	godaddykey := r.Form.Get("godaddy_key")
 	godaddysecret := r.Form.Get("godaddy_secret")
 	namecheapuser := r.Form.Get("namecheap_user")
 	namecheapkey := r.Form.Get("namecheap_key")
-	awsaccount := r.Form.Get("aws_account")
-	awssecret := r.Form.Get("aws_secret")
 	sshkey := r.Form.Get("ssh_key")
 	sshfingerprint := r.Form.Get("ssh_fingerprint")
 
 	// Update API keys and secrets
-	m.ChangeAPIKey("digitalocean", digitalocean)
-	m.ChangeAPIKey("linode", linode)
 	m.ChangeAPIKey("godaddykey", godaddykey)
 	m.ChangeAPIKey("godaddysecret", godaddysecret)
 	m.ChangeAPIKey("namecheapuser", namecheapuser)
 	m.ChangeAPIKey("namecheapkey", namecheapkey)
-	m.ChangeAPIKey("awsaccount", awsaccount)
-	m.ChangeAPIKey("awssecret", awssecret)
 	m.ChangeAPIKey("sshkey", sshkey)
 	m.ChangeAPIKey("sshfingerprint", sshfingerprint)
 
@@ -75,17 +76,14 @@ func (m *Repository) UpdateSSH(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Attempt to update SSH key on Linode if a Linode token exists
-	if providerKeys["linode"] != "" {
-		// Placeholder for Linode SSH key update logic
-		// err = updateLinodeSSHKey(providerKeys["linode"])
-		// if err != nil {
-		//     log.Printf("Error adding SSH key to Linode: %v", err)
-		// }
-	} else if providerKeys["digitalocean"] != "" {
-		err = server.Repo.AddSSHKeyToDigitalOcean()
-		if err != nil {
-			log.Printf("Error adding SSH key to Digital Ocean: %v", err)
+	// Linode takes the key at instance creation, so only providers with a key store need updating
+	for _, provider := range server.ConfiguredProviders(providerKeys) {
+		registrar, ok := provider.(server.KeyRegistrar)
+		if !ok {
+			continue
+		}
+		if err := registrar.RegisterSSHKey(); err != nil {
+			log.Printf("Error adding SSH key to %s: %v", provider.DisplayName(), err)
 		}
 	}
 }
@@ -205,9 +203,11 @@ func (m *Repository) EditUser(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	keyChanged := false
 	if formSSHKey != "" && formSSHKey != user.SSHKey {
 		user.SSHKey = formSSHKey
 		updated = true
+		keyChanged = true
 	}
 
 	if updated {
@@ -216,6 +216,10 @@ func (m *Repository) EditUser(w http.ResponseWriter, r *http.Request) {
 			printErrorPage(w, err)
 			return
 		}
+		if keyChanged && len(user.Projects) > 0 {
+			userID := strconv.Itoa(m.App.Session.Get(r.Context(), "user_id").(int))
+			go m.ReconcileProjectsRoutine(projectNumbers(user.Projects), userID)
+		}
 		m.App.Session.Put(r.Context(), "flash", "User updated!")
 	}
 
@@ -286,12 +290,25 @@ func (m *Repository) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The user's projects are read first, their servers still have the user's account on them
+	user, err := m.DB.GetUserFromID(id)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		printErrorPage(w, err)
+		return
+	}
+
 	if err := m.DB.DeleteUser(id); err != nil {
 		log.Printf("Error deleting user: %v", err)
 		printErrorPage(w, err)
 		return
 	}
 
+	if len(user.Projects) > 0 {
+		userID := strconv.Itoa(m.App.Session.Get(r.Context(), "user_id").(int))
+		go m.ReconcileProjectsRoutine(projectNumbers(user.Projects), userID)
+	}
+
 	m.App.Session.Put(r.Context(), "flash", "User deleted!")
 	http.Redirect(w, r, "/app/admin/users", http.StatusSeeOther)
 }
@@ -0,0 +1,538 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/nickzer0/GoBoxer/internal/blueprint"
+	"github.com/nickzer0/GoBoxer/internal/domains"
+	"github.com/nickzer0/GoBoxer/internal/helpers"
+	"github.com/nickzer0/GoBoxer/internal/models"
+	"github.com/nickzer0/GoBoxer/internal/redirectors"
+	"github.com/nickzer0/GoBoxer/internal/server"
+)
+
+// blueprintBuild is a blueprint resolved against a project, checked before anything is created
+type blueprintBuild struct {
+	Name      string
+	Spec      blueprint.Spec
+	Project   int
+	Domain    models.Domains
+	Instances []blueprint.Instance
+	// Servers holds the server to create for each instance, keyed by instance key
+	Servers map[string]models.Server
+}
+
+// Blueprints displays a list of stored blueprints.
+func (m *Repository) Blueprints(w http.ResponseWriter, r *http.Request) {
+	blueprints, err := m.DB.ListBlueprints()
+	if err != nil {
+		log.Printf("Error listing blueprints: %v", err)
+		printErrorPage(w, err)
+		return
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("blueprints", blueprints)
+
+	if err := helpers.RenderPage(w, r, "blueprints", vars, nil); err != nil {
+		log.Printf("Error rendering blueprints page: %v", err)
+		printTemplateError(w, err)
+	}
+}
+
+// BlueprintsAdd displays the editor for a new blueprint, starting from an example.
+func (m *Repository) BlueprintsAdd(w http.ResponseWriter, r *http.Request) {
+	vars := make(jet.VarMap)
+	vars.Set("blueprint", models.Blueprint{Spec: blueprint.Example})
+
+	if err := helpers.RenderPage(w, r, "blueprints-add", vars, nil); err != nil {
+		log.Printf("Error rendering blueprints-add page: %v", err)
+		printTemplateError(w, err)
+	}
+}
+
+// BlueprintsAddPost validates and saves a new blueprint.
+func (m *Repository) BlueprintsAddPost(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Error parsing form: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Failed to process form data.")
+		http.Redirect(w, r, "/app/blueprints/add", http.StatusSeeOther)
+		return
+	}
+
+	newBlueprint := models.Blueprint{
+		Name:        strings.TrimSpace(r.Form.Get("name")),
+		Description: r.Form.Get("description"),
+		Spec:        r.Form.Get("spec"),
+		CreatedBy:   m.App.Session.Get(r.Context(), "username").(string),
+		CreatedAt:   time.Now(),
+	}
+
+	if newBlueprint.Name == "" {
+		m.App.Session.Put(r.Context(), "error", "Blueprint name is required.")
+		m.renderBlueprintForm(w, r, newBlueprint)
+		return
+	}
+
+	if _, err := blueprint.Parse(newBlueprint.Spec); err != nil {
+		m.App.Session.Put(r.Context(), "error", fmt.Sprintf("Invalid blueprint: %v", err))
+		m.renderBlueprintForm(w, r, newBlueprint)
+		return
+	}
+
+	saved, err := m.DB.AddBlueprint(newBlueprint)
+	if err != nil {
+		log.Printf("Error adding blueprint: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Failed to save blueprint.")
+		m.renderBlueprintForm(w, r, newBlueprint)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Blueprint saved.")
+	http.Redirect(w, r, fmt.Sprintf("/app/blueprints/%d", saved.ID), http.StatusSeeOther)
+}
+
+// renderBlueprintForm shows the add form again with what was submitted, so edits are not lost on a validation error
+func (m *Repository) renderBlueprintForm(w http.ResponseWriter, r *http.Request, submitted models.Blueprint) {
+	vars := make(jet.VarMap)
+	vars.Set("blueprint", submitted)
+
+	if err := helpers.RenderPage(w, r, "blueprints-add", vars, nil); err != nil {
+		log.Printf("Error rendering blueprints-add page: %v", err)
+		printTemplateError(w, err)
+	}
+}
+
+// ViewBlueprint displays a blueprint for editing, along with what it would create and a form to build it into a project.
+func (m *Repository) ViewBlueprint(w http.ResponseWriter, r *http.Request) {
+	exploded := strings.Split(r.RequestURI, "/")
+	if len(exploded) < 4 {
+		log.Printf("Invalid blueprint ID in URL")
+		http.Redirect(w, r, "/app/blueprints", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.Atoi(exploded[3])
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Invalid blueprint ID.")
+		http.Redirect(w, r, "/app/blueprints", http.StatusSeeOther)
+		return
+	}
+
+	stored, err := m.DB.GetBlueprint(id)
+	if err != nil {
+		log.Printf("Error getting blueprint %d: %v", id, err)
+		m.App.Session.Put(r.Context(), "error", "Blueprint not found.")
+		http.Redirect(w, r, "/app/blueprints", http.StatusSeeOther)
+		return
+	}
+
+	username := m.App.Session.Get(r.Context(), "username").(string)
+	projects, err := m.DB.GetProjectsForUser(username)
+	if err != nil {
+		log.Printf("Error getting projects for user %s: %v", username, err)
+		printErrorPage(w, err)
+		return
+	}
+
+	// A blueprint that no longer validates, for example after a provider is removed, can still be edited
+	spec, err := blueprint.Parse(stored.Spec)
+	specError := ""
+	if err != nil {
+		specError = err.Error()
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("blueprint", stored)
+	vars.Set("spec", spec)
+	vars.Set("spec_error", specError)
+	vars.Set("instances", spec.Instances(0))
+	vars.Set("projects", projects)
+
+	if err := helpers.RenderPage(w, r, "blueprints-view", vars, nil); err != nil {
+		log.Printf("Error rendering blueprints-view page: %v", err)
+		printTemplateError(w, err)
+	}
+}
+
+// UpdateBlueprint validates and saves changes to a blueprint.
+func (m *Repository) UpdateBlueprint(w http.ResponseWriter, r *http.Request) {
+	exploded := strings.Split(r.RequestURI, "/")
+	if len(exploded) < 5 {
+		log.Printf("Invalid blueprint ID in URL")
+		http.Redirect(w, r, "/app/blueprints", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.Atoi(exploded[4])
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Invalid blueprint ID.")
+		http.Redirect(w, r, "/app/blueprints", http.StatusSeeOther)
+		return
+	}
+	redirectURL := fmt.Sprintf("/app/blueprints/%d", id)
+
+	stored, err := m.DB.GetBlueprint(id)
+	if err != nil {
+		log.Printf("Error getting blueprint %d: %v", id, err)
+		m.App.Session.Put(r.Context(), "error", "Blueprint not found.")
+		http.Redirect(w, r, "/app/blueprints", http.StatusSeeOther)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Error parsing form: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Failed to process form data.")
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		return
+	}
+
+	if _, err := blueprint.Parse(r.Form.Get("spec")); err != nil {
+		m.App.Session.Put(r.Context(), "error", fmt.Sprintf("Blueprint not saved, it is invalid: %v", err))
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		return
+	}
+
+	if name := strings.TrimSpace(r.Form.Get("name")); name != "" {
+		stored.Name = name
+	}
+	stored.Description = r.Form.Get("description")
+	stored.Spec = r.Form.Get("spec")
+
+	if err := m.DB.UpdateBlueprint(stored); err != nil {
+		log.Printf("Error updating blueprint %d: %v", id, err)
+		m.App.Session.Put(r.Context(), "error", "Failed to save blueprint.")
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Blueprint updated.")
+	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+}
+
+// RemoveBlueprint deletes a blueprint. Anything already built from it is left in place.
+func (m *Repository) RemoveBlueprint(w http.ResponseWriter, r *http.Request) {
+	exploded := strings.Split(r.RequestURI, "/")
+	if len(exploded) < 5 {
+		log.Printf("Invalid blueprint ID in URL")
+		http.Redirect(w, r, "/app/blueprints", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.Atoi(exploded[4])
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Invalid blueprint ID.")
+		http.Redirect(w, r, "/app/blueprints", http.StatusSeeOther)
+		return
+	}
+
+	if err := m.DB.DeleteBlueprint(id); err != nil {
+		log.Printf("Error deleting blueprint %d: %v", id, err)
+		m.App.Session.Put(r.Context(), "error", "Failed to remove blueprint.")
+		http.Redirect(w, r, "/app/blueprints", http.StatusSeeOther)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Blueprint removed.")
+	http.Redirect(w, r, "/app/blueprints", http.StatusSeeOther)
+}
+
+// InstantiateBlueprint builds a blueprint into one of the user's projects in the background.
+func (m *Repository) InstantiateBlueprint(w http.ResponseWriter, r *http.Request) {
+	userID := strconv.Itoa(m.App.Session.Get(r.Context(), "user_id").(int))
+	username := m.App.Session.Get(r.Context(), "username").(string)
+
+	exploded := strings.Split(r.RequestURI, "/")
+	if len(exploded) < 5 {
+		log.Printf("Invalid blueprint ID in URL")
+		http.Redirect(w, r, "/app/blueprints", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.Atoi(exploded[4])
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Invalid blueprint ID.")
+		http.Redirect(w, r, "/app/blueprints", http.StatusSeeOther)
+		return
+	}
+	redirectURL := fmt.Sprintf("/app/blueprints/%d", id)
+
+	if m.App.Session.Get(r.Context(), "ssh_key") == "0" {
+		m.App.Session.Put(r.Context(), "error", "No SSH Key set for user!")
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Error parsing form: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Failed to process form data.")
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		return
+	}
+
+	project, err := strconv.Atoi(r.Form.Get("assign_project"))
+	if err != nil || !m.userInProject(username, project) {
+		m.App.Session.Put(r.Context(), "error", "Invalid project selection.")
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		return
+	}
+
+	stored, err := m.DB.GetBlueprint(id)
+	if err != nil {
+		log.Printf("Error getting blueprint %d: %v", id, err)
+		m.App.Session.Put(r.Context(), "error", "Blueprint not found.")
+		http.Redirect(w, r, "/app/blueprints", http.StatusSeeOther)
+		return
+	}
+
+	build, err := m.resolveBlueprint(stored.Name, stored.Spec, project, username)
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", fmt.Sprintf("Blueprint cannot be built: %v", err))
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		return
+	}
+
+	go m.InstantiateBlueprintRoutine(build, userID)
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Building blueprint %s into project %d...", stored.Name, project))
+	http.Redirect(w, r, fmt.Sprintf("/app/projects/%d", project), http.StatusSeeOther)
+}
+
+// userInProject reports whether the user is assigned to the project
+func (m *Repository) userInProject(username string, project int) bool {
+	projects, err := m.DB.GetProjectsForUser(username)
+	if err != nil {
+		log.Printf("Error getting projects for user %s: %v", username, err)
+		return false
+	}
+	for _, p := range projects {
+		if p.ProjectNumber == project {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveBlueprint checks a blueprint can be built into a project and works out every server it creates,
+// so that a bad firewall or domain is reported before anything is deployed
+func (m *Repository) resolveBlueprint(name, specText string, project int, username string) (blueprintBuild, error) {
+	spec, err := blueprint.Parse(specText)
+	if err != nil {
+		return blueprintBuild{}, err
+	}
+
+	build := blueprintBuild{
+		Name:      name,
+		Spec:      spec,
+		Project:   project,
+		Instances: spec.Instances(project),
+		Servers:   make(map[string]models.Server),
+	}
+
+	if spec.Domain != "" {
+		build.Domain, err = m.DB.GetDomainFromDatabase(spec.Domain)
+		if err != nil {
+			return build, fmt.Errorf("domain %s is not managed here", spec.Domain)
+		}
+		if _, err := m.DB.GetAWSHostedZone(spec.Domain); err != nil {
+			return build, fmt.Errorf("domain %s has no Route 53 hosted zone", spec.Domain)
+		}
+	}
+
+	expiresAt, err := m.resolveServerTTL(project, "")
+	if err != nil {
+		return build, fmt.Errorf("error getting project server lifetime: %v", err)
+	}
+
+	for _, instance := range build.Instances {
+		if !hostnamePattern.MatchString(instance.Hostname) {
+			return build, fmt.Errorf("server %s has an invalid hostname %s", instance.Key, instance.Hostname)
+		}
+
+		provider, err := server.GetProvider(instance.Provider)
+		if err != nil {
+			return build, err
+		}
+
+		// Servers at providers without a cloud firewall are left to the Ansible roles
+		firewall := 0
+		if _, ok := provider.(server.Firewaller); ok {
+			firewall, err = m.blueprintFirewall(project, instance.Firewall)
+			if err != nil {
+				return build, fmt.Errorf("server %s: %v", instance.Key, err)
+			}
+		}
+
+		cloudInit, err := m.cloudInitByName(instance.CloudInit)
+		if err != nil {
+			return build, fmt.Errorf("server %s: %v", instance.Key, err)
+		}
+
+		build.Servers[instance.Key] = models.Server{
+			OS:        serverOS(provider, instance.Image),
+			IP:        "Pending",
+			Provider:  provider.Name(),
+			Name:      instance.Hostname,
+			Region:    instance.Region,
+			Size:      instance.Size,
+			Image:     instance.Image,
+			Status:    "Deploying",
+			Roles:     instance.Roles,
+			Firewall:  firewall,
+			CloudInit: cloudInit,
+			Project:   project,
+			Creator:   username,
+			CreatedAt: time.Now(),
+			ExpiresAt: expiresAt,
+		}
+	}
+
+	return build, nil
+}
+
+// blueprintFirewall returns the ID of the firewall policy a blueprint names, the project default
+// when it names none, or 0 for "none"
+func (m *Repository) blueprintFirewall(project int, name string) (int, error) {
+	switch name {
+	case "":
+		return m.resolveFirewallPolicy(project, "")
+	case "none":
+		return 0, nil
+	}
+
+	policies, err := m.DB.GetFirewallPoliciesForProject(project)
+	if err != nil {
+		return 0, err
+	}
+	for _, policy := range policies {
+		if policy.Name == name {
+			return policy.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("no firewall policy named %s in project %d", name, project)
+}
+
+// InstantiateBlueprintRoutine builds a blueprint in dependency order: servers, then the DNS records
+// pointing at them, then redirectors fronting those records, then the records pointing at redirectors.
+// It stops at the first failure, leaving what was built for the user to inspect or remove.
+func (m *Repository) InstantiateBlueprintRoutine(build blueprintBuild, userID string) {
+	m.SendMessage(userID, fmt.Sprintf("Blueprint %s: creating %d servers...", build.Name, len(build.Instances)))
+
+	var keys []string
+	for _, instance := range build.Instances {
+		keys = append(keys, instance.Key)
+	}
+	addresses, err := m.deployBlueprintServers(build, keys, userID)
+	if err != nil {
+		m.SendError(userID, fmt.Sprintf("Blueprint %s stopped: %v", build.Name, err))
+		return
+	}
+
+	// Records pointing at servers or fixed data come first, redirectors use them as their origin
+	for _, record := range build.Spec.Records {
+		if record.Redirector != "" {
+			continue
+		}
+		data := record.Data
+		if record.Server != "" {
+			data = addresses[record.Server]
+		}
+		if err := m.upsertBlueprintRecord(build, record, data); err != nil {
+			m.SendError(userID, fmt.Sprintf("Blueprint %s stopped: %v", build.Name, err))
+			return
+		}
+	}
+
+	redirectorURLs := make(map[string]string)
+	for _, redirector := range build.Spec.Redirectors {
+		newRedirector, err := m.createBlueprintRedirector(build, redirector)
+		if err != nil {
+			m.SendError(userID, fmt.Sprintf("Blueprint %s stopped: %v", build.Name, err))
+			return
+		}
+		redirectorURLs[redirector.Name] = newRedirector.URL
+	}
+
+	for _, record := range build.Spec.Records {
+		if record.Redirector == "" {
+			continue
+		}
+		if err := m.upsertBlueprintRecord(build, record, redirectorURLs[record.Redirector]); err != nil {
+			m.SendError(userID, fmt.Sprintf("Blueprint %s stopped: %v", build.Name, err))
+			return
+		}
+	}
+
+	m.SendMessage(userID, fmt.Sprintf("Blueprint %s built into project %d, servers are still being provisioned.", build.Name, build.Project))
+}
+
+// deployBlueprintServers adds the servers of the given instances to the database and deploys them, returning
+// the IP address of each by instance key. Provisioning of their roles carries on in the background.
+func (m *Repository) deployBlueprintServers(build blueprintBuild, keys []string, userID string) (map[string]string, error) {
+	var queued []models.Server
+	byID := make(map[int]string)
+	for _, key := range keys {
+		databaseServer, err := m.DB.AddServerToDatabase(build.Servers[key])
+		if err != nil {
+			log.Printf("Error adding server %s to database: %v", build.Servers[key].Name, err)
+			return nil, fmt.Errorf("failed to add server %s to database", build.Servers[key].Name)
+		}
+		queued = append(queued, databaseServer)
+		byID[databaseServer.ID] = key
+	}
+
+	m.createServers(queued, userID)
+
+	// Servers that failed to deploy are removed from the database by CreateServerRoutine
+	addresses := make(map[string]string)
+	var failed []string
+	for _, vps := range queued {
+		deployed, err := m.DB.GetServer(vps.ID)
+		if err != nil || deployed.IP == "" || deployed.IP == "Pending" {
+			failed = append(failed, vps.Name)
+			continue
+		}
+		addresses[byID[vps.ID]] = deployed.IP
+	}
+	if len(failed) > 0 {
+		return addresses, fmt.Errorf("servers %s failed to deploy", strings.Join(failed, ", "))
+	}
+	return addresses, nil
+}
+
+// createBlueprintRedirector creates a CloudFront distribution in front of a redirector's origin record
+func (m *Repository) createBlueprintRedirector(build blueprintBuild, redirector blueprint.Redirector) (models.Redirector, error) {
+	origin, _ := build.Spec.Record(redirector.Origin)
+	newRedirector, err := redirectors.Repo.CreateCloudfrontDomain(models.Redirector{
+		Domain:  build.Spec.RecordName(origin),
+		Project: build.Project,
+	})
+	if err != nil {
+		log.Printf("Error creating Cloudfront domain for %s: %v", redirector.Name, err)
+		return newRedirector, fmt.Errorf("failed to create redirector %s", redirector.Name)
+	}
+	m.startRedirectorCost(newRedirector)
+	go m.WaitUntilReadyRoutine(newRedirector)
+	return newRedirector, nil
+}
+
+// upsertBlueprintRecord points a blueprint record at its data in Route 53
+func (m *Repository) upsertBlueprintRecord(build blueprintBuild, record blueprint.Record, data string) error {
+	err := domains.Repo.UpsertDNSRecordInAWS(build.Domain, models.DNS{
+		Name: record.Name,
+		Type: record.Type,
+		Ttl:  record.TTL,
+		Data: data,
+	})
+	if err != nil {
+		log.Printf("Error adding DNS record %s: %v", build.Spec.RecordName(record), err)
+		return fmt.Errorf("failed to add DNS record %s", build.Spec.RecordName(record))
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/nickzer0/GoBoxer/internal/helpers"
+	"github.com/nickzer0/GoBoxer/internal/models"
+	"github.com/nickzer0/GoBoxer/internal/server"
+)
+
+// bulkMaxServers is the most servers a single bulk deployment can create
+const bulkMaxServers = 50
+
+// bulkConcurrency is how many servers of a bulk deployment are created at once, to stay inside provider rate limits
+const bulkConcurrency = 5
+
+// hostnamePattern matches the hostnames generated from a naming pattern
+var hostnamePattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9.-]*[a-zA-Z0-9])?$`)
+
+// bulkTarget is a provider, region, size and image that servers of a bulk deployment are spread across
+type bulkTarget struct {
+	Provider server.CloudProvider
+	Region   string
+	Size     string
+	Image    string
+}
+
+// bulkHostname expands a naming pattern for the nth server. {n} is the server number, {provider} and
+// {region} where it is deployed. Patterns without {n} have the number appended.
+func bulkHostname(pattern string, n int, target bulkTarget) string {
+	if !strings.Contains(pattern, "{n}") {
+		pattern += "-{n}"
+	}
+	return strings.NewReplacer(
+		"{n}", fmt.Sprintf("%02d", n),
+		"{provider}", target.Provider.Name(),
+		"{region}", target.Region,
+	).Replace(pattern)
+}
+
+// parseBulkTargets reads the provider, region, size and image of each target row in the bulk form
+func parseBulkTargets(r *http.Request) ([]bulkTarget, error) {
+	providers := r.PostForm["target_provider"]
+	regions := r.PostForm["target_region"]
+	sizes := r.PostForm["target_size"]
+	images := r.PostForm["target_image"]
+	if len(regions) != len(providers) || len(sizes) != len(providers) || len(images) != len(providers) {
+		return nil, fmt.Errorf("incomplete deployment targets")
+	}
+
+	var targets []bulkTarget
+	for i, name := range providers {
+		if name == "" {
+			continue
+		}
+		provider, err := server.GetProvider(name)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, bulkTarget{Provider: provider, Region: regions[i], Size: sizes[i], Image: images[i]})
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no deployment targets selected")
+	}
+	return targets, nil
+}
+
+// ServersBulk displays the form for deploying several servers at once.
+func (m *Repository) ServersBulk(w http.ResponseWriter, r *http.Request) {
+	vars, err := m.serverFormVars(r)
+	if err != nil {
+		printErrorPage(w, err)
+		return
+	}
+	vars.Set("max_servers", bulkMaxServers)
+
+	if err := helpers.RenderPage(w, r, "servers-bulk", vars, nil); err != nil {
+		log.Printf("Error rendering servers-bulk page: %v", err)
+		printTemplateError(w, err)
+	}
+}
+
+// ServersBulkPost processes the bulk deployment form, adding every server to the database and deploying them in the background.
+func (m *Repository) ServersBulkPost(w http.ResponseWriter, r *http.Request) {
+	userID := strconv.Itoa(m.App.Session.Get(r.Context(), "user_id").(int))
+	username := m.App.Session.Get(r.Context(), "username").(string)
+
+	if m.App.Session.Get(r.Context(), "ssh_key") == "0" {
+		m.App.Session.Put(r.Context(), "error", "No SSH Key set for user!")
+		http.Redirect(w, r, "/app/servers/bulk", http.StatusSeeOther)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Error parsing form: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Failed to process form data.")
+		http.Redirect(w, r, "/app/servers/bulk", http.StatusSeeOther)
+		return
+	}
+
+	count, err := strconv.Atoi(r.Form.Get("count"))
+	if err != nil || count < 1 || count > bulkMaxServers {
+		m.App.Session.Put(r.Context(), "error", fmt.Sprintf("Number of servers must be between 1 and %d.", bulkMaxServers))
+		http.Redirect(w, r, "/app/servers/bulk", http.StatusSeeOther)
+		return
+	}
+
+	project, err := strconv.Atoi(r.Form.Get("assign_project"))
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Invalid project selection.")
+		http.Redirect(w, r, "/app/servers/bulk", http.StatusSeeOther)
+		return
+	}
+
+	targets, err := parseBulkTargets(r)
+	if err != nil {
+		log.Printf("Error parsing bulk deployment targets: %v", err)
+		m.App.Session.Put(r.Context(), "error", fmt.Sprintf("Invalid deployment targets: %v", err))
+		http.Redirect(w, r, "/app/servers/bulk", http.StatusSeeOther)
+		return
+	}
+
+	firewallPolicy, err := m.resolveFirewallPolicy(project, r.Form.Get("firewall"))
+	if err != nil {
+		log.Printf("Error resolving firewall policy: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Invalid firewall policy selection.")
+		http.Redirect(w, r, "/app/servers/bulk", http.StatusSeeOther)
+		return
+	}
+
+	expiresAt, err := m.resolveServerTTL(project, r.Form.Get("ttl"))
+	if err != nil {
+		log.Printf("Error resolving server lifetime: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Invalid server lifetime.")
+		http.Redirect(w, r, "/app/servers/bulk", http.StatusSeeOther)
+		return
+	}
+
+	cloudInit, err := m.resolveCloudInit(r.Form.Get("cloud_init"))
+	if err != nil {
+		log.Printf("Error resolving cloud-init template: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Invalid cloud-init template selection.")
+		http.Redirect(w, r, "/app/servers/bulk", http.StatusSeeOther)
+		return
+	}
+
+	// Check every hostname before anything is deployed, so a bad pattern does not leave a partial deployment
+	pattern := strings.TrimSpace(r.Form.Get("pattern"))
+	var queued []models.Server
+	for i := 0; i < count; i++ {
+		target := targets[i%len(targets)]
+		hostname := bulkHostname(pattern, i+1, target)
+		if !hostnamePattern.MatchString(hostname) {
+			m.App.Session.Put(r.Context(), "error", fmt.Sprintf("Naming pattern gives an invalid hostname: %s", hostname))
+			http.Redirect(w, r, "/app/servers/bulk", http.StatusSeeOther)
+			return
+		}
+
+		// Servers at providers without a cloud firewall are left to the Ansible roles
+		firewall := 0
+		if _, ok := target.Provider.(server.Firewaller); ok {
+			firewall = firewallPolicy
+		}
+
+		queued = append(queued, models.Server{
+			OS:        serverOS(target.Provider, target.Image),
+			IP:        "Pending",
+			Provider:  target.Provider.Name(),
+			Name:      hostname,
+			Region:    target.Region,
+			Size:      target.Size,
+			Image:     target.Image,
+			Status:    "Deploying",
+			Roles:     r.PostForm["scripts"],
+			Firewall:  firewall,
+			CloudInit: cloudInit,
+			Project:   project,
+			Creator:   username,
+			CreatedAt: time.Now(),
+			ExpiresAt: expiresAt,
+		})
+	}
+
+	deployment := models.BulkDeployment{Project: project, CreatedBy: username}
+	for _, newServer := range queued {
+		databaseServer, err := m.DB.AddServerToDatabase(newServer)
+		if err != nil {
+			log.Printf("Error adding server %s to database: %v", newServer.Name, err)
+			m.SendError(userID, fmt.Sprintf("Failed to add server %s to database.", newServer.Name))
+			continue
+		}
+		deployment.Servers = append(deployment.Servers, databaseServer)
+	}
+
+	// The servers are deployed either way, only the progress page is lost if the deployment is not saved
+	redirectURL := "/app/servers"
+	deployment, err = m.DB.AddBulkDeployment(deployment)
+	if err != nil {
+		log.Printf("Error adding bulk deployment to database: %v", err)
+	} else {
+		redirectURL = fmt.Sprintf("/app/servers/bulk/%d", deployment.ID)
+	}
+
+	go m.BulkCreateServersRoutine(deployment.Servers, userID)
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Deploying %d servers...", len(deployment.Servers)))
+	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+}
+
+// BulkCreateServersRoutine deploys the servers of a bulk deployment.
+func (m *Repository) BulkCreateServersRoutine(servers []models.Server, userID string) {
+	m.createServers(servers, userID)
+	m.SendMessage(userID, fmt.Sprintf("Bulk deployment of %d servers finished.", len(servers)))
+}
+
+// createServers deploys servers a few at a time, returning once each has been deployed or has failed.
+// Provisioning of their roles carries on in the background.
+func (m *Repository) createServers(servers []models.Server, userID string) {
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, bulkConcurrency)
+
+	for _, newServer := range servers {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(newServer models.Server) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			m.CreateServerRoutine(newServer, userID)
+		}(newServer)
+	}
+
+	wg.Wait()
+}
+
+// ViewBulkDeployment shows the progress of each server in a bulk deployment.
+func (m *Repository) ViewBulkDeployment(w http.ResponseWriter, r *http.Request) {
+	exploded := strings.Split(r.RequestURI, "/")
+	if len(exploded) < 5 {
+		log.Printf("Invalid bulk deployment ID in URL")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.Atoi(exploded[4])
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Invalid bulk deployment ID.")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+
+	deployment, err := m.DB.GetBulkDeployment(id)
+	if err != nil {
+		log.Printf("Error getting bulk deployment %d: %v", id, err)
+		m.App.Session.Put(r.Context(), "error", "Bulk deployment not found.")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+
+	// Servers that failed to deploy are removed from the database, so show them as failed
+	var servers []models.Server
+	for _, queued := range deployment.Servers {
+		current, err := m.DB.GetServer(queued.ID)
+		if err != nil {
+			queued.Status = "Failed"
+			current = queued
+		}
+		servers = append(servers, current)
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("deployment", deployment)
+	vars.Set("servers", servers)
+
+	if err := helpers.RenderPage(w, r, "servers-bulk-view", vars, nil); err != nil {
+		log.Printf("Error rendering servers-bulk-view page: %v", err)
+		printTemplateError(w, err)
+	}
+}
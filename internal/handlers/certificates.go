@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/nickzer0/GoBoxer/internal/credentials"
+	"github.com/nickzer0/GoBoxer/internal/deploy"
+	"github.com/nickzer0/GoBoxer/internal/helpers"
+	"github.com/nickzer0/GoBoxer/internal/models"
+	"github.com/nickzer0/GoBoxer/internal/sshca"
+)
+
+// defaultCertificateHours is how long certificates are valid for unless the operator asks otherwise
+const defaultCertificateHours = 8
+
+// Certificates displays the form operators request SSH certificates for a project's servers with.
+func (m *Repository) Certificates(w http.ResponseWriter, r *http.Request) {
+	project, _ := strconv.Atoi(r.URL.Query().Get("project"))
+	m.renderCertificates(w, r, project, defaultCertificateHours, nil)
+}
+
+// IssueCertificate signs the operator's SSH key for root on the servers of one of their projects.
+// Certificates are short-lived, so removing someone from a project ends their access once it expires.
+func (m *Repository) IssueCertificate(w http.ResponseWriter, r *http.Request) {
+	username := m.App.Session.Get(r.Context(), "username").(string)
+
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Error parsing form: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Invalid form submission.")
+		http.Redirect(w, r, "/app/certificates", http.StatusSeeOther)
+		return
+	}
+
+	project, err := strconv.Atoi(r.Form.Get("project"))
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Select a project.")
+		http.Redirect(w, r, "/app/certificates", http.StatusSeeOther)
+		return
+	}
+	redirectURL := fmt.Sprintf("/app/certificates?project=%d", project)
+
+	hours, err := strconv.Atoi(r.Form.Get("validity"))
+	if err != nil || hours < 1 || time.Duration(hours)*time.Hour > sshca.MaxValidity {
+		m.App.Session.Put(r.Context(), "error", fmt.Sprintf("Validity must be between 1 and %d hours.", int(sshca.MaxValidity.Hours())))
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		return
+	}
+
+	// Membership is checked on every request, which is what revokes access when someone leaves a project
+	if !m.userInProject(username, project) {
+		m.App.Session.Put(r.Context(), "error", "You are not assigned to that project.")
+		http.Redirect(w, r, "/app/certificates", http.StatusSeeOther)
+		return
+	}
+
+	publicKey := strings.TrimSpace(r.Form.Get("ssh_key"))
+	if publicKey == "" {
+		m.App.Session.Put(r.Context(), "error", "An SSH public key is required.")
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		return
+	}
+
+	encrypted, err := m.DB.GetSecret("ssh_ca_private_key")
+	if err != nil {
+		log.Printf("Error getting SSH CA private key: %v", err)
+		m.App.Session.Put(r.Context(), "error", "The SSH CA is not configured.")
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		return
+	}
+	caPrivateKey, err := credentials.Decrypt(m.App.CredentialKey, encrypted)
+	if err != nil {
+		log.Printf("Error decrypting SSH CA private key: %v", err)
+		m.App.Session.Put(r.Context(), "error", "The SSH CA key could not be read.")
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		return
+	}
+
+	request := sshca.Request{
+		PublicKey: publicKey,
+		KeyID:     fmt.Sprintf("%s@goboxer-%s", username, sshca.Principal(project)),
+		Project:   project,
+		Validity:  time.Duration(hours) * time.Hour,
+	}
+	certificate, validBefore, err := sshca.Sign(caPrivateKey, request)
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", fmt.Sprintf("Could not issue certificate: %v", err))
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		return
+	}
+	log.Printf("Issued SSH certificate %s valid until %s", request.KeyID, validBefore.Format(time.RFC3339))
+
+	issued := map[string]interface{}{
+		"Certificate": certificate,
+		"ValidBefore": validBefore,
+		"Principal":   sshca.Principal(project),
+	}
+	m.renderCertificates(w, r, project, hours, issued)
+}
+
+// renderCertificates renders the certificate page, with the certificate just issued if there is one
+func (m *Repository) renderCertificates(w http.ResponseWriter, r *http.Request, project, hours int, issued map[string]interface{}) {
+	username := m.App.Session.Get(r.Context(), "username").(string)
+	userID := m.App.Session.Get(r.Context(), "user_id").(int)
+
+	user, err := m.DB.GetUserFromID(userID)
+	if err != nil {
+		log.Printf("Error fetching user from ID: %v", err)
+		printErrorPage(w, err)
+		return
+	}
+
+	projects, err := m.DB.GetProjectsForUser(username)
+	if err != nil {
+		log.Printf("Error getting projects for user %s: %v", username, err)
+		printErrorPage(w, err)
+		return
+	}
+
+	caPublicKey, err := m.DB.GetSecret("ssh_ca_public_key")
+	if err != nil {
+		log.Printf("Error getting SSH CA public key: %v", err)
+	}
+
+	var servers []models.Server
+	if issued != nil {
+		all, err := m.DB.ListAllServers()
+		if err != nil {
+			log.Printf("Error listing servers: %v", err)
+		}
+		for _, vps := range all {
+			if vps.Project == project {
+				servers = append(servers, vps)
+			}
+		}
+	}
+
+	// A certificate covers a single key, so the first one on the user's profile is offered
+	sshKey := ""
+	if keys := user.AuthorizedKeys(); len(keys) > 0 {
+		sshKey = keys[0]
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("projects", projects)
+	vars.Set("project", project)
+	vars.Set("validity", hours)
+	vars.Set("max_validity", int(sshca.MaxValidity.Hours()))
+	vars.Set("ssh_key", sshKey)
+	vars.Set("operator_account", deploy.OperatorAccount(userID, username))
+	vars.Set("has_key", sshKey != "")
+	vars.Set("ca_public_key", caPublicKey)
+	vars.Set("issued", issued)
+	vars.Set("servers", servers)
+
+	if err := helpers.RenderPage(w, r, "certificates", vars, nil); err != nil {
+		log.Printf("Error rendering certificates page: %v", err)
+		printTemplateError(w, err)
+	}
+}
@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/nickzer0/GoBoxer/internal/cloudinit"
+	"github.com/nickzer0/GoBoxer/internal/helpers"
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
+
+// CloudInit displays a list of stored cloud-init templates.
+func (m *Repository) CloudInit(w http.ResponseWriter, r *http.Request) {
+	templates, err := m.DB.ListCloudInitTemplates()
+	if err != nil {
+		log.Printf("Error listing cloud-init templates: %v", err)
+		printErrorPage(w, err)
+		return
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("templates", templates)
+
+	if err := helpers.RenderPage(w, r, "cloudinit", vars, nil); err != nil {
+		log.Printf("Error rendering cloudinit page: %v", err)
+		printTemplateError(w, err)
+	}
+}
+
+// CloudInitAdd displays the editor for a new cloud-init template, starting from an example.
+func (m *Repository) CloudInitAdd(w http.ResponseWriter, r *http.Request) {
+	m.renderCloudInitForm(w, r, models.CloudInitTemplate{Content: cloudinit.Example})
+}
+
+// CloudInitAddPost validates and saves a new cloud-init template.
+func (m *Repository) CloudInitAddPost(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Error parsing form: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Failed to process form data.")
+		http.Redirect(w, r, "/app/cloudinit/add", http.StatusSeeOther)
+		return
+	}
+
+	newTemplate := models.CloudInitTemplate{
+		Name:        strings.TrimSpace(r.Form.Get("name")),
+		Description: r.Form.Get("description"),
+		Content:     normaliseUserData(r.Form.Get("content")),
+		CreatedBy:   m.App.Session.Get(r.Context(), "username").(string),
+		CreatedAt:   time.Now(),
+	}
+
+	if newTemplate.Name == "" {
+		m.App.Session.Put(r.Context(), "error", "Template name is required.")
+		m.renderCloudInitForm(w, r, newTemplate)
+		return
+	}
+
+	if err := cloudinit.Validate(newTemplate.Content); err != nil {
+		m.App.Session.Put(r.Context(), "error", fmt.Sprintf("Invalid template: %v", err))
+		m.renderCloudInitForm(w, r, newTemplate)
+		return
+	}
+
+	saved, err := m.DB.AddCloudInitTemplate(newTemplate)
+	if err != nil {
+		log.Printf("Error adding cloud-init template: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Failed to save template.")
+		m.renderCloudInitForm(w, r, newTemplate)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Cloud-init template saved.")
+	http.Redirect(w, r, fmt.Sprintf("/app/cloudinit/%d", saved.ID), http.StatusSeeOther)
+}
+
+// renderCloudInitForm shows the add form with the given template, so edits are not lost on a validation error
+func (m *Repository) renderCloudInitForm(w http.ResponseWriter, r *http.Request, submitted models.CloudInitTemplate) {
+	vars := make(jet.VarMap)
+	vars.Set("template", submitted)
+
+	if err := helpers.RenderPage(w, r, "cloudinit-add", vars, nil); err != nil {
+		log.Printf("Error rendering cloudinit-add page: %v", err)
+		printTemplateError(w, err)
+	}
+}
+
+// ViewCloudInit displays a cloud-init template for editing, along with how it renders.
+func (m *Repository) ViewCloudInit(w http.ResponseWriter, r *http.Request) {
+	exploded := strings.Split(r.RequestURI, "/")
+	if len(exploded) < 4 {
+		log.Printf("Invalid template ID in URL")
+		http.Redirect(w, r, "/app/cloudinit", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.Atoi(exploded[3])
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Invalid template ID.")
+		http.Redirect(w, r, "/app/cloudinit", http.StatusSeeOther)
+		return
+	}
+
+	stored, err := m.DB.GetCloudInitTemplate(id)
+	if err != nil {
+		log.Printf("Error getting cloud-init template %d: %v", id, err)
+		m.App.Session.Put(r.Context(), "error", "Template not found.")
+		http.Redirect(w, r, "/app/cloudinit", http.StatusSeeOther)
+		return
+	}
+
+	// The preview uses the current user's key, so it shows what a server they create would receive
+	currentUser := m.App.Session.Get(r.Context(), "user").(models.User)
+	preview, err := cloudinit.Render(stored.Content, cloudinit.Vars{
+		Hostname:     "preview",
+		ProjectName:  "Preview",
+		OperatorKeys: currentUser.AuthorizedKeys(),
+	})
+	previewError := ""
+	if err != nil {
+		previewError = err.Error()
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("template", stored)
+	vars.Set("preview", preview)
+	vars.Set("preview_error", previewError)
+
+	if err := helpers.RenderPage(w, r, "cloudinit-view", vars, nil); err != nil {
+		log.Printf("Error rendering cloudinit-view page: %v", err)
+		printTemplateError(w, err)
+	}
+}
+
+// UpdateCloudInit validates and saves changes to a cloud-init template. Servers already created are not changed.
+func (m *Repository) UpdateCloudInit(w http.ResponseWriter, r *http.Request) {
+	exploded := strings.Split(r.RequestURI, "/")
+	if len(exploded) < 5 {
+		log.Printf("Invalid template ID in URL")
+		http.Redirect(w, r, "/app/cloudinit", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.Atoi(exploded[4])
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Invalid template ID.")
+		http.Redirect(w, r, "/app/cloudinit", http.StatusSeeOther)
+		return
+	}
+	redirectURL := fmt.Sprintf("/app/cloudinit/%d", id)
+
+	stored, err := m.DB.GetCloudInitTemplate(id)
+	if err != nil {
+		log.Printf("Error getting cloud-init template %d: %v", id, err)
+		m.App.Session.Put(r.Context(), "error", "Template not found.")
+		http.Redirect(w, r, "/app/cloudinit", http.StatusSeeOther)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Error parsing form: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Failed to process form data.")
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		return
+	}
+
+	content := normaliseUserData(r.Form.Get("content"))
+	if err := cloudinit.Validate(content); err != nil {
+		m.App.Session.Put(r.Context(), "error", fmt.Sprintf("Template not saved, it is invalid: %v", err))
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		return
+	}
+
+	if name := strings.TrimSpace(r.Form.Get("name")); name != "" {
+		stored.Name = name
+	}
+	stored.Description = r.Form.Get("description")
+	stored.Content = content
+
+	if err := m.DB.UpdateCloudInitTemplate(stored); err != nil {
+		log.Printf("Error updating cloud-init template %d: %v", id, err)
+		m.App.Session.Put(r.Context(), "error", "Failed to save template.")
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Cloud-init template updated.")
+	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+}
+
+// RemoveCloudInit deletes a cloud-init template. Servers created with it keep running.
+func (m *Repository) RemoveCloudInit(w http.ResponseWriter, r *http.Request) {
+	exploded := strings.Split(r.RequestURI, "/")
+	if len(exploded) < 5 {
+		log.Printf("Invalid template ID in URL")
+		http.Redirect(w, r, "/app/cloudinit", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.Atoi(exploded[4])
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Invalid template ID.")
+		http.Redirect(w, r, "/app/cloudinit", http.StatusSeeOther)
+		return
+	}
+
+	if err := m.DB.DeleteCloudInitTemplate(id); err != nil {
+		log.Printf("Error deleting cloud-init template %d: %v", id, err)
+		m.App.Session.Put(r.Context(), "error", "Failed to remove template.")
+		http.Redirect(w, r, "/app/cloudinit", http.StatusSeeOther)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Cloud-init template removed.")
+	http.Redirect(w, r, "/app/cloudinit", http.StatusSeeOther)
+}
+
+// normaliseUserData strips the carriage returns browsers submit textareas with, which break #! scripts
+func normaliseUserData(content string) string {
+	return strings.ReplaceAll(content, "\r\n", "\n")
+}
+
+// resolveCloudInit returns the ID of the cloud-init template chosen on a form, or 0 for none
+func (m *Repository) resolveCloudInit(choice string) (int, error) {
+	if choice == "" {
+		return 0, nil
+	}
+
+	templateID, err := strconv.Atoi(choice)
+	if err != nil || templateID == 0 {
+		return 0, err
+	}
+
+	if _, err := m.DB.GetCloudInitTemplate(templateID); err != nil {
+		return 0, err
+	}
+	return templateID, nil
+}
+
+// cloudInitByName returns the ID of the cloud-init template a blueprint names, or 0 when it names none
+func (m *Repository) cloudInitByName(name string) (int, error) {
+	if name == "" {
+		return 0, nil
+	}
+
+	templates, err := m.DB.ListCloudInitTemplates()
+	if err != nil {
+		return 0, err
+	}
+	for _, template := range templates {
+		if template.Name == name {
+			return template.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("no cloud-init template named %s", name)
+}
+
+// serverUserData renders the cloud-init template a server is created with, giving it the SSH keys
+// of everyone assigned to its project along with its creator
+func (m *Repository) serverUserData(vps models.Server) (string, error) {
+	if vps.CloudInit == 0 {
+		return "", nil
+	}
+
+	template, err := m.DB.GetCloudInitTemplate(vps.CloudInit)
+	if err != nil {
+		return "", fmt.Errorf("error getting cloud-init template %d: %v", vps.CloudInit, err)
+	}
+
+	project, err := m.DB.GetProjectByNumber(vps.Project)
+	if err != nil {
+		return "", fmt.Errorf("error getting project %d: %v", vps.Project, err)
+	}
+
+	users, err := m.DB.GetUsers()
+	if err != nil {
+		return "", fmt.Errorf("error getting users: %v", err)
+	}
+
+	operators := map[string]bool{vps.Creator: true}
+	for _, username := range project.AssignedTo {
+		operators[username] = true
+	}
+	var keys []string
+	for _, user := range users {
+		if operators[user.Username] {
+			keys = append(keys, user.AuthorizedKeys()...)
+		}
+	}
+
+	return cloudinit.Render(template.Content, cloudinit.Vars{
+		Hostname:     vps.Name,
+		Project:      project.ProjectNumber,
+		ProjectName:  project.ProjectName,
+		Provider:     vps.Provider,
+		Region:       vps.Region,
+		OperatorKeys: keys,
+	})
+}
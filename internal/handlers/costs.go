@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/nickzer0/GoBoxer/internal/models"
+	"github.com/nickzer0/GoBoxer/internal/server"
+)
+
+// hoursPerYear converts yearly registrar prices into an hourly cost
+const hoursPerYear = 365 * 24
+
+// serverPriceHourly returns the hourly price of a server's plan size, or 0 if the provider does not list it
+func serverPriceHourly(vps models.Server) float64 {
+	provider, err := server.GetProvider(vps.Provider)
+	if err != nil {
+		return 0
+	}
+	catalog, err := server.GetCatalog(provider)
+	if err != nil {
+		log.Printf("Error getting catalog for %s: %v", provider.Name(), err)
+		return 0
+	}
+	size, ok := catalog.Size(vps.Size)
+	if !ok {
+		return 0
+	}
+	return size.PriceHourly
+}
+
+// domainPriceHourly spreads the yearly registration price of a domain over the hours in a year
+func domainPriceHourly(price string) float64 {
+	yearly, err := strconv.ParseFloat(price, 64)
+	if err != nil {
+		return 0
+	}
+	return yearly / hoursPerYear
+}
+
+// startCost starts recording the cost of a resource, logging rather than failing as
+// cost tracking should never stop a deployment
+func (m *Repository) startCost(cost models.Cost) {
+	if err := m.DB.AddCost(cost); err != nil {
+		log.Printf("Error recording cost of %s %s: %v", cost.ResourceType, cost.Name, err)
+	}
+}
+
+// startServerCost starts recording the cost of a deployed server from its plan size
+func (m *Repository) startServerCost(vps models.Server) {
+	m.startCost(models.Cost{
+		ResourceType: models.CostServer,
+		ResourceID:   vps.ID,
+		Name:         vps.Name,
+		Provider:     vps.Provider,
+		Project:      vps.Project,
+		PriceHourly:  serverPriceHourly(vps),
+		StartedAt:    time.Now(),
+	})
+}
+
+// startRedirectorCost starts recording the lifetime of a redirector. CloudFront bills per request
+// and transfer, so there is no fixed hourly price to record.
+func (m *Repository) startRedirectorCost(redirector models.Redirector) {
+	m.startCost(models.Cost{
+		ResourceType: models.CostRedirector,
+		ResourceID:   redirector.ID,
+		Name:         redirector.URL,
+		Provider:     redirector.Provider,
+		Project:      redirector.Project,
+		StartedAt:    time.Now(),
+	})
+}
+
+// endCost stops recording the cost of a removed resource
+func (m *Repository) endCost(resourceType string, id int) {
+	if err := m.DB.EndCost(resourceType, id); err != nil {
+		log.Printf("Error ending cost of %s %d: %v", resourceType, id, err)
+	}
+}
+
+// summariseSpend groups costs by the name returned for each, largest spend first
+func summariseSpend(costs []models.Cost, now time.Time, name func(models.Cost) string) []models.Spend {
+	groups := make(map[string]*models.Spend)
+	for _, cost := range costs {
+		key := name(cost)
+		spend, ok := groups[key]
+		if !ok {
+			spend = &models.Spend{Name: key}
+			groups[key] = spend
+		}
+		spend.Total += cost.Spend(now)
+		if cost.Running() {
+			spend.Running++
+			spend.PriceHourly += cost.PriceHourly
+		}
+	}
+
+	summary := make([]models.Spend, 0, len(groups))
+	for _, spend := range groups {
+		summary = append(summary, *spend)
+	}
+	sort.Slice(summary, func(i, j int) bool {
+		if summary[i].Total != summary[j].Total {
+			return summary[i].Total > summary[j].Total
+		}
+		return summary[i].Name < summary[j].Name
+	})
+	return summary
+}
+
+// spendReport returns spend for the given projects and, for admins, resources outside any project
+// such as domains, grouped by project and by provider
+func (m *Repository) spendReport(projects []models.Project, admin bool) (byProject, byProvider []models.Spend, err error) {
+	costs, err := m.DB.ListCosts()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	projectNames := make(map[int]string)
+	for _, project := range projects {
+		projectNames[project.ProjectNumber] = fmt.Sprintf("%d - %s", project.ProjectNumber, project.ProjectName)
+	}
+
+	var visible []models.Cost
+	for _, cost := range costs {
+		if _, ok := projectNames[cost.Project]; ok || admin {
+			visible = append(visible, cost)
+		}
+	}
+
+	now := time.Now()
+	byProject = summariseSpend(visible, now, func(cost models.Cost) string {
+		if name, ok := projectNames[cost.Project]; ok {
+			return name
+		}
+		if cost.Project == 0 {
+			return "Unassigned"
+		}
+		return fmt.Sprintf("%d - Deleted project", cost.Project)
+	})
+	byProvider = summariseSpend(visible, now, func(cost models.Cost) string {
+		if provider, err := server.GetProvider(cost.Provider); err == nil {
+			return provider.DisplayName()
+		}
+		return cost.Provider
+	})
+	return byProject, byProvider, nil
+}
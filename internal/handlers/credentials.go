@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/nickzer0/GoBoxer/internal/deploy"
+	"github.com/nickzer0/GoBoxer/internal/helpers"
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
+
+// rotateRootCredentials gives a server root credentials of its own, telling the user if it still trusts the shared root key
+func (m *Repository) rotateRootCredentials(vps models.Server, userID string) bool {
+	if err := deploy.RotateRootCredentials(vps); err != nil {
+		log.Printf("Error rotating root credentials of server %d: %v", vps.ID, err)
+		m.SendError(userID, fmt.Sprintf("Root credentials of %s were not rotated: %v", vps.Name, err))
+		return false
+	}
+	return true
+}
+
+// RotateServerCredentials rotates the root key and password of a server in the background.
+func (m *Repository) RotateServerCredentials(w http.ResponseWriter, r *http.Request) {
+	userID := strconv.Itoa(m.App.Session.Get(r.Context(), "user_id").(int))
+
+	exploded := strings.Split(r.RequestURI, "/")
+	if len(exploded) < 5 {
+		log.Printf("Invalid server ID in URL")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+
+	serverID, err := strconv.Atoi(exploded[4])
+	if err != nil {
+		log.Printf("Error converting server ID: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Invalid server ID.")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+
+	// The admin credentials page sends operators back to it
+	redirectURL := fmt.Sprintf("/app/servers/%d", serverID)
+	if r.FormValue("from") == "admin" {
+		redirectURL = "/app/admin/credentials"
+	}
+
+	vps, err := m.DB.GetServer(serverID)
+	if err != nil {
+		log.Printf("Error fetching server %d: %v", serverID, err)
+		m.App.Session.Put(r.Context(), "error", "Server not found.")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+
+	go func() {
+		if m.rotateRootCredentials(vps, userID) {
+			m.SendMessage(userID, fmt.Sprintf("Root credentials of %s rotated", vps.Name))
+		}
+	}()
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Rotating root credentials of %s...", vps.Name))
+	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+}
+
+// Credentials displays when the root credentials of every server were last rotated.
+func (m *Repository) Credentials(w http.ResponseWriter, r *http.Request) {
+	serverCredentials, err := m.DB.ListServerCredentials()
+	if err != nil {
+		log.Printf("Error listing server credentials: %v", err)
+		printErrorPage(w, err)
+		return
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("credentials", serverCredentials)
+
+	if err := helpers.RenderPage(w, r, "credentials", vars, nil); err != nil {
+		log.Printf("Error rendering credentials page: %v", err)
+		printTemplateError(w, err)
+	}
+}
+
+// RotateAllCredentials rotates the root credentials of every server in the background, one at a time.
+func (m *Repository) RotateAllCredentials(w http.ResponseWriter, r *http.Request) {
+	userID := strconv.Itoa(m.App.Session.Get(r.Context(), "user_id").(int))
+
+	servers, err := m.DB.ListAllServers()
+	if err != nil {
+		log.Printf("Error listing servers: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Failed to list servers.")
+		http.Redirect(w, r, "/app/admin/credentials", http.StatusSeeOther)
+		return
+	}
+
+	go m.RotateAllCredentialsRoutine(servers, userID)
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Rotating root credentials of %d server(s)...", len(servers)))
+	http.Redirect(w, r, "/app/admin/credentials", http.StatusSeeOther)
+}
+
+// RotateAllCredentialsRoutine rotates the root credentials of each server, reporting how many were rotated.
+func (m *Repository) RotateAllCredentialsRoutine(servers []models.Server, userID string) {
+	rotated := 0
+	for _, vps := range servers {
+		if m.rotateRootCredentials(vps, userID) {
+			rotated++
+		}
+	}
+	m.SendMessage(userID, fmt.Sprintf("Rotated root credentials of %d of %d server(s)", rotated, len(servers)))
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/CloudyKit/jet/v6"
 	"github.com/nickzer0/GoBoxer/internal/domains"
@@ -135,6 +136,12 @@ func (m *Repository) DomainsPurchase(w http.ResponseWriter, r *http.Request) {
 	if err != nil && err.Error() == "not found" {
 		// Assuming GoDaddy as the provider for simplicity; extend as needed
 		if formProvider == "godaddy" {
+			// Look the price up again rather than trusting the one shown to the user
+			lookup, err := domains.Repo.GoDaddyLookupDomain(formDomain)
+			if err != nil {
+				log.Printf("Error looking up price of domain %s: %v", formDomain, err)
+			}
+
 			err = domains.Repo.GoDaddyPurchaseDomain(formDomain)
 			if err != nil {
 				log.Printf("Error purchasing domain %s: %v", formDomain, err)
@@ -153,6 +160,17 @@ func (m *Repository) DomainsPurchase(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
+			if domain, err = m.DB.GetDomainFromDatabase(formDomain); err == nil {
+				m.startCost(models.Cost{
+					ResourceType: models.CostDomain,
+					ResourceID:   domain.ID,
+					Name:         domain.Name,
+					Provider:     "GoDaddy",
+					PriceHourly:  domainPriceHourly(lookup.Price),
+					StartedAt:    time.Now(),
+				})
+			}
+
 			// Optionally create AWS Hosted zone for the domain
 			// go domains.Repo.CreateAWSHostedZoneForDomain(domain)
 		}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nickzer0/GoBoxer/internal/models"
+	"github.com/nickzer0/GoBoxer/internal/server"
+)
+
+// expiryWarning is how long before a server is torn down its owner is warned
+const expiryWarning = time.Hour
+
+var (
+	expiryMutex sync.Mutex
+	// expiryWarned holds the expiry each server's owner was last warned about, so they are
+	// warned again if the expiry is changed
+	expiryWarned = make(map[int]time.Time)
+	// expiryRemoving holds the servers being torn down, so a slow teardown is not started twice
+	expiryRemoving = make(map[int]bool)
+)
+
+// resolveServerTTL returns when a new server in a project expires. An empty choice means the project
+// default, if it has one, and "0" means the server never expires.
+func (m *Repository) resolveServerTTL(project int, choice string) (time.Time, error) {
+	hours := 0
+	if choice == "" {
+		defaults, err := m.DB.GetProjectByNumber(project)
+		if err != nil {
+			return time.Time{}, err
+		}
+		hours = defaults.ServerTTL
+	} else {
+		var err error
+		if hours, err = parseServerTTL(choice); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	if hours == 0 {
+		return time.Time{}, nil
+	}
+	return time.Now().Add(time.Duration(hours) * time.Hour), nil
+}
+
+// ServerExpiry sets or clears when a server is automatically torn down.
+func (m *Repository) ServerExpiry(w http.ResponseWriter, r *http.Request) {
+	exploded := strings.Split(r.RequestURI, "/")
+	if len(exploded) < 5 {
+		log.Printf("Invalid server ID in URL")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+
+	serverID, err := strconv.Atoi(exploded[4])
+	if err != nil {
+		log.Printf("Error converting server ID: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Invalid server ID.")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Error parsing form: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Failed to process form data.")
+		http.Redirect(w, r, fmt.Sprintf("/app/servers/%d", serverID), http.StatusSeeOther)
+		return
+	}
+
+	vps, err := m.DB.GetServer(serverID)
+	if err != nil {
+		log.Printf("Error fetching server %d: %v", serverID, err)
+		m.App.Session.Put(r.Context(), "error", "Server not found.")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+
+	var expiresAt time.Time
+	if r.Form.Get("action") != "clear" {
+		hours, err := strconv.Atoi(r.Form.Get("hours"))
+		if err != nil || hours < 1 {
+			m.App.Session.Put(r.Context(), "error", "Expiry must be a whole number of hours from now.")
+			http.Redirect(w, r, fmt.Sprintf("/app/servers/%d", serverID), http.StatusSeeOther)
+			return
+		}
+		expiresAt = time.Now().Add(time.Duration(hours) * time.Hour)
+	}
+
+	if err := m.DB.UpdateServerExpiry(vps.ID, expiresAt); err != nil {
+		log.Printf("Error updating expiry of server %d: %v", vps.ID, err)
+		m.App.Session.Put(r.Context(), "error", "Failed to update server expiry.")
+		http.Redirect(w, r, fmt.Sprintf("/app/servers/%d", serverID), http.StatusSeeOther)
+		return
+	}
+
+	if expiresAt.IsZero() {
+		m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Server %s will no longer expire.", vps.Name))
+	} else {
+		m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Server %s will be torn down at %s.", vps.Name, expiresAt.Format("02-01-2006 15:04")))
+	}
+	http.Redirect(w, r, fmt.Sprintf("/app/servers/%d", serverID), http.StatusSeeOther)
+}
+
+// ExpiryRoutine tears down expired servers on the given interval
+func (m *Repository) ExpiryRoutine(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		m.ExpireServers()
+		<-ticker.C
+	}
+}
+
+// ExpireServers warns the owners of servers that are about to expire and removes servers that have expired
+func (m *Repository) ExpireServers() {
+	servers, err := m.DB.ListExpiringServers()
+	if err != nil {
+		log.Printf("Error listing expiring servers: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, vps := range servers {
+		// Servers still being created have nothing to remove at the provider yet
+		if vps.ProviderID == "" {
+			continue
+		}
+
+		owner, err := m.DB.GetUserIDFromUsername(vps.Creator)
+		if err != nil {
+			log.Printf("Error finding owner %s of server %d: %v", vps.Creator, vps.ID, err)
+		}
+		userID := strconv.Itoa(owner)
+
+		if now.Before(vps.ExpiresAt) {
+			if vps.ExpiresAt.Sub(now) <= expiryWarning {
+				m.warnServerExpiry(vps, userID)
+			}
+			continue
+		}
+
+		// The expiry is kept until the server is gone, so a failed teardown is tried again on the next run
+		expiryMutex.Lock()
+		removing := expiryRemoving[vps.ID]
+		expiryRemoving[vps.ID] = true
+		delete(expiryWarned, vps.ID)
+		expiryMutex.Unlock()
+		if removing {
+			continue
+		}
+
+		if err := m.DB.UpdateServerStatus(vps.ID, server.StatusExpired); err != nil {
+			log.Printf("Error updating status of server %d: %v", vps.ID, err)
+		}
+
+		log.Printf("Server %s (%d) expired, tearing it down", vps.Name, vps.ID)
+		m.SendMessage(userID, fmt.Sprintf("Server %s has expired and is being torn down.", vps.Name))
+		m.Broadcast("public-channel", "server-changed", map[string]string{
+			"server_id":  strconv.Itoa(vps.ID),
+			"project":    strconv.Itoa(vps.Project),
+			"provider":   vps.Provider,
+			"hostname":   vps.Name,
+			"os":         vps.OS,
+			"ip_address": vps.IP,
+			"status":     server.StatusExpired,
+		})
+		go m.expireServer(vps, userID)
+	}
+}
+
+// expireServer tears down an expired server. If it is still there afterwards its previous status is
+// put back, as reconcile leaves servers marked expired alone while they are torn down.
+func (m *Repository) expireServer(vps models.Server, userID string) {
+	defer func() {
+		expiryMutex.Lock()
+		delete(expiryRemoving, vps.ID)
+		expiryMutex.Unlock()
+	}()
+
+	m.RemoveServersRoutine(vps.ID, userID)
+	if _, err := m.DB.GetServer(vps.ID); err != nil {
+		return
+	}
+
+	// A server already marked expired was left mid-teardown, so what it was before is not known
+	status := vps.Status
+	if status == server.StatusExpired {
+		status = "ERROR"
+	}
+	log.Printf("Server %s (%d) could not be torn down, trying again on the next run", vps.Name, vps.ID)
+	m.SendError(userID, fmt.Sprintf("Expired server %s could not be torn down, it will be tried again.", vps.Name))
+	if err := m.DB.UpdateServerStatus(vps.ID, status); err != nil {
+		log.Printf("Error updating status of server %d: %v", vps.ID, err)
+	}
+	m.Broadcast("public-channel", "server-changed", map[string]string{
+		"server_id":  strconv.Itoa(vps.ID),
+		"project":    strconv.Itoa(vps.Project),
+		"provider":   vps.Provider,
+		"hostname":   vps.Name,
+		"os":         vps.OS,
+		"ip_address": vps.IP,
+		"status":     status,
+	})
+}
+
+// warnServerExpiry tells the owner of a server it is about to be torn down, once per expiry
+func (m *Repository) warnServerExpiry(vps models.Server, userID string) {
+	expiryMutex.Lock()
+	warned := expiryWarned[vps.ID].Equal(vps.ExpiresAt)
+	expiryWarned[vps.ID] = vps.ExpiresAt
+	expiryMutex.Unlock()
+
+	if warned {
+		return
+	}
+	m.SendError(userID, fmt.Sprintf("Server %s expires at %s and will be torn down, extend it from the server page to keep it.", vps.Name, vps.ExpiresAt.Format("15:04")))
+}
@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nickzer0/GoBoxer/internal/models"
+	"github.com/nickzer0/GoBoxer/internal/server"
+)
+
+// resolveFirewallPolicy returns the ID of the firewall policy chosen for a new server in a project. An empty
+// choice means the project default, if it has one, and "0" means no firewall.
+func (m *Repository) resolveFirewallPolicy(project int, choice string) (int, error) {
+	if choice == "" {
+		policy, err := m.DB.GetProjectDefaultFirewallPolicy(project)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return policy.ID, err
+	}
+
+	policyID, err := strconv.Atoi(choice)
+	if err != nil || policyID == 0 {
+		return 0, err
+	}
+
+	policy, err := m.DB.GetFirewallPolicy(policyID)
+	if err != nil {
+		return 0, err
+	}
+	if policy.Project != 0 && policy.Project != project {
+		return 0, fmt.Errorf("firewall policy %s belongs to another project", policy.Name)
+	}
+	return policy.ID, nil
+}
+
+// serverFirewaller returns the server's provider if it has a cloud firewall
+func serverFirewaller(vps models.Server) (server.Firewaller, bool) {
+	provider, err := server.GetProvider(vps.Provider)
+	if err != nil {
+		return nil, false
+	}
+
+	firewaller, ok := provider.(server.Firewaller)
+	return firewaller, ok && vps.ProviderID != ""
+}
+
+// applyServerFirewall applies the server's firewall policy at its provider
+func (m *Repository) applyServerFirewall(vps models.Server) error {
+	if vps.Firewall == 0 {
+		return nil
+	}
+
+	firewaller, ok := serverFirewaller(vps)
+	if !ok {
+		return server.ErrNotSupported
+	}
+
+	policy, err := m.DB.GetFirewallPolicy(vps.Firewall)
+	if err != nil {
+		return fmt.Errorf("error fetching firewall policy: %v", err)
+	}
+
+	return firewaller.ApplyFirewall(vps, policy.Rules)
+}
+
+// removeServerFirewall deletes the server's firewall at its provider if it has one
+func (m *Repository) removeServerFirewall(vps models.Server) error {
+	if vps.Firewall == 0 {
+		return nil
+	}
+
+	firewaller, ok := serverFirewaller(vps)
+	if !ok {
+		return nil
+	}
+	return firewaller.RemoveFirewall(vps)
+}
+
+// firewallPoliciesForUser returns the firewall policies of the user's projects along with the shared ones
+func (m *Repository) firewallPoliciesForUser(username string) ([]models.FirewallPolicy, error) {
+	projects, err := m.DB.GetProjectsForUser(username)
+	if err != nil {
+		return nil, err
+	}
+
+	var policies []models.FirewallPolicy
+	seen := make(map[int]bool)
+	for _, project := range projects {
+		projectPolicies, err := m.DB.GetFirewallPoliciesForProject(project.ProjectNumber)
+		if err != nil {
+			return nil, err
+		}
+		for _, policy := range projectPolicies {
+			if !seen[policy.ID] {
+				seen[policy.ID] = true
+				policies = append(policies, policy)
+			}
+		}
+	}
+	return policies, nil
+}
+
+// serverFirewallPolicies returns the firewall policies available to a server with their rules
+// formatted for editing, and whether the server's provider has a cloud firewall
+func (m *Repository) serverFirewallPolicies(vps models.Server) ([]models.FirewallPolicy, map[int]string, bool) {
+	rules := make(map[int]string)
+	if _, ok := serverFirewaller(vps); !ok {
+		return nil, rules, false
+	}
+
+	policies, err := m.DB.GetFirewallPoliciesForProject(vps.Project)
+	if err != nil {
+		log.Printf("Error fetching firewall policies for project %d: %v", vps.Project, err)
+	}
+	for _, policy := range policies {
+		rules[policy.ID] = server.FormatFirewallRules(policy.Rules)
+	}
+	return policies, rules, true
+}
+
+// ServerFirewall assigns, edits, creates or deletes the firewall policy of the server identified in the URL.
+func (m *Repository) ServerFirewall(w http.ResponseWriter, r *http.Request) {
+	exploded := strings.Split(r.RequestURI, "/")
+	if len(exploded) < 5 {
+		log.Printf("Invalid server ID in URL")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+
+	serverID, err := strconv.Atoi(exploded[4])
+	if err != nil {
+		log.Printf("Error converting server ID to integer: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Invalid server ID provided.")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+	serverURL := fmt.Sprintf("/app/servers/%d", serverID)
+
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Error parsing form: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Failed to process form data.")
+		http.Redirect(w, r, serverURL, http.StatusSeeOther)
+		return
+	}
+
+	targetServer, err := m.DB.GetServer(serverID)
+	if err != nil {
+		log.Printf("Error retrieving server by ID: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Server not found.")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+
+	if _, ok := serverFirewaller(targetServer); !ok {
+		m.App.Session.Put(r.Context(), "error", "Cloud firewalls are not supported for this server.")
+		http.Redirect(w, r, serverURL, http.StatusSeeOther)
+		return
+	}
+
+	userID := strconv.Itoa(m.App.Session.Get(r.Context(), "user_id").(int))
+	choice := r.Form.Get("policy")
+
+	// Removing the firewall from a server leaves the policy in place for other servers
+	if choice == "0" {
+		go m.RemoveFirewallRoutine(targetServer, userID)
+		m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Removing firewall from %s.", targetServer.Name))
+		http.Redirect(w, r, serverURL, http.StatusSeeOther)
+		return
+	}
+
+	rules, err := server.ParseFirewallRules(r.Form.Get("rules"))
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", fmt.Sprintf("Invalid firewall rules: %v", err))
+		http.Redirect(w, r, serverURL, http.StatusSeeOther)
+		return
+	}
+
+	var policy models.FirewallPolicy
+	if choice == "new" {
+		policy = models.FirewallPolicy{
+			Name:           strings.TrimSpace(r.Form.Get("policy_name")),
+			Project:        targetServer.Project,
+			ProjectDefault: r.Form.Get("project_default") == "on",
+			Rules:          rules,
+			CreatedBy:      m.App.Session.Get(r.Context(), "username").(string),
+		}
+		if policy.Name == "" {
+			m.App.Session.Put(r.Context(), "error", "A name is needed for the new firewall policy.")
+			http.Redirect(w, r, serverURL, http.StatusSeeOther)
+			return
+		}
+
+		policy, err = m.DB.AddFirewallPolicy(policy)
+		if err != nil {
+			log.Printf("Error adding firewall policy: %v", err)
+			m.App.Session.Put(r.Context(), "error", "Failed to save firewall policy.")
+			http.Redirect(w, r, serverURL, http.StatusSeeOther)
+			return
+		}
+	} else {
+		policyID, err := m.resolveFirewallPolicy(targetServer.Project, choice)
+		if err == nil && policyID != 0 {
+			policy, err = m.DB.GetFirewallPolicy(policyID)
+		}
+		if err != nil || policyID == 0 {
+			m.App.Session.Put(r.Context(), "error", "Invalid firewall policy selection.")
+			http.Redirect(w, r, serverURL, http.StatusSeeOther)
+			return
+		}
+
+		if r.Form.Get("action") == "delete" {
+			go m.DeleteFirewallPolicyRoutine(policy, userID)
+			m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Deleting firewall policy %s.", policy.Name))
+			http.Redirect(w, r, serverURL, http.StatusSeeOther)
+			return
+		}
+
+		policy.Rules = rules
+		// Shared policies cannot be the default for one project
+		if policy.Project != 0 {
+			policy.ProjectDefault = r.Form.Get("project_default") == "on"
+		}
+		if err := m.DB.UpdateFirewallPolicy(policy); err != nil {
+			log.Printf("Error updating firewall policy: %v", err)
+			m.App.Session.Put(r.Context(), "error", "Failed to save firewall policy.")
+			http.Redirect(w, r, serverURL, http.StatusSeeOther)
+			return
+		}
+	}
+
+	if err := m.DB.UpdateServerFirewall(targetServer.ID, policy.ID); err != nil {
+		log.Printf("Error updating server firewall: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Failed to assign firewall policy to server.")
+		http.Redirect(w, r, serverURL, http.StatusSeeOther)
+		return
+	}
+
+	go m.ApplyFirewallRoutine(policy.ID, userID)
+
+	if !server.AllowsSSH(rules) {
+		m.App.Session.Put(r.Context(), "warning", "No rule allows SSH, GoBoxer will not be able to provision servers using this policy.")
+	}
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Applying firewall policy %s.", policy.Name))
+	http.Redirect(w, r, serverURL, http.StatusSeeOther)
+}
+
+// ApplyFirewallRoutine applies a firewall policy to every server using it, so edits reach them all.
+func (m *Repository) ApplyFirewallRoutine(policyID int, userID string) {
+	servers, err := m.DB.ListServersWithFirewall(policyID)
+	if err != nil {
+		log.Printf("Error fetching servers using firewall policy %d: %v", policyID, err)
+		m.SendError(userID, "Error fetching servers using firewall policy.")
+		return
+	}
+
+	for _, vps := range servers {
+		if err := m.applyServerFirewall(vps); err != nil {
+			log.Printf("Error applying firewall to server %d: %v", vps.ID, err)
+			m.SendError(userID, fmt.Sprintf("Error applying firewall to %s: %v", vps.Name, err))
+			continue
+		}
+		m.SendMessage(userID, fmt.Sprintf("Firewall applied to %s", vps.Name))
+	}
+}
+
+// RemoveFirewallRoutine deletes a server's firewall at its provider and unassigns its policy.
+func (m *Repository) RemoveFirewallRoutine(vps models.Server, userID string) {
+	if err := m.removeServerFirewall(vps); err != nil {
+		log.Printf("Error removing firewall from server %d: %v", vps.ID, err)
+		m.SendError(userID, fmt.Sprintf("Error removing firewall from %s: %v", vps.Name, err))
+		return
+	}
+
+	if err := m.DB.UpdateServerFirewall(vps.ID, 0); err != nil {
+		log.Printf("Error updating server firewall: %v", err)
+		m.SendError(userID, "Error updating server in database!")
+		return
+	}
+
+	m.SendMessage(userID, fmt.Sprintf("Firewall removed from %s", vps.Name))
+}
+
+// DeleteFirewallPolicyRoutine removes the firewalls of every server using a policy, then deletes the policy.
+func (m *Repository) DeleteFirewallPolicyRoutine(policy models.FirewallPolicy, userID string) {
+	servers, err := m.DB.ListServersWithFirewall(policy.ID)
+	if err != nil {
+		log.Printf("Error fetching servers using firewall policy %d: %v", policy.ID, err)
+		m.SendError(userID, "Error fetching servers using firewall policy.")
+		return
+	}
+
+	for _, vps := range servers {
+		if err := m.removeServerFirewall(vps); err != nil {
+			// The policy is kept so the firewall is not left behind untracked
+			log.Printf("Error removing firewall from server %d: %v", vps.ID, err)
+			m.SendError(userID, fmt.Sprintf("Error removing firewall from %s, policy %s not deleted: %v", vps.Name, policy.Name, err))
+			return
+		}
+	}
+
+	if err := m.DB.DeleteFirewallPolicy(policy.ID); err != nil {
+		log.Printf("Error deleting firewall policy %d: %v", policy.ID, err)
+		m.SendError(userID, "Error deleting firewall policy from database!")
+		return
+	}
+
+	m.SendMessage(userID, fmt.Sprintf("Firewall policy %s deleted", policy.Name))
+}
@@ -26,6 +26,7 @@ func (m *Repository) Home(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	vars.Set("services", services)
+	vars.Set("drift", LastDriftReport())
 
 	username := m.App.Session.Get(r.Context(), "username").(string)
 	var projects []models.Project
@@ -40,7 +41,16 @@ func (m *Repository) Home(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	spendByProject, spendByProvider, err := m.spendReport(projects, helpers.IsAdmin(r))
+	if err != nil {
+		log.Printf("Error building spend report: %v", err)
+		printErrorPage(w, err)
+		return
+	}
+
 	vars.Set("projects", projects)
+	vars.Set("spend_by_project", spendByProject)
+	vars.Set("spend_by_provider", spendByProvider)
 	if err := helpers.RenderPage(w, r, "home", vars, nil); err != nil {
 		log.Printf("Error rendering home page: %v", err)
 		printTemplateError(w, err)
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nickzer0/GoBoxer/internal/deploy"
+)
+
+// RepinServerHostKey trusts the host keys a server presents now, for when they have changed legitimately,
+// such as after the server was reinstalled outside GoBoxer. The new fingerprints are shown so they can
+// be checked against the provider's console.
+func (m *Repository) RepinServerHostKey(w http.ResponseWriter, r *http.Request) {
+	exploded := strings.Split(r.RequestURI, "/")
+	if len(exploded) < 5 {
+		log.Printf("Invalid server ID in URL")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+
+	serverID, err := strconv.Atoi(exploded[4])
+	if err != nil {
+		log.Printf("Error converting server ID: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Invalid server ID.")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+	redirectURL := fmt.Sprintf("/app/servers/%d", serverID)
+
+	vps, err := m.DB.GetServer(serverID)
+	if err != nil {
+		log.Printf("Error fetching server %d: %v", serverID, err)
+		m.App.Session.Put(r.Context(), "error", "Server not found.")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+
+	keys, err := deploy.ScanHostKeys(vps.IP)
+	if err != nil {
+		log.Printf("Error reading host keys of server %d: %v", serverID, err)
+		m.App.Session.Put(r.Context(), "error", fmt.Sprintf("Could not read host keys from %s: %v", vps.Name, err))
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		return
+	}
+
+	hostKey := strings.Join(keys, "\n")
+	if err := m.DB.UpdateServerHostKey(vps.ID, hostKey); err != nil {
+		log.Printf("Error updating host key of server %d: %v", serverID, err)
+		m.App.Session.Put(r.Context(), "error", "Failed to store host keys.")
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Pinned host keys of %s: %s", vps.Name, strings.Join(deploy.HostKeyFingerprints(hostKey), ", ")))
+	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+}
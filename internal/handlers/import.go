@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/nickzer0/GoBoxer/internal/deploy"
+	"github.com/nickzer0/GoBoxer/internal/helpers"
+	"github.com/nickzer0/GoBoxer/internal/models"
+	"github.com/nickzer0/GoBoxer/internal/server"
+)
+
+// untrackedServers lists the instances at each configured provider which are not in the servers table,
+// keyed by "provider/providerID". Providers which cannot be listed are returned in the errors map.
+func (m *Repository) untrackedServers() (map[string]models.Server, map[string]string, error) {
+	untracked := make(map[string]models.Server)
+	listErrors := make(map[string]string)
+
+	secrets, err := m.DB.GetAllSecrets()
+	if err != nil {
+		return untracked, listErrors, fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	recorded, err := m.DB.ListAllServers()
+	if err != nil {
+		return untracked, listErrors, fmt.Errorf("error fetching list of servers from database: %v", err)
+	}
+
+	tracked := make(map[string]bool)
+	for _, dbServer := range recorded {
+		tracked[dbServer.Provider+"/"+dbServer.ProviderID] = true
+	}
+
+	for _, provider := range server.ConfiguredProviders(secrets) {
+		var live []models.Server
+		// Providers which cannot list untagged instances only offer servers GoBoxer lost track of
+		if importer, ok := provider.(server.Importer); ok {
+			live, err = importer.ListAllServers()
+		} else {
+			live, err = provider.ListServers()
+		}
+		if err != nil {
+			log.Printf("Error listing servers from %s: %v", provider.DisplayName(), err)
+			listErrors[provider.DisplayName()] = err.Error()
+			continue
+		}
+
+		for _, vps := range live {
+			key := provider.Name() + "/" + vps.ProviderID
+			if !tracked[key] {
+				untracked[key] = vps
+			}
+		}
+	}
+
+	return untracked, listErrors, nil
+}
+
+// ServersImport lists the provider instances not tracked by GoBoxer so they can be adopted into a project.
+func (m *Repository) ServersImport(w http.ResponseWriter, r *http.Request) {
+	untracked, listErrors, err := m.untrackedServers()
+	if err != nil {
+		log.Printf("Error listing untracked servers: %v", err)
+		printErrorPage(w, err)
+		return
+	}
+
+	projects, err := m.DB.GetAllProjects()
+	if err != nil {
+		log.Printf("Error fetching projects: %v", err)
+		printErrorPage(w, err)
+		return
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("instances", untracked)
+	vars.Set("list_errors", listErrors)
+	vars.Set("projects", projects)
+
+	if err := helpers.RenderPage(w, r, "servers-import", vars, nil); err != nil {
+		log.Printf("Error rendering servers-import page: %v", err)
+		printTemplateError(w, err)
+	}
+}
+
+// ServersImportPost adopts the selected provider instances into a project.
+func (m *Repository) ServersImportPost(w http.ResponseWriter, r *http.Request) {
+	userID := strconv.Itoa(m.App.Session.Get(r.Context(), "user_id").(int))
+
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Error parsing form: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Failed to process form data.")
+		http.Redirect(w, r, "/app/admin/servers/import", http.StatusSeeOther)
+		return
+	}
+
+	projectInt, err := strconv.Atoi(r.Form.Get("assign_project"))
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Invalid project selection.")
+		http.Redirect(w, r, "/app/admin/servers/import", http.StatusSeeOther)
+		return
+	}
+
+	selected := r.PostForm["instances"]
+	if len(selected) == 0 {
+		m.App.Session.Put(r.Context(), "error", "No servers selected.")
+		http.Redirect(w, r, "/app/admin/servers/import", http.StatusSeeOther)
+		return
+	}
+
+	// Look the instances up again rather than trusting details from the form
+	untracked, _, err := m.untrackedServers()
+	if err != nil {
+		log.Printf("Error listing untracked servers: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Failed to list servers from providers.")
+		http.Redirect(w, r, "/app/admin/servers/import", http.StatusSeeOther)
+		return
+	}
+
+	var imported []models.Server
+	for _, key := range selected {
+		vps, ok := untracked[key]
+		if !ok {
+			m.SendError(userID, fmt.Sprintf("Server %s is no longer available to import.", key))
+			continue
+		}
+
+		provider, _, _ := strings.Cut(key, "/")
+		vps.Provider = provider
+		vps.Project = projectInt
+		vps.Creator = m.App.Session.Get(r.Context(), "username").(string)
+		vps.CreatedAt = time.Now()
+		if vps.Name == "" {
+			vps.Name = vps.ProviderID
+		}
+		if vps.OS == "" {
+			vps.OS = vps.Image
+		}
+		if server.IsRunning(vps.Status) {
+			vps.Status = "Ready"
+		}
+
+		databaseServer, err := m.DB.AddServerToDatabase(vps)
+		if err != nil {
+			log.Printf("Error adding server to database: %v", err)
+			m.SendError(userID, fmt.Sprintf("Failed to add server %s to database.", vps.Name))
+			continue
+		}
+		m.startServerCost(databaseServer)
+		imported = append(imported, databaseServer)
+	}
+
+	go m.ImportServersRoutine(imported, r.Form.Get("rekey") == "on", userID)
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Imported %d server(s).", len(imported)))
+	http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+}
+
+// ImportServersRoutine tags adopted servers at their provider and optionally makes them trust the SSH CA,
+// as hand-built servers will not.
+func (m *Repository) ImportServersRoutine(imported []models.Server, rekey bool, userID string) {
+	for _, importedServer := range imported {
+		provider, err := server.GetProvider(importedServer.Provider)
+		if err != nil {
+			log.Printf("Unknown provider for server %d: %s", importedServer.ID, importedServer.Provider)
+			continue
+		}
+
+		if importer, ok := provider.(server.Importer); ok {
+			if err := importer.TagServer(importedServer); err != nil {
+				log.Printf("Error tagging %s server %s: %v", provider.DisplayName(), importedServer.ProviderID, err)
+				m.SendError(userID, fmt.Sprintf("Could not tag %s on %s, reconciliation will report it as missing", importedServer.Name, provider.DisplayName()))
+			}
+		}
+
+		if rekey {
+			// This connects with the root key, so it only works on servers which already trust it
+			if err := deploy.TrustCertificateAuthority(importedServer); err != nil {
+				log.Printf("Error enabling access on server: %v", err)
+				m.SendError(userID, fmt.Sprintf("Could not make %s trust the SSH CA, check the root key is authorized on it", importedServer.Name))
+				continue
+			}
+			m.rotateRootCredentials(importedServer, userID)
+			m.reconcileOperators(importedServer, userID)
+		}
+
+		m.SendMessage(userID, fmt.Sprintf("Server %s imported", importedServer.Name))
+	}
+}
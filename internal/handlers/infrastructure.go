@@ -0,0 +1,498 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/nickzer0/GoBoxer/internal/blueprint"
+	"github.com/nickzer0/GoBoxer/internal/domains"
+	"github.com/nickzer0/GoBoxer/internal/helpers"
+	"github.com/nickzer0/GoBoxer/internal/models"
+	"github.com/nickzer0/GoBoxer/internal/redirectors"
+	"github.com/nickzer0/GoBoxer/internal/server"
+)
+
+// errNoInfrastructure is returned when planning a project which has not declared its infrastructure
+var errNoInfrastructure = errors.New("no infrastructure declared for this project")
+
+// applying holds the projects with an apply in progress, so two cannot race each other
+var applying = struct {
+	sync.Mutex
+	projects map[int]bool
+}{projects: make(map[int]bool)}
+
+// projectFromURL returns the project numbered in the URL if the user is assigned to it
+func (m *Repository) projectFromURL(r *http.Request) (models.Project, error) {
+	exploded := strings.Split(r.RequestURI, "/")
+	if len(exploded) < 4 {
+		return models.Project{}, fmt.Errorf("invalid project number")
+	}
+
+	number, err := strconv.Atoi(exploded[3])
+	if err != nil {
+		return models.Project{}, fmt.Errorf("invalid project number")
+	}
+
+	username := m.App.Session.Get(r.Context(), "username").(string)
+	if !m.userInProject(username, number) {
+		return models.Project{}, fmt.Errorf("not assigned to project %d", number)
+	}
+	return m.DB.GetProjectByNumber(number)
+}
+
+// ProjectInfrastructure displays the editor for a project's declared infrastructure.
+func (m *Repository) ProjectInfrastructure(w http.ResponseWriter, r *http.Request) {
+	project, err := m.projectFromURL(r)
+	if err != nil {
+		log.Printf("Error getting project: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Project not found.")
+		http.Redirect(w, r, "/app/projects", http.StatusSeeOther)
+		return
+	}
+
+	blueprints, err := m.DB.ListBlueprints()
+	if err != nil {
+		log.Printf("Error listing blueprints: %v", err)
+		printErrorPage(w, err)
+		return
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("project", project)
+	vars.Set("blueprints", blueprints)
+
+	if err := helpers.RenderPage(w, r, "projects-infrastructure", vars, nil); err != nil {
+		log.Printf("Error rendering projects-infrastructure page: %v", err)
+		printTemplateError(w, err)
+	}
+}
+
+// ProjectInfrastructurePost validates and saves a project's declared infrastructure, then shows its plan.
+func (m *Repository) ProjectInfrastructurePost(w http.ResponseWriter, r *http.Request) {
+	project, err := m.projectFromURL(r)
+	if err != nil {
+		log.Printf("Error getting project: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Project not found.")
+		http.Redirect(w, r, "/app/projects", http.StatusSeeOther)
+		return
+	}
+	editURL := fmt.Sprintf("/app/projects/%d/infrastructure", project.ProjectNumber)
+
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Error parsing form: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Failed to process form data.")
+		http.Redirect(w, r, editURL, http.StatusSeeOther)
+		return
+	}
+
+	// Loading a blueprint only fills the editor, it is saved like any other edit
+	if r.Form.Get("action") == "load" {
+		id, _ := strconv.Atoi(r.Form.Get("blueprint"))
+		stored, err := m.DB.GetBlueprint(id)
+		if err != nil {
+			m.App.Session.Put(r.Context(), "error", "Blueprint not found.")
+			http.Redirect(w, r, editURL, http.StatusSeeOther)
+			return
+		}
+		project.Infrastructure = stored.Spec
+		m.renderInfrastructureForm(w, r, project)
+		return
+	}
+
+	project.Infrastructure = r.Form.Get("infrastructure")
+	if strings.TrimSpace(project.Infrastructure) != "" {
+		if _, err := blueprint.Parse(project.Infrastructure); err != nil {
+			m.App.Session.Put(r.Context(), "error", fmt.Sprintf("Infrastructure not saved, it is invalid: %v", err))
+			m.renderInfrastructureForm(w, r, project)
+			return
+		}
+	}
+
+	if err := m.DB.UpdateProjectInfrastructure(project.ProjectNumber, project.Infrastructure); err != nil {
+		log.Printf("Error updating infrastructure of project %d: %v", project.ProjectNumber, err)
+		m.App.Session.Put(r.Context(), "error", "Failed to save infrastructure.")
+		m.renderInfrastructureForm(w, r, project)
+		return
+	}
+
+	if strings.TrimSpace(project.Infrastructure) == "" {
+		m.App.Session.Put(r.Context(), "flash", "Infrastructure declaration removed, the project is no longer managed by plans.")
+		http.Redirect(w, r, fmt.Sprintf("/app/projects/%d", project.ProjectNumber), http.StatusSeeOther)
+		return
+	}
+
+	m.App.Session.Put(r.Context(), "flash", "Infrastructure saved.")
+	http.Redirect(w, r, fmt.Sprintf("/app/projects/%d/plan", project.ProjectNumber), http.StatusSeeOther)
+}
+
+// renderInfrastructureForm shows the editor again with what was submitted, so edits are not lost on a validation error
+func (m *Repository) renderInfrastructureForm(w http.ResponseWriter, r *http.Request, project models.Project) {
+	blueprints, err := m.DB.ListBlueprints()
+	if err != nil {
+		log.Printf("Error listing blueprints: %v", err)
+	}
+
+	vars := make(jet.VarMap)
+	vars.Set("project", project)
+	vars.Set("blueprints", blueprints)
+
+	if err := helpers.RenderPage(w, r, "projects-infrastructure", vars, nil); err != nil {
+		log.Printf("Error rendering projects-infrastructure page: %v", err)
+		printTemplateError(w, err)
+	}
+}
+
+// ProjectPlan shows the changes needed to bring a project in line with its declared infrastructure.
+func (m *Repository) ProjectPlan(w http.ResponseWriter, r *http.Request) {
+	project, err := m.projectFromURL(r)
+	if err != nil {
+		log.Printf("Error getting project: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Project not found.")
+		http.Redirect(w, r, "/app/projects", http.StatusSeeOther)
+		return
+	}
+
+	username := m.App.Session.Get(r.Context(), "username").(string)
+	_, plan, err := m.planProject(project, username)
+	planError := ""
+	if errors.Is(err, errNoInfrastructure) {
+		http.Redirect(w, r, fmt.Sprintf("/app/projects/%d/infrastructure", project.ProjectNumber), http.StatusSeeOther)
+		return
+	} else if err != nil {
+		planError = err.Error()
+	}
+
+	applying.Lock()
+	inProgress := applying.projects[project.ProjectNumber]
+	applying.Unlock()
+
+	vars := make(jet.VarMap)
+	vars.Set("project", project)
+	vars.Set("plan", plan)
+	vars.Set("plan_error", planError)
+	vars.Set("applying", inProgress)
+
+	if err := helpers.RenderPage(w, r, "projects-plan", vars, nil); err != nil {
+		log.Printf("Error rendering projects-plan page: %v", err)
+		printTemplateError(w, err)
+	}
+}
+
+// ProjectApply re-plans a project and, if nothing changed since the plan was reviewed, applies it in the background.
+func (m *Repository) ProjectApply(w http.ResponseWriter, r *http.Request) {
+	userID := strconv.Itoa(m.App.Session.Get(r.Context(), "user_id").(int))
+	username := m.App.Session.Get(r.Context(), "username").(string)
+
+	project, err := m.projectFromURL(r)
+	if err != nil {
+		log.Printf("Error getting project: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Project not found.")
+		http.Redirect(w, r, "/app/projects", http.StatusSeeOther)
+		return
+	}
+	planURL := fmt.Sprintf("/app/projects/%d/plan", project.ProjectNumber)
+
+	if m.App.Session.Get(r.Context(), "ssh_key") == "0" {
+		m.App.Session.Put(r.Context(), "error", "No SSH Key set for user!")
+		http.Redirect(w, r, planURL, http.StatusSeeOther)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Error parsing form: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Failed to process form data.")
+		http.Redirect(w, r, planURL, http.StatusSeeOther)
+		return
+	}
+
+	build, plan, err := m.planProject(project, username)
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", fmt.Sprintf("Cannot plan project: %v", err))
+		http.Redirect(w, r, planURL, http.StatusSeeOther)
+		return
+	}
+
+	if plan.Fingerprint() != r.Form.Get("fingerprint") {
+		m.App.Session.Put(r.Context(), "warning", "The project changed since the plan was made, review the new plan before applying.")
+		http.Redirect(w, r, planURL, http.StatusSeeOther)
+		return
+	}
+
+	if plan.Empty() {
+		m.App.Session.Put(r.Context(), "flash", "Nothing to apply, the project matches its declared infrastructure.")
+		http.Redirect(w, r, planURL, http.StatusSeeOther)
+		return
+	}
+
+	applying.Lock()
+	if applying.projects[project.ProjectNumber] {
+		applying.Unlock()
+		m.App.Session.Put(r.Context(), "error", "An apply is already running for this project.")
+		http.Redirect(w, r, planURL, http.StatusSeeOther)
+		return
+	}
+	applying.projects[project.ProjectNumber] = true
+	applying.Unlock()
+
+	go m.ApplyPlanRoutine(build, plan, userID)
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Applying %d changes to project %d...", len(plan.Changes), project.ProjectNumber))
+	http.Redirect(w, r, fmt.Sprintf("/app/projects/%d", project.ProjectNumber), http.StatusSeeOther)
+}
+
+// planProject resolves a project's declared infrastructure and diffs it against what the project has
+func (m *Repository) planProject(project models.Project, username string) (blueprintBuild, blueprint.Plan, error) {
+	if strings.TrimSpace(project.Infrastructure) == "" {
+		return blueprintBuild{}, blueprint.Plan{}, errNoInfrastructure
+	}
+
+	build, err := m.resolveBlueprint(project.ProjectName, project.Infrastructure, project.ProjectNumber, username)
+	if err != nil {
+		return build, blueprint.Plan{}, err
+	}
+
+	state, err := m.projectState(build)
+	if err != nil {
+		return build, blueprint.Plan{}, err
+	}
+
+	return build, build.Spec.Diff(project.ProjectNumber, build.Servers, state), nil
+}
+
+// projectState gathers the servers, redirectors and DNS records a project currently has. Servers are
+// checked against their provider, as a plan built on a stale database would not converge.
+func (m *Repository) projectState(build blueprintBuild) (blueprint.State, error) {
+	state := blueprint.State{Missing: make(map[int]bool)}
+
+	servers, err := m.DB.ListAllServersForProject(strconv.Itoa(build.Project))
+	if err != nil {
+		return state, fmt.Errorf("error listing project servers: %v", err)
+	}
+	state.Servers = servers
+
+	byProvider := make(map[string][]models.Server)
+	for _, vps := range servers {
+		byProvider[vps.Provider] = append(byProvider[vps.Provider], vps)
+	}
+	for name, recorded := range byProvider {
+		provider, err := server.GetProvider(name)
+		if err != nil {
+			return state, err
+		}
+		live, err := provider.ListServers()
+		if err != nil {
+			// Without a listing every server would look missing and be replaced
+			return state, fmt.Errorf("error listing servers from %s: %v", provider.DisplayName(), err)
+		}
+		for _, finding := range server.Reconcile(name, live, recorded) {
+			if finding.Kind == models.DriftGhost {
+				state.Missing[finding.ServerID] = true
+			}
+		}
+	}
+
+	allRedirectors, err := m.DB.GetAllDomainRedirectors()
+	if err != nil {
+		return state, fmt.Errorf("error listing redirectors: %v", err)
+	}
+	for _, redirector := range allRedirectors {
+		if redirector.Project == build.Project {
+			state.Redirectors = append(state.Redirectors, redirector)
+		}
+	}
+
+	if build.Spec.Domain != "" {
+		state.Records, err = domains.Repo.GetDNSRecordsFromAWS(build.Domain)
+		if err != nil {
+			return state, fmt.Errorf("error listing DNS records of %s: %v", build.Spec.Domain, err)
+		}
+	}
+
+	return state, nil
+}
+
+// ApplyPlanRoutine converges a project on its declared infrastructure. Replaced servers are destroyed first to
+// free their hostnames, then resources are created and updated in dependency order, and removed resources are
+// destroyed last so nothing still in use loses what it points at. It stops at the first failure.
+func (m *Repository) ApplyPlanRoutine(build blueprintBuild, plan blueprint.Plan, userID string) {
+	defer func() {
+		applying.Lock()
+		delete(applying.projects, build.Project)
+		applying.Unlock()
+	}()
+
+	fail := func(err error) {
+		m.SendError(userID, fmt.Sprintf("Apply to project %d stopped: %v", build.Project, err))
+	}
+
+	changes := make(map[string][]blueprint.Change)
+	pending := make(map[string]bool)
+	for _, change := range plan.Changes {
+		changes[change.Kind+" "+change.Action] = append(changes[change.Kind+" "+change.Action], change)
+		if change.Key != "" {
+			pending[change.Kind+" "+change.Key] = true
+		}
+	}
+
+	for _, change := range changes[blueprint.KindServer+" "+blueprint.ActionReplace] {
+		if err := m.destroyPlanServer(change, userID); err != nil {
+			fail(err)
+			return
+		}
+	}
+
+	var keys []string
+	for _, action := range []string{blueprint.ActionCreate, blueprint.ActionReplace} {
+		for _, change := range changes[blueprint.KindServer+" "+action] {
+			keys = append(keys, change.Key)
+		}
+	}
+	if len(keys) > 0 {
+		m.SendMessage(userID, fmt.Sprintf("Project %d: creating %d servers...", build.Project, len(keys)))
+		if _, err := m.deployBlueprintServers(build, keys, userID); err != nil {
+			fail(err)
+			return
+		}
+	}
+
+	for _, change := range changes[blueprint.KindServer+" "+blueprint.ActionUpdate] {
+		if err := m.updatePlanFirewall(change, build.Servers[change.Key].Firewall, userID); err != nil {
+			fail(err)
+			return
+		}
+	}
+
+	// Addresses are read back from the database so kept and newly created servers are treated alike
+	servers, err := m.DB.ListAllServersForProject(strconv.Itoa(build.Project))
+	if err != nil {
+		fail(fmt.Errorf("error listing project servers: %v", err))
+		return
+	}
+	byHostname := make(map[string]string)
+	for _, vps := range servers {
+		byHostname[vps.Name] = vps.IP
+	}
+	addresses := make(map[string]string)
+	for _, instance := range build.Instances {
+		addresses[instance.Key] = byHostname[instance.Hostname]
+	}
+
+	for _, record := range build.Spec.Records {
+		if record.Redirector != "" || !pending[blueprint.KindRecord+" "+record.Name] {
+			continue
+		}
+		data := record.Data
+		if record.Server != "" {
+			data = addresses[record.Server]
+		}
+		if err := m.upsertBlueprintRecord(build, record, data); err != nil {
+			fail(err)
+			return
+		}
+	}
+
+	redirectorURLs := make(map[string]string)
+	existing, err := m.DB.GetAllDomainRedirectors()
+	if err != nil {
+		fail(fmt.Errorf("error listing redirectors: %v", err))
+		return
+	}
+	for _, redirector := range build.Spec.Redirectors {
+		origin, _ := build.Spec.Record(redirector.Origin)
+		if pending[blueprint.KindRedirector+" "+redirector.Name] {
+			newRedirector, err := m.createBlueprintRedirector(build, redirector)
+			if err != nil {
+				fail(err)
+				return
+			}
+			redirectorURLs[redirector.Name] = newRedirector.URL
+			continue
+		}
+		for _, have := range existing {
+			if have.Project == build.Project && have.Domain == build.Spec.RecordName(origin) {
+				redirectorURLs[redirector.Name] = have.URL
+				break
+			}
+		}
+	}
+
+	for _, record := range build.Spec.Records {
+		if record.Redirector == "" || !pending[blueprint.KindRecord+" "+record.Name] {
+			continue
+		}
+		if err := m.upsertBlueprintRecord(build, record, redirectorURLs[record.Redirector]); err != nil {
+			fail(err)
+			return
+		}
+	}
+
+	for _, change := range changes[blueprint.KindRecord+" "+blueprint.ActionDestroy] {
+		if err := domains.Repo.DeleteDNSRecordFromAWS(build.Domain, change.Name, change.Type); err != nil {
+			log.Printf("Error deleting DNS record %s: %v", change.Name, err)
+			fail(fmt.Errorf("failed to delete DNS record %s", change.Name))
+			return
+		}
+	}
+
+	for _, change := range changes[blueprint.KindRedirector+" "+blueprint.ActionDestroy] {
+		redirector, err := m.DB.GetDomainRedirector(change.ID)
+		if err != nil {
+			fail(fmt.Errorf("redirector %s not found", change.Name))
+			return
+		}
+		if err := redirectors.Repo.DeleteCloudfrontDistribution(redirector); err != nil {
+			log.Printf("Error deleting Cloudfront domain: %v", err)
+			fail(fmt.Errorf("failed to delete redirector %s", redirector.URL))
+			return
+		}
+		m.endCost(models.CostRedirector, redirector.ID)
+	}
+
+	for _, change := range changes[blueprint.KindServer+" "+blueprint.ActionDestroy] {
+		if err := m.destroyPlanServer(change, userID); err != nil {
+			fail(err)
+			return
+		}
+	}
+
+	m.SendMessage(userID, fmt.Sprintf("Project %d matches its declared infrastructure, new servers are still being provisioned.", build.Project))
+}
+
+// destroyPlanServer removes a server, checking it is gone as RemoveServersRoutine only reports failures to the user
+func (m *Repository) destroyPlanServer(change blueprint.Change, userID string) error {
+	m.RemoveServersRoutine(change.ID, userID)
+	if _, err := m.DB.GetServer(change.ID); err == nil {
+		return fmt.Errorf("failed to destroy server %s", change.Name)
+	}
+	return nil
+}
+
+// updatePlanFirewall moves a kept server onto the firewall policy its declaration resolves to
+func (m *Repository) updatePlanFirewall(change blueprint.Change, policyID int, userID string) error {
+	vps, err := m.DB.GetServer(change.ID)
+	if err != nil {
+		return fmt.Errorf("server %s not found", change.Name)
+	}
+
+	if policyID == 0 {
+		m.RemoveFirewallRoutine(vps, userID)
+		return nil
+	}
+
+	if err := m.DB.UpdateServerFirewall(vps.ID, policyID); err != nil {
+		log.Printf("Error updating server firewall: %v", err)
+		return fmt.Errorf("failed to assign firewall policy to %s", vps.Name)
+	}
+	vps.Firewall = policyID
+	if err := m.applyServerFirewall(vps); err != nil {
+		log.Printf("Error applying firewall to server %d: %v", vps.ID, err)
+		return fmt.Errorf("failed to apply firewall to %s", vps.Name)
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nickzer0/GoBoxer/internal/domains"
+	"github.com/nickzer0/GoBoxer/internal/models"
+	"github.com/nickzer0/GoBoxer/internal/redirectors"
+	"github.com/nickzer0/GoBoxer/internal/server"
+)
+
+// serverReservedIPs returns the reserved IPs in the server's region and whether its provider supports reserved IPs
+func serverReservedIPs(vps models.Server) ([]server.ReservedIP, bool) {
+	provider, err := server.GetProvider(vps.Provider)
+	if err != nil {
+		return nil, false
+	}
+
+	manager, ok := provider.(server.IPManager)
+	if !ok || vps.ProviderID == "" {
+		return nil, false
+	}
+
+	ips, err := manager.ListIPs(vps.Region)
+	if err != nil {
+		log.Printf("Error listing reserved IPs for server %d: %v", vps.ID, err)
+	}
+	return ips, true
+}
+
+// ServerIP allocates, attaches, detaches, releases or rotates a reserved IP on the server identified in the URL.
+func (m *Repository) ServerIP(w http.ResponseWriter, r *http.Request) {
+	exploded := strings.Split(r.RequestURI, "/")
+	if len(exploded) < 5 {
+		log.Printf("Invalid server ID in URL")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+
+	serverID, err := strconv.Atoi(exploded[4])
+	if err != nil {
+		log.Printf("Error converting server ID to integer: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Invalid server ID provided.")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Error parsing form: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Failed to process form data.")
+		http.Redirect(w, r, fmt.Sprintf("/app/servers/%d", serverID), http.StatusSeeOther)
+		return
+	}
+
+	action := r.Form.Get("action")
+	address := r.Form.Get("address")
+	switch action {
+	case "allocate", "rotate":
+	case "attach", "detach", "release":
+		if address == "" {
+			m.App.Session.Put(r.Context(), "error", "No IP address selected.")
+			http.Redirect(w, r, fmt.Sprintf("/app/servers/%d", serverID), http.StatusSeeOther)
+			return
+		}
+	default:
+		m.App.Session.Put(r.Context(), "error", "Invalid IP action.")
+		http.Redirect(w, r, fmt.Sprintf("/app/servers/%d", serverID), http.StatusSeeOther)
+		return
+	}
+
+	targetServer, err := m.DB.GetServer(serverID)
+	if err != nil {
+		log.Printf("Error retrieving server by ID: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Server not found.")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+
+	provider, err := server.GetProvider(targetServer.Provider)
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Unknown provider for this server.")
+		http.Redirect(w, r, fmt.Sprintf("/app/servers/%d", serverID), http.StatusSeeOther)
+		return
+	}
+
+	manager, ok := provider.(server.IPManager)
+	if !ok || targetServer.ProviderID == "" {
+		m.App.Session.Put(r.Context(), "error", fmt.Sprintf("%s does not support reserved IPs.", provider.DisplayName()))
+		http.Redirect(w, r, fmt.Sprintf("/app/servers/%d", serverID), http.StatusSeeOther)
+		return
+	}
+
+	userID := strconv.Itoa(m.App.Session.Get(r.Context(), "user_id").(int))
+	go m.ServerIPRoutine(targetServer, provider, manager, action, address, userID)
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("IP %s requested for %s.", action, targetServer.Name))
+	http.Redirect(w, r, fmt.Sprintf("/app/servers/%d", serverID), http.StatusSeeOther)
+}
+
+// ServerIPRoutine performs a reserved IP action in the background. If the address the server is
+// reached on changes, the server record, DNS records and redirector origins are updated to match.
+func (m *Repository) ServerIPRoutine(targetServer models.Server, provider server.CloudProvider, manager server.IPManager, action, address, userID string) {
+	newIP, err := m.serverIPAction(targetServer, provider, manager, action, address)
+	if err != nil {
+		log.Printf("Error performing IP %s on server %d: %v", action, targetServer.ID, err)
+		m.SendError(userID, fmt.Sprintf("Error performing IP %s on %s: %v", action, targetServer.Name, err))
+		if newIP == "" {
+			return
+		}
+	}
+
+	oldIP := targetServer.IP
+	if newIP == "" || newIP == oldIP {
+		m.SendMessage(userID, fmt.Sprintf("IP %s complete for %s", action, targetServer.Name))
+		return
+	}
+
+	targetServer.IP = newIP
+	if err := m.DB.UpdateServer(targetServer); err != nil {
+		log.Printf("Error updating server in database: %v", err)
+		m.SendError(userID, "Error updating server in database!")
+		return
+	}
+
+	m.Broadcast("public-channel", "server-changed", map[string]string{
+		"server_id":  strconv.Itoa(targetServer.ID),
+		"project":    strconv.Itoa(targetServer.Project),
+		"provider":   targetServer.Provider,
+		"hostname":   targetServer.Name,
+		"os":         targetServer.OS,
+		"ip_address": newIP,
+		"status":     targetServer.Status,
+	})
+	m.SendMessage(userID, fmt.Sprintf("%s is now reachable on %s, updating DNS records and redirectors...", targetServer.Name, newIP))
+
+	m.updateIPReferences(oldIP, newIP, userID)
+}
+
+// serverIPAction performs a reserved IP action and returns the address the server is reached on afterwards,
+// or an empty string if it is unchanged
+func (m *Repository) serverIPAction(targetServer models.Server, provider server.CloudProvider, manager server.IPManager, action, address string) (string, error) {
+	switch action {
+	case "allocate":
+		ip, err := manager.AllocateIP(targetServer)
+		return ip.Address, err
+	case "rotate":
+		// The new address is returned even if the burned one could not be released
+		ip, err := server.RotateIP(manager, targetServer)
+		if errors.Is(err, server.ErrIPNotReattached) {
+			// References are moved to wherever the server can be reached now
+			current, getErr := provider.GetServer(targetServer)
+			if getErr != nil {
+				return "", fmt.Errorf("%v, and error fetching server from %s: %v", err, provider.DisplayName(), getErr)
+			}
+			return current.IP, err
+		}
+		return ip.Address, err
+	}
+
+	ip, err := server.FindIP(manager, targetServer.Region, address)
+	if err != nil {
+		return "", err
+	}
+
+	switch action {
+	case "attach":
+		if err := manager.AttachIP(targetServer, ip); err != nil {
+			return "", err
+		}
+		return ip.Address, nil
+	case "detach":
+		if err := manager.DetachIP(ip); err != nil {
+			if errors.Is(err, server.ErrNotSupported) {
+				return "", fmt.Errorf("%s cannot detach addresses, release it instead", provider.DisplayName())
+			}
+			return "", err
+		}
+	case "release":
+		if err := server.ReleaseServerIP(manager, ip); err != nil {
+			return "", err
+		}
+	}
+
+	// Fall back to the address the provider reports once the reserved IP is gone from the server
+	if ip.ServerProviderID != targetServer.ProviderID || ip.Address != targetServer.IP {
+		return "", nil
+	}
+	current, err := provider.GetServer(targetServer)
+	if err != nil {
+		return "", fmt.Errorf("error fetching server from %s: %v", provider.DisplayName(), err)
+	}
+	return current.IP, nil
+}
+
+// updateIPReferences points the DNS records and redirector origins using oldIP at newIP
+func (m *Repository) updateIPReferences(oldIP, newIP, userID string) {
+	// Without a previous address every record would match, so nothing is updated
+	if oldIP == "" || oldIP == "Pending" {
+		return
+	}
+
+	allDomains, err := m.DB.GetAllDomains()
+	if err != nil {
+		log.Printf("Error fetching domains: %v", err)
+		m.SendError(userID, "Error fetching domains, DNS records have not been updated.")
+	}
+
+	for _, domain := range allDomains {
+		// Only domains with a Route53 hosted zone are managed by GoBoxer
+		if _, err := m.DB.GetAWSHostedZone(domain.Name); err != nil {
+			continue
+		}
+
+		changed, err := domains.Repo.ReplaceRecordDataInAWS(domain, "A", oldIP, newIP)
+		if err != nil {
+			log.Printf("Error updating DNS records for %s: %v", domain.Name, err)
+			m.SendError(userID, fmt.Sprintf("Error updating DNS records for %s: %v", domain.Name, err))
+			continue
+		}
+		if changed > 0 {
+			m.SendMessage(userID, fmt.Sprintf("Updated %d DNS record(s) for %s", changed, domain.Name))
+		}
+	}
+
+	allRedirectors, err := m.DB.GetAllDomainRedirectors()
+	if err != nil {
+		log.Printf("Error fetching redirectors: %v", err)
+		m.SendError(userID, "Error fetching redirectors, origins have not been updated.")
+		return
+	}
+
+	for _, redirector := range allRedirectors {
+		// Origins using a domain name follow the DNS records, only those with the IP in the hostname need changing
+		origin, ok := replaceIPInHost(redirector.Domain, oldIP, newIP)
+		if !ok || redirector.Provider != "AWS" {
+			continue
+		}
+
+		if err := redirectors.Repo.UpdateCloudfrontOrigin(redirector, origin); err != nil {
+			log.Printf("Error updating origin of redirector %d: %v", redirector.ID, err)
+			m.SendError(userID, fmt.Sprintf("Error updating origin of redirector %s: %v", redirector.URL, err))
+			continue
+		}
+
+		m.SendMessage(userID, fmt.Sprintf("Redirector %s now points at %s", redirector.URL, origin))
+		m.Broadcast("public-channel", "redirector-changed", map[string]string{
+			"redirector_id": strconv.Itoa(redirector.ID),
+			"status":        "Updating",
+		})
+		go m.WaitUntilReadyRoutine(redirector)
+	}
+}
+
+// replaceIPInHost replaces an IPv4 address embedded in a hostname, either dotted as used by nip.io
+// or dashed as used by sslip.io and EC2 public DNS names, and reports whether it was found
+func replaceIPInHost(host, oldIP, newIP string) (string, bool) {
+	if oldIP == "" {
+		return host, false
+	}
+	if host == oldIP {
+		return newIP, true
+	}
+
+	for _, separator := range []string{".", "-"} {
+		old := strings.ReplaceAll(oldIP, ".", separator)
+		pattern := regexp.MustCompile(`(^|[^0-9])` + regexp.QuoteMeta(old) + `([^0-9]|$)`)
+		if pattern.MatchString(host) {
+			replacement := "${1}" + strings.ReplaceAll(newIP, ".", separator) + "${2}"
+			return pattern.ReplaceAllString(host, replacement), true
+		}
+	}
+	return host, false
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import "testing"
+
+func TestReplaceIPInHost(t *testing.T) {
+	tests := []struct {
+		name     string
+		host     string
+		oldIP    string
+		newIP    string
+		want     string
+		replaced bool
+	}{
+		{"bare address", "203.0.113.10", "203.0.113.10", "198.51.100.7", "198.51.100.7", true},
+		{"nip.io", "app.203.0.113.10.nip.io", "203.0.113.10", "198.51.100.7", "app.198.51.100.7.nip.io", true},
+		{"sslip.io", "203-0-113-10.sslip.io", "203.0.113.10", "198.51.100.7", "198-51-100-7.sslip.io", true},
+		{"ec2 public dns", "ec2-203-0-113-10.compute-1.amazonaws.com", "203.0.113.10", "198.51.100.7", "ec2-198-51-100-7.compute-1.amazonaws.com", true},
+		{"longer address", "203.0.113.100.nip.io", "203.0.113.10", "198.51.100.7", "203.0.113.100.nip.io", false},
+		{"address prefixed by digits", "1203.0.113.10.nip.io", "203.0.113.10", "198.51.100.7", "1203.0.113.10.nip.io", false},
+		{"other host", "cdn.example.com", "203.0.113.10", "198.51.100.7", "cdn.example.com", false},
+		{"empty old address", "cdn.example.com", "", "198.51.100.7", "cdn.example.com", false},
+		{"empty host and old address", "", "", "198.51.100.7", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, replaced := replaceIPInHost(tt.host, tt.oldIP, tt.newIP)
+			if got != tt.want || replaced != tt.replaced {
+				t.Errorf("replaceIPInHost(%q, %q, %q) = %q, %v, want %q, %v", tt.host, tt.oldIP, tt.newIP, got, replaced, tt.want, tt.replaced)
+			}
+		})
+	}
+}
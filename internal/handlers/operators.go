@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/nickzer0/GoBoxer/internal/deploy"
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
+
+// reconcileOperators brings the operator accounts on a server in line with its project's members
+func (m *Repository) reconcileOperators(vps models.Server, userID string) bool {
+	if err := deploy.ReconcileOperators(vps); err != nil {
+		log.Printf("Error reconciling operators on server %d: %v", vps.ID, err)
+		m.SendError(userID, fmt.Sprintf("Operator accounts on %s were not updated: %v", vps.Name, err))
+		return false
+	}
+	return true
+}
+
+// ReconcileProjectsRoutine updates the operator accounts on every server of the given projects,
+// after someone joins or leaves them or changes their SSH key
+func (m *Repository) ReconcileProjectsRoutine(projects []int, userID string) {
+	servers, err := m.DB.ListAllServers()
+	if err != nil {
+		log.Printf("Error listing servers: %v", err)
+		m.SendError(userID, "Operator accounts were not updated, servers could not be listed.")
+		return
+	}
+
+	inProject := make(map[int]bool)
+	for _, project := range projects {
+		inProject[project] = true
+	}
+
+	total, updated := 0, 0
+	for _, vps := range servers {
+		// Servers still being deployed are reconciled once they are reachable
+		if !inProject[vps.Project] || vps.IP == "" || vps.IP == "Pending" {
+			continue
+		}
+		total++
+		if m.reconcileOperators(vps, userID) {
+			updated++
+		}
+	}
+
+	if total > 0 {
+		m.SendMessage(userID, fmt.Sprintf("Updated operator accounts on %d of %d server(s)", updated, total))
+	}
+}
+
+// projectNumbers returns the numbers of the given projects
+func projectNumbers(projects []models.Project) []int {
+	var numbers []int
+	for _, project := range projects {
+		numbers = append(numbers, project.ProjectNumber)
+	}
+	return numbers
+}
+
+// sameMembers reports whether two lists of project members hold the same users
+func sameMembers(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	members := make(map[string]bool)
+	for _, username := range a {
+		members[username] = true
+	}
+	for _, username := range b {
+		if !members[username] {
+			return false
+		}
+	}
+	return true
+}
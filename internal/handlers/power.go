@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nickzer0/GoBoxer/internal/models"
+	"github.com/nickzer0/GoBoxer/internal/server"
+)
+
+// serverPowerActions returns the power actions the server's provider supports
+func serverPowerActions(providerName string) []server.PowerAction {
+	provider, err := server.GetProvider(providerName)
+	if err != nil {
+		return nil
+	}
+	return provider.Capabilities().PowerActions
+}
+
+// ServerPower starts a power action (power on, shutdown, reboot or reset) on the server identified in the URL.
+func (m *Repository) ServerPower(w http.ResponseWriter, r *http.Request) {
+	exploded := strings.Split(r.RequestURI, "/")
+	if len(exploded) < 5 {
+		log.Printf("Invalid server ID in URL")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+
+	serverID, err := strconv.Atoi(exploded[4])
+	if err != nil {
+		log.Printf("Error converting server ID to integer: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Invalid server ID provided.")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Error parsing form: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Failed to process form data.")
+		http.Redirect(w, r, fmt.Sprintf("/app/servers/%d", serverID), http.StatusSeeOther)
+		return
+	}
+
+	action, err := server.ParsePowerAction(r.Form.Get("action"))
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Invalid power action.")
+		http.Redirect(w, r, fmt.Sprintf("/app/servers/%d", serverID), http.StatusSeeOther)
+		return
+	}
+
+	targetServer, err := m.DB.GetServer(serverID)
+	if err != nil {
+		log.Printf("Error retrieving server by ID: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Server not found.")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+
+	provider, err := server.GetProvider(targetServer.Provider)
+	if err != nil || !provider.Capabilities().SupportsPower(action) {
+		m.App.Session.Put(r.Context(), "error", fmt.Sprintf("%s is not supported for this server.", action.Label()))
+		http.Redirect(w, r, fmt.Sprintf("/app/servers/%d", serverID), http.StatusSeeOther)
+		return
+	}
+
+	userID := strconv.Itoa(m.App.Session.Get(r.Context(), "user_id").(int))
+	go m.PowerServerRoutine(targetServer, provider, action, userID)
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("%s requested for %s.", action.Label(), targetServer.Name))
+	http.Redirect(w, r, fmt.Sprintf("/app/servers/%d", serverID), http.StatusSeeOther)
+}
+
+// PowerServerRoutine performs a power action in the background, recording the server status before and after.
+func (m *Repository) PowerServerRoutine(targetServer models.Server, provider server.CloudProvider, action server.PowerAction, userID string) {
+	previousStatus := targetServer.Status
+	data := map[string]string{
+		"server_id":  strconv.Itoa(targetServer.ID),
+		"project":    strconv.Itoa(targetServer.Project),
+		"provider":   targetServer.Provider,
+		"hostname":   targetServer.Name,
+		"os":         targetServer.OS,
+		"ip_address": targetServer.IP,
+		"status":     action.InProgressStatus(),
+	}
+
+	if err := m.DB.UpdateServerStatus(targetServer.ID, action.InProgressStatus()); err != nil {
+		log.Printf("Error updating server status: %v", err)
+	}
+	m.Broadcast("public-channel", "server-changed", data)
+
+	powerErr := provider.Power(targetServer, action)
+	if powerErr != nil {
+		log.Printf("Error performing %s on server %d: %v", action, targetServer.ID, powerErr)
+		m.SendError(userID, fmt.Sprintf("Error performing %s on %s: %v", action.Label(), targetServer.Name, powerErr))
+	}
+
+	// Record what the provider reports rather than assuming the action worked
+	status := previousStatus
+	current, err := provider.GetServer(targetServer)
+	if err != nil {
+		log.Printf("Error fetching server %d from %s: %v", targetServer.ID, provider.DisplayName(), err)
+	} else {
+		status = server.ReconciledStatus(previousStatus, current.Status)
+	}
+
+	data["status"] = status
+	if err := m.DB.UpdateServerStatus(targetServer.ID, status); err != nil {
+		log.Printf("Error updating server status: %v", err)
+	}
+	m.Broadcast("public-channel", "server-changed", data)
+
+	if powerErr == nil {
+		m.SendMessage(userID, fmt.Sprintf("%s complete for %s", action.Label(), targetServer.Name))
+	}
+}
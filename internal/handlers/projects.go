@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
@@ -152,6 +153,13 @@ func (m *Repository) AddProjectsPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	serverTTL, err := parseServerTTL(r.Form.Get("server_ttl"))
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Server lifetime must be a whole number of hours.")
+		http.Redirect(w, r, "/app/projects/add", http.StatusSeeOther)
+		return
+	}
+
 	assignedUsers := r.PostForm["assign_users"]
 
 	project := models.Project{
@@ -159,6 +167,7 @@ func (m *Repository) AddProjectsPost(w http.ResponseWriter, r *http.Request) {
 		ProjectName:   r.Form.Get("project_name"),
 		CreatedBy:     m.App.Session.Get(r.Context(), "username").(string),
 		Notes:         r.Form.Get("project_notes"),
+		ServerTTL:     serverTTL,
 	}
 
 	exists, err := m.DB.CheckProjectByNumber(projectID)
@@ -210,6 +219,13 @@ func (m *Repository) UpdateProjectsPost(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	serverTTL, err := parseServerTTL(r.Form.Get("server_ttl"))
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Server lifetime must be a whole number of hours.")
+		http.Redirect(w, r, fmt.Sprintf("/app/projects/%d", projectNumber), http.StatusSeeOther)
+		return
+	}
+
 	assignedUsers := r.PostForm["assign_users"]
 	project := models.Project{
 		ID:            projectID,
@@ -217,6 +233,13 @@ func (m *Repository) UpdateProjectsPost(w http.ResponseWriter, r *http.Request)
 		ProjectName:   r.Form.Get("project_name"),
 		CreatedBy:     m.App.Session.Get(r.Context(), "username").(string),
 		Notes:         r.Form.Get("project_notes"),
+		ServerTTL:     serverTTL,
+	}
+
+	// Membership is compared with what is stored so servers are only touched when it changes
+	previous, err := m.DB.GetProjectByNumber(projectNumber)
+	if err != nil {
+		log.Printf("Error fetching project by number: %v", err)
 	}
 
 	if err := m.DB.UpdateProject(project, assignedUsers); err != nil {
@@ -226,10 +249,29 @@ func (m *Repository) UpdateProjectsPost(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	m.App.Session.Put(r.Context(), "flash", "Project updated successfully.")
+	flash := "Project updated successfully."
+	if !sameMembers(previous.AssignedTo, assignedUsers) {
+		userID := strconv.Itoa(m.App.Session.Get(r.Context(), "user_id").(int))
+		go m.ReconcileProjectsRoutine([]int{projectNumber}, userID)
+		flash = "Project updated, updating operator accounts on its servers..."
+	}
+
+	m.App.Session.Put(r.Context(), "flash", flash)
 	http.Redirect(w, r, "/app/projects", http.StatusSeeOther)
 }
 
+// parseServerTTL reads the default server lifetime of a project in hours, blank meaning servers never expire
+func parseServerTTL(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	hours, err := strconv.Atoi(value)
+	if err != nil || hours < 0 {
+		return 0, fmt.Errorf("invalid server lifetime: %s", value)
+	}
+	return hours, nil
+}
+
 // RemoveProject handles the request to delete a project from the database.
 func (m *Repository) RemoveProject(w http.ResponseWriter, r *http.Request) {
 	exploded := strings.Split(r.RequestURI, "/")
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nickzer0/GoBoxer/internal/deploy"
+	"github.com/nickzer0/GoBoxer/internal/models"
+	"github.com/nickzer0/GoBoxer/internal/server"
+)
+
+// serverRebuildImages returns the images a server can be rebuilt with and whether its provider supports rebuilding
+func serverRebuildImages(vps models.Server) ([]server.CatalogItem, bool) {
+	provider, err := server.GetProvider(vps.Provider)
+	if err != nil {
+		return nil, false
+	}
+
+	if _, ok := provider.(server.Rebuilder); !ok || vps.ProviderID == "" {
+		return nil, false
+	}
+
+	catalog, err := server.GetCatalog(provider)
+	if err != nil {
+		log.Printf("Error fetching %s catalog: %v", provider.DisplayName(), err)
+	}
+	return catalog.Images, true
+}
+
+// ServerRebuild reimages the server identified in the URL in place, keeping its IP address.
+func (m *Repository) ServerRebuild(w http.ResponseWriter, r *http.Request) {
+	exploded := strings.Split(r.RequestURI, "/")
+	if len(exploded) < 5 {
+		log.Printf("Invalid server ID in URL")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+
+	serverID, err := strconv.Atoi(exploded[4])
+	if err != nil {
+		log.Printf("Error converting server ID to integer: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Invalid server ID provided.")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Error parsing form: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Failed to process form data.")
+		http.Redirect(w, r, fmt.Sprintf("/app/servers/%d", serverID), http.StatusSeeOther)
+		return
+	}
+
+	targetServer, err := m.DB.GetServer(serverID)
+	if err != nil {
+		log.Printf("Error retrieving server by ID: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Server not found.")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+
+	provider, err := server.GetProvider(targetServer.Provider)
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Unknown provider for this server.")
+		http.Redirect(w, r, fmt.Sprintf("/app/servers/%d", serverID), http.StatusSeeOther)
+		return
+	}
+
+	rebuilder, ok := provider.(server.Rebuilder)
+	if !ok || targetServer.ProviderID == "" {
+		m.App.Session.Put(r.Context(), "error", fmt.Sprintf("%s does not support rebuilding servers.", provider.DisplayName()))
+		http.Redirect(w, r, fmt.Sprintf("/app/servers/%d", serverID), http.StatusSeeOther)
+		return
+	}
+
+	// Only accept images from the provider's catalog
+	catalog, err := server.GetCatalog(provider)
+	if err != nil {
+		log.Printf("Error fetching %s catalog: %v", provider.DisplayName(), err)
+		m.App.Session.Put(r.Context(), "error", "Failed to fetch images from provider.")
+		http.Redirect(w, r, fmt.Sprintf("/app/servers/%d", serverID), http.StatusSeeOther)
+		return
+	}
+
+	image, ok := catalog.Image(r.Form.Get("image"))
+	if !ok {
+		m.App.Session.Put(r.Context(), "error", "Invalid image selection.")
+		http.Redirect(w, r, fmt.Sprintf("/app/servers/%d", serverID), http.StatusSeeOther)
+		return
+	}
+
+	userID := strconv.Itoa(m.App.Session.Get(r.Context(), "user_id").(int))
+	go m.RebuildServerRoutine(targetServer, rebuilder, image, userID)
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Rebuild of %s started.", targetServer.Name))
+	http.Redirect(w, r, fmt.Sprintf("/app/servers/%d", serverID), http.StatusSeeOther)
+}
+
+// RebuildServerRoutine reimages a server in the background, then restores access and re-runs its roles.
+func (m *Repository) RebuildServerRoutine(targetServer models.Server, rebuilder server.Rebuilder, image server.CatalogItem, userID string) {
+	data := map[string]string{
+		"server_id":  strconv.Itoa(targetServer.ID),
+		"project":    strconv.Itoa(targetServer.Project),
+		"provider":   targetServer.Provider,
+		"hostname":   targetServer.Name,
+		"os":         targetServer.OS,
+		"ip_address": targetServer.IP,
+		"status":     server.StatusRebuilding,
+	}
+
+	if err := m.DB.UpdateServerStatus(targetServer.ID, server.StatusRebuilding); err != nil {
+		log.Printf("Error updating server status: %v", err)
+	}
+	m.Broadcast("public-channel", "server-changed", data)
+
+	if err := rebuilder.RebuildServer(targetServer, image.Slug); err != nil {
+		log.Printf("Error rebuilding server %d: %v", targetServer.ID, err)
+		m.SendError(userID, fmt.Sprintf("Error rebuilding %s: %v", targetServer.Name, err))
+
+		data["status"] = "ERROR"
+		if err := m.DB.UpdateServerStatus(targetServer.ID, "ERROR"); err != nil {
+			log.Printf("Error updating server status: %v", err)
+		}
+		m.Broadcast("public-channel", "server-changed", data)
+		return
+	}
+
+	targetServer.Image = image.Slug
+	targetServer.OS = image.Name
+	targetServer.Status = "Configuring"
+	data["os"] = image.Name
+	data["status"] = targetServer.Status
+
+	if err := m.DB.UpdateServer(targetServer); err != nil {
+		log.Printf("Error updating server in database: %v", err)
+	}
+	if err := m.DB.UpdateServerOS(targetServer.ID, targetServer.OS); err != nil {
+		log.Printf("Error updating server OS in database: %v", err)
+	}
+
+	m.SendMessage(userID, fmt.Sprintf("Server %s rebuilt, configuring...", targetServer.Name))
+	m.Broadcast("public-channel", "server-changed", data)
+
+	// The new disk has new host keys, they are pinned again when access is restored
+	if err := m.DB.UpdateServerHostKey(targetServer.ID, ""); err != nil {
+		log.Printf("Error clearing host key of server %d: %v", targetServer.ID, err)
+	}
+
+	// The new disk only trusts the shared root key, so its own credentials are rotated in again
+	if err := m.DB.DeleteServerCredential(targetServer.ID); err != nil {
+		log.Printf("Error clearing root credentials of server %d: %v", targetServer.ID, err)
+	}
+
+	// The new disk does not trust the SSH CA yet
+	if err := deploy.TrustCertificateAuthority(targetServer); err != nil {
+		log.Printf("Error enabling access on server: %v", err)
+		m.SendError(userID, fmt.Sprintf("Error enabling access on %s: %v", targetServer.Name, err))
+	} else {
+		m.rotateRootCredentials(targetServer, userID)
+		m.reconcileOperators(targetServer, userID)
+	}
+
+	if len(targetServer.Roles) > 0 {
+		go m.ProvisionServerRoutine(targetServer, userID)
+		return
+	}
+
+	targetServer.Status = "Ready"
+	data["status"] = targetServer.Status
+	if err := m.DB.UpdateServerStatus(targetServer.ID, targetServer.Status); err != nil {
+		log.Printf("Error updating server status to ready: %v", err)
+	}
+
+	m.SendMessage(userID, fmt.Sprintf("Server %s ready", targetServer.Name))
+	m.Broadcast("public-channel", "server-changed", data)
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nickzer0/GoBoxer/internal/models"
+	"github.com/nickzer0/GoBoxer/internal/server"
+)
+
+var (
+	driftMutex  sync.RWMutex
+	driftReport models.DriftReport
+)
+
+// LastDriftReport returns the result of the most recent reconciliation run
+func LastDriftReport() models.DriftReport {
+	driftMutex.RLock()
+	defer driftMutex.RUnlock()
+	return driftReport
+}
+
+// ReconcileRoutine reconciles the servers table with the server providers on the given interval
+func (m *Repository) ReconcileRoutine(interval time.Duration) {
+	if interval <= 0 {
+		log.Println("Server reconciliation disabled")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		m.ReconcileServers()
+		<-ticker.C
+	}
+}
+
+// ReconcileServers compares the servers in the database with those listed by each configured
+// provider, updates the status of drifted servers and broadcasts any new findings.
+func (m *Repository) ReconcileServers() models.DriftReport {
+	report := models.DriftReport{
+		CheckedAt: time.Now(),
+		Errors:    make(map[string]string),
+	}
+
+	secrets, err := m.DB.GetAllSecrets()
+	if err != nil {
+		log.Printf("Error getting secrets from database: %v", err)
+		return report
+	}
+
+	recorded, err := m.DB.ListAllServers()
+	if err != nil {
+		log.Printf("Error fetching list of servers from database: %v", err)
+		return report
+	}
+
+	byProvider := make(map[string][]models.Server)
+	for _, dbServer := range recorded {
+		byProvider[dbServer.Provider] = append(byProvider[dbServer.Provider], dbServer)
+	}
+
+	for _, provider := range server.ConfiguredProviders(secrets) {
+		live, err := provider.ListServers()
+		if err != nil {
+			// A failed listing would report every server as a ghost, so skip the provider
+			log.Printf("Error listing servers from %s: %v", provider.DisplayName(), err)
+			report.Errors[provider.Name()] = err.Error()
+			continue
+		}
+		if manager, ok := provider.(server.IPManager); ok {
+			if ips, err := manager.ListIPs(""); err != nil {
+				log.Printf("Error listing reserved IPs from %s: %v", provider.DisplayName(), err)
+			} else {
+				server.PreferReservedIPs(live, byProvider[provider.Name()], ips)
+			}
+		}
+		report.Findings = append(report.Findings, server.Reconcile(provider.Name(), live, byProvider[provider.Name()])...)
+	}
+
+	for _, finding := range report.Findings {
+		var status string
+		switch finding.Kind {
+		case models.DriftGhost, models.DriftStatus:
+			status = finding.Actual
+		default:
+			// IP drift is only flagged, as DNS records and redirectors point at the recorded address
+			continue
+		}
+
+		if finding.Expected == status {
+			continue
+		}
+		if err := m.DB.UpdateServerStatus(finding.ServerID, status); err != nil {
+			log.Printf("Error updating status of server %d: %v", finding.ServerID, err)
+			continue
+		}
+		m.Broadcast("public-channel", "server-changed", map[string]string{
+			"server_id":  strconv.Itoa(finding.ServerID),
+			"provider":   finding.Provider,
+			"hostname":   finding.Name,
+			"ip_address": "",
+			"status":     status,
+		})
+	}
+
+	driftMutex.Lock()
+	previous := driftReport
+	driftReport = report
+	driftMutex.Unlock()
+
+	if count := newFindings(previous, report); count > 0 {
+		orphans, ghosts, drifted := countFindings(report)
+		m.Broadcast("public-channel", "drift-detected", map[string]string{
+			"orphans": strconv.Itoa(orphans),
+			"ghosts":  strconv.Itoa(ghosts),
+			"drift":   strconv.Itoa(drifted),
+			"message": fmt.Sprintf("Reconciliation found %d new issue(s): %d orphaned, %d missing, %d drifted", count, orphans, ghosts, drifted),
+		})
+	}
+
+	return report
+}
+
+// newFindings counts the findings in report which were not in previous. Findings are compared
+// without their values so a ghost is not reported again once its status has been updated.
+func newFindings(previous, report models.DriftReport) int {
+	type findingKey struct {
+		Kind       string
+		Provider   string
+		ProviderID string
+		ServerID   int
+	}
+
+	seen := make(map[findingKey]bool)
+	for _, finding := range previous.Findings {
+		seen[findingKey{finding.Kind, finding.Provider, finding.ProviderID, finding.ServerID}] = true
+	}
+
+	count := 0
+	for _, finding := range report.Findings {
+		if !seen[findingKey{finding.Kind, finding.Provider, finding.ProviderID, finding.ServerID}] {
+			count++
+		}
+	}
+	return count
+}
+
+// countFindings returns the number of orphans, ghosts and drifted servers in a report
+func countFindings(report models.DriftReport) (int, int, int) {
+	var orphans, ghosts, drifted int
+	for _, finding := range report.Findings {
+		switch finding.Kind {
+		case models.DriftOrphan:
+			orphans++
+		case models.DriftGhost:
+			ghosts++
+		default:
+			drifted++
+		}
+	}
+	return orphans, ghosts, drifted
+}
+
+// Reconcile runs a reconciliation in the background and returns to the dashboard
+func (m *Repository) Reconcile(w http.ResponseWriter, r *http.Request) {
+	go m.ReconcileServers()
+
+	m.App.Session.Put(r.Context(), "flash", "Reconciliation started, findings will appear on the dashboard.")
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
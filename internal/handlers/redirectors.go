@@ -125,6 +125,8 @@ func (m *Repository) RedirectorAddPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	m.startRedirectorCost(newRedirector)
+
 	go m.WaitUntilReadyRoutine(newRedirector)
 	m.SendMessage(userID, "Domain redirector creation initiated.")
 	http.Redirect(w, r, "/app/redirectors", http.StatusSeeOther)
@@ -187,6 +189,7 @@ func (m *Repository) RedirectorDelete(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, "/app/redirectors", http.StatusSeeOther)
 		return
 	}
+	m.endCost(models.CostRedirector, redirector.ID)
 
 	m.App.Session.Put(r.Context(), "flash", "Redirector deleted successfully!")
 	http.Redirect(w, r, "/app/redirectors", http.StatusSeeOther)
@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -38,45 +41,61 @@ func (m *Repository) Servers(w http.ResponseWriter, r *http.Request) {
 
 // ServersAdd displays the form for adding a new server, including lists of projects, scripts, and providers.
 func (m *Repository) ServersAdd(w http.ResponseWriter, r *http.Request) {
+	vars, err := m.serverFormVars(r)
+	if err != nil {
+		printErrorPage(w, err)
+		return
+	}
+
+	if err := helpers.RenderPage(w, r, "servers-add", vars, nil); err != nil {
+		log.Printf("Error rendering servers-add page: %v", err)
+		printTemplateError(w, err)
+	}
+}
+
+// serverFormVars returns the projects, scripts, providers and firewall policies offered when adding servers.
+func (m *Repository) serverFormVars(r *http.Request) (jet.VarMap, error) {
 	currentUser := m.App.Session.Get(r.Context(), "user").(models.User)
+	vars := make(jet.VarMap)
 
 	projects, err := m.DB.GetProjectsForUser(currentUser.Username)
 	if err != nil {
 		log.Printf("Error getting projects for user %s: %v", currentUser.Username, err)
-		printErrorPage(w, err)
-		return
+		return vars, err
 	}
 
 	scripts, err := GetAnsibleScriptsNames()
 	if err != nil {
 		log.Printf("Error getting Ansible script names: %v", err)
-		printErrorPage(w, err)
-		return
+		return vars, err
 	}
 
 	secrets, err := m.DB.GetAllSecrets()
 	if err != nil {
 		log.Printf("Error getting all secrets: %v", err)
-		printTemplateError(w, err)
-		return
+		return vars, err
 	}
 
-	var providers []string
-	for name := range secrets {
-		if secrets[name] != "" {
-			providers = append(providers, name)
-		}
+	providers := server.ConfiguredProviders(secrets)
+
+	firewallPolicies, err := m.firewallPoliciesForUser(currentUser.Username)
+	if err != nil {
+		log.Printf("Error getting firewall policies for user %s: %v", currentUser.Username, err)
+		return vars, err
 	}
 
-	vars := make(jet.VarMap)
+	cloudInitTemplates, err := m.DB.ListCloudInitTemplates()
+	if err != nil {
+		log.Printf("Error listing cloud-init templates: %v", err)
+		return vars, err
+	}
+
+	vars.Set("firewall_policies", firewallPolicies)
+	vars.Set("cloud_init_templates", cloudInitTemplates)
 	vars.Set("projects", projects)
 	vars.Set("scripts", scripts)
 	vars.Set("providers", providers)
-
-	if err := helpers.RenderPage(w, r, "servers-add", vars, nil); err != nil {
-		log.Printf("Error rendering servers-add page: %v", err)
-		printTemplateError(w, err)
-	}
+	return vars, nil
 }
 
 // ServersAddPost processes the form submission for adding a new server and initiates its deployment.
@@ -91,7 +110,7 @@ func (m *Repository) ServersAddPost(w http.ResponseWriter, r *http.Request) {
 
 	if err := r.ParseForm(); err != nil {
 		log.Printf("Error parsing form: %v", err)
-		m.SendError(userID, "Failed to process form data.")
+		m.App.Session.Put(r.Context(), "error", "Failed to process form data.")
 		http.Redirect(w, r, "/app/servers/add", http.StatusSeeOther)
 		return
 	}
@@ -99,32 +118,77 @@ func (m *Repository) ServersAddPost(w http.ResponseWriter, r *http.Request) {
 	provider := r.Form.Get("provider")
 	project := r.Form.Get("assign_project")
 	hostname := r.Form.Get("hostname")
+	region := r.Form.Get("region")
+	size := r.Form.Get("size")
+	image := r.Form.Get("image")
 	scripts := r.PostForm["scripts"]
 
 	projectInt, err := strconv.Atoi(project)
 	if err != nil {
 		log.Printf("Error converting project ID: %v", err)
-		m.SendError(userID, "Invalid project selection.")
+		m.App.Session.Put(r.Context(), "error", "Invalid project selection.")
+		http.Redirect(w, r, "/app/servers/add", http.StatusSeeOther)
+		return
+	}
+
+	cloudProvider, err := server.GetProvider(provider)
+	if err != nil {
+		log.Printf("Error getting provider: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Invalid provider selection.")
+		http.Redirect(w, r, "/app/servers/add", http.StatusSeeOther)
+		return
+	}
+
+	// Servers at providers without a cloud firewall are left to the Ansible roles
+	firewallPolicy := 0
+	if _, ok := cloudProvider.(server.Firewaller); ok {
+		firewallPolicy, err = m.resolveFirewallPolicy(projectInt, r.Form.Get("firewall"))
+		if err != nil {
+			log.Printf("Error resolving firewall policy: %v", err)
+			m.App.Session.Put(r.Context(), "error", "Invalid firewall policy selection.")
+			http.Redirect(w, r, "/app/servers/add", http.StatusSeeOther)
+			return
+		}
+	}
+
+	expiresAt, err := m.resolveServerTTL(projectInt, r.Form.Get("ttl"))
+	if err != nil {
+		log.Printf("Error resolving server lifetime: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Invalid server lifetime.")
+		http.Redirect(w, r, "/app/servers/add", http.StatusSeeOther)
+		return
+	}
+
+	cloudInit, err := m.resolveCloudInit(r.Form.Get("cloud_init"))
+	if err != nil {
+		log.Printf("Error resolving cloud-init template: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Invalid cloud-init template selection.")
 		http.Redirect(w, r, "/app/servers/add", http.StatusSeeOther)
 		return
 	}
 
 	newServer := models.Server{
-		OS:        "ubuntu-2204",
+		OS:        serverOS(cloudProvider, image),
 		IP:        "Pending",
 		Provider:  provider,
 		Name:      hostname,
+		Region:    region,
+		Size:      size,
+		Image:     image,
 		Status:    "Deploying",
 		Roles:     scripts,
+		Firewall:  firewallPolicy,
+		CloudInit: cloudInit,
 		Project:   projectInt,
 		Creator:   m.App.Session.Get(r.Context(), "username").(string),
 		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
 	}
 
 	databaseServer, err := m.DB.AddServerToDatabase(newServer)
 	if err != nil {
 		log.Printf("Error adding server to database: %v", err)
-		m.SendError(userID, "Failed to add server to database.")
+		m.App.Session.Put(r.Context(), "error", "Failed to add server to database.")
 		http.Redirect(w, r, "/app/servers/add", http.StatusSeeOther)
 		return
 	}
@@ -136,6 +200,46 @@ func (m *Repository) ServersAddPost(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
 }
 
+// serverOS returns the image name to record as a new server's OS, falling back to the default image
+func serverOS(provider server.CloudProvider, image string) string {
+	if image == "" {
+		return "ubuntu-2204"
+	}
+	if catalog, err := server.GetCatalog(provider); err == nil {
+		if item, ok := catalog.Image(image); ok {
+			return item.Name
+		}
+	}
+	return image
+}
+
+// ServersCatalog returns the regions, sizes and images offered by a provider as JSON for the add server form.
+func (m *Repository) ServersCatalog(w http.ResponseWriter, r *http.Request) {
+	exploded := strings.Split(r.RequestURI, "/")
+	if len(exploded) < 5 {
+		http.Error(w, "missing provider", http.StatusBadRequest)
+		return
+	}
+
+	provider, err := server.GetProvider(exploded[4])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	catalog, err := server.GetCatalog(provider)
+	if err != nil {
+		log.Printf("Error getting catalog for %s: %v", provider.Name(), err)
+		http.Error(w, "Failed to get provider catalog", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(catalog); err != nil {
+		log.Printf("Error encoding catalog: %v", err)
+	}
+}
+
 func (m *Repository) CreateServerRoutine(newServer models.Server, userID string) {
 	// Initialize deployment status and data
 	status := "Configuring"
@@ -148,28 +252,60 @@ func (m *Repository) CreateServerRoutine(newServer models.Server, userID string)
 		"status":    status,
 	}
 
-	// Deploy server based on its provider
-	var deployedServer models.Server
-	var err error
-	switch newServer.Provider {
-	case "digitalocean":
-		deployedServer, err = server.Repo.DigitalOceanCreateServer(newServer)
-	case "linode":
-		deployedServer, err = server.Repo.LinodeCreateServer(newServer)
-	default:
-		log.Printf("Unknown provider: %s", newServer.Provider)
+	provider, err := server.GetProvider(newServer.Provider)
+	if err != nil {
+		_ = m.DB.DeleteServerFromDatabase(newServer.ID)
+		log.Printf("Error deploying server: %v", err)
 		m.SendMessage(userID, "Unknown provider specified for deployment.")
 		return
 	}
 
+	// First-boot setup from the cloud-init template runs before the server is reachable over SSH
+	if newServer.CloudInit != 0 {
+		if provider.Capabilities().UserData {
+			newServer.UserData, err = m.serverUserData(newServer)
+			if err != nil {
+				_ = m.DB.DeleteServerFromDatabase(newServer.ID)
+				log.Printf("Error rendering cloud-init for server %s: %v", newServer.Name, err)
+				m.SendError(userID, fmt.Sprintf("Error rendering cloud-init for %s: %v", newServer.Name, err))
+				data["status"] = "Failed"
+				m.Broadcast("public-channel", "server-changed", data)
+				return
+			}
+		} else {
+			m.SendError(userID, fmt.Sprintf("%s does not support cloud-init, %s is created without it", provider.DisplayName(), newServer.Name))
+		}
+	}
+
+	// Deploy server to the selected provider
+	deployedServer, err := provider.CreateServer(newServer)
+
 	// Update data with IP address of the deployed server
 	data["ip_address"] = deployedServer.IP
 
 	// Handle errors from server deployment
 	if err != nil {
-		_ = m.DB.DeleteServerFromDatabase(newServer.ID)
 		log.Printf("Error deploying server: %v", err)
 		m.SendMessage(userID, "Error deploying server.")
+		data["status"] = "Failed"
+
+		// The provider may have created the server before failing, it must not be left running untracked
+		if deployedServer.ProviderID != "" {
+			if deleteErr := provider.DeleteServer(deployedServer); deleteErr != nil {
+				log.Printf("Error deleting failed server %s at %s: %v", deployedServer.ProviderID, provider.DisplayName(), deleteErr)
+				m.SendError(userID, fmt.Sprintf("%s could not be removed from %s after failing, it is kept with status ERROR", deployedServer.Name, provider.DisplayName()))
+				deployedServer.Status = "ERROR"
+				data["status"] = deployedServer.Status
+				if err := m.DB.UpdateServer(deployedServer); err != nil {
+					log.Printf("Error updating server in database: %v", err)
+				}
+				m.Broadcast("public-channel", "server-changed", data)
+				return
+			}
+		}
+
+		_ = m.DB.DeleteServerFromDatabase(newServer.ID)
+		m.Broadcast("public-channel", "server-changed", data)
 		return
 	}
 
@@ -177,27 +313,33 @@ func (m *Repository) CreateServerRoutine(newServer models.Server, userID string)
 	if err = m.DB.UpdateServer(deployedServer); err != nil {
 		log.Printf("Error updating server in database: %v", err)
 	}
-
-	m.SendMessage(userID, fmt.Sprintf("Server %s deployed, configuring...", deployedServer.Name))
-	m.Broadcast("public-channel", "server-changed", data)
-
-	// Convert userID to int for database query
-	userIDint, err := strconv.Atoi(userID)
-	if err != nil {
-		log.Printf("Error converting userID to int: %v", err)
+	m.startServerCost(deployedServer)
+
+	// Lock the server down before anything is installed on it
+	if err = m.applyServerFirewall(deployedServer); err != nil {
+		// Nothing is installed on a server that is not behind the policy it was deployed with
+		log.Printf("Error applying firewall to server %d: %v", deployedServer.ID, err)
+		m.SendError(userID, fmt.Sprintf("Error applying firewall to %s, it has not been configured: %v", deployedServer.Name, err))
+		deployedServer.Status = "ERROR"
+		data["status"] = deployedServer.Status
+		if err := m.DB.UpdateServer(deployedServer); err != nil {
+			log.Printf("Error updating server in database: %v", err)
+		}
+		m.Broadcast("public-channel", "server-changed", data)
 		return
 	}
 
-	// Retrieve user details from the database
-	user, err := m.DB.GetUserFromID(userIDint)
-	if err != nil {
-		log.Printf("Error retrieving user from database: %v", err)
-		return
-	}
+	m.SendMessage(userID, fmt.Sprintf("Server %s deployed, configuring...", deployedServer.Name))
+	m.Broadcast("public-channel", "server-changed", data)
 
-	// Add SSH key for the user to the deployed server
-	if err = deploy.AddSSHUser(deployedServer, user); err != nil {
+	// Let operators in with certificates from the SSH CA
+	if err = deploy.TrustCertificateAuthority(deployedServer); err != nil {
 		log.Printf("Error enabling access on server: %v", err)
+		m.SendError(userID, fmt.Sprintf("Error enabling access on %s: %v", deployedServer.Name, err))
+	} else {
+		// Replace the shared root key with the server's own before anything else is pushed to it
+		m.rotateRootCredentials(deployedServer, userID)
+		m.reconcileOperators(deployedServer, userID)
 	}
 
 	// If server has roles, initiate provisioning routine
@@ -339,6 +481,32 @@ func (m *Repository) ViewServer(w http.ResponseWriter, r *http.Request) {
 	vars := make(jet.VarMap)
 	vars.Set("server", server)
 	vars.Set("scripts", scripts)
+	vars.Set("power_actions", serverPowerActions(server.Provider))
+	vars.Set("host_key_fingerprints", deploy.HostKeyFingerprints(server.HostKey))
+
+	// Servers without credentials of their own have never been rotated
+	credential, err := m.DB.GetServerCredential(server.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Error getting root credentials of server %d: %v", server.ID, err)
+	}
+	vars.Set("credentials_rotated", credential.RotatedAt)
+
+	snapshots, snapshotsSupported := serverSnapshots(server)
+	vars.Set("snapshots", snapshots)
+	vars.Set("snapshots_supported", snapshotsSupported)
+
+	rebuildImages, rebuildSupported := serverRebuildImages(server)
+	vars.Set("rebuild_images", rebuildImages)
+	vars.Set("rebuild_supported", rebuildSupported)
+
+	reservedIPs, ipsSupported := serverReservedIPs(server)
+	vars.Set("reserved_ips", reservedIPs)
+	vars.Set("ips_supported", ipsSupported)
+
+	firewallPolicies, firewallRules, firewallSupported := m.serverFirewallPolicies(server)
+	vars.Set("firewall_policies", firewallPolicies)
+	vars.Set("firewall_rules", firewallRules)
+	vars.Set("firewall_supported", firewallSupported)
 
 	if err := helpers.RenderPage(w, r, "servers-view", vars, nil); err != nil {
 		log.Printf("Error rendering server view page: %v", err)
@@ -445,26 +613,26 @@ func (m *Repository) RemoveServersRoutine(serverID int, userID string) {
 		return
 	}
 
-	// Attempt to delete server from the cloud provider
-	switch serverToRemove.Provider {
-	case "digitalocean":
-		if err := server.Repo.DigitalOceanDeleteServer(serverToRemove.ProviderID); err != nil {
-			log.Printf("Error deleting DigitalOcean server %d: %v", serverToRemove.ProviderID, err)
-			m.SendMessage(userID, fmt.Sprintf("Error removing server from DigitalOcean: %s", serverToRemove.Name))
-			return
-		}
-	case "linode":
-		if err := server.Repo.LinodeDeleteServer(serverToRemove.ProviderID); err != nil {
-			log.Printf("Error deleting Linode server %d: %v", serverToRemove.ProviderID, err)
-			m.SendMessage(userID, fmt.Sprintf("Error removing server from Linode: %s", serverToRemove.Name))
-			return
-		}
-	default:
+	provider, err := server.GetProvider(serverToRemove.Provider)
+	if err != nil {
 		log.Printf("Unknown provider for server %d: %s", serverID, serverToRemove.Provider)
 		m.SendMessage(userID, fmt.Sprintf("Unknown provider for server: %s", serverToRemove.Name))
 		return
 	}
 
+	// Provider firewalls outlive the servers they are attached to
+	if err := m.removeServerFirewall(serverToRemove); err != nil {
+		log.Printf("Error removing firewall from server %d: %v", serverID, err)
+	}
+
+	// Attempt to delete server from the cloud provider
+	if err := provider.DeleteServer(serverToRemove); err != nil {
+		log.Printf("Error deleting %s server %s: %v", provider.DisplayName(), serverToRemove.ProviderID, err)
+		m.SendMessage(userID, fmt.Sprintf("Error removing server from %s: %s", provider.DisplayName(), serverToRemove.Name))
+		return
+	}
+	m.endCost(models.CostServer, serverID)
+
 	if err := m.DB.DeleteServerFromDatabase(serverID); err != nil {
 		log.Printf("Error deleting server %d from database: %v", serverID, err)
 		m.SendMessage(userID, fmt.Sprintf("Error removing server from database: %s", serverToRemove.Name))
@@ -498,22 +666,24 @@ func (m *Repository) DeleteAllServersRoutine() {
 	}
 
 	for _, server := range servers {
+		m.endCost(models.CostServer, server.ID)
 		if err := m.DB.DeleteServerFromDatabase(server.ID); err != nil {
 			log.Printf("error deleting server (ID: %d) from database: %v", server.ID, err)
 		}
 	}
 
-	// Asynchronously delete all servers from DigitalOcean
-	go func() {
-		if err := server.Repo.DigitalOceanDeleteAll(); err != nil {
-			log.Printf("error deleting all servers from DigitalOcean: %v", err)
-		}
-	}()
+	secrets, err := m.DB.GetAllSecrets()
+	if err != nil {
+		log.Printf("error getting secrets from database: %v", err)
+		return
+	}
 
-	// Asynchronously delete all servers from Linode
-	go func() {
-		if err := server.Repo.LinodeDeleteAll(); err != nil {
-			log.Printf("error deleting all servers from Linode: %v", err)
-		}
-	}()
+	// Asynchronously delete all servers from each configured provider
+	for _, provider := range server.ConfiguredProviders(secrets) {
+		go func(provider server.CloudProvider) {
+			if err := provider.DeleteAll(); err != nil {
+				log.Printf("error deleting all servers from %s: %v", provider.DisplayName(), err)
+			}
+		}(provider)
+	}
 }
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nickzer0/GoBoxer/internal/models"
+	"github.com/nickzer0/GoBoxer/internal/server"
+)
+
+// serverSnapshots returns the snapshots of a server and whether its provider supports snapshots
+func serverSnapshots(vps models.Server) ([]server.Snapshot, bool) {
+	provider, err := server.GetProvider(vps.Provider)
+	if err != nil {
+		return nil, false
+	}
+
+	snapshotter, ok := provider.(server.Snapshotter)
+	if !ok || vps.ProviderID == "" {
+		return nil, false
+	}
+
+	snapshots, err := snapshotter.ListSnapshots(vps)
+	if err != nil {
+		log.Printf("Error listing snapshots for server %d: %v", vps.ID, err)
+	}
+	return snapshots, true
+}
+
+// ServerSnapshot starts a snapshot of the server identified in the URL.
+func (m *Repository) ServerSnapshot(w http.ResponseWriter, r *http.Request) {
+	exploded := strings.Split(r.RequestURI, "/")
+	if len(exploded) < 5 {
+		log.Printf("Invalid server ID in URL")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+
+	serverID, err := strconv.Atoi(exploded[4])
+	if err != nil {
+		log.Printf("Error converting server ID to integer: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Invalid server ID provided.")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Error parsing form: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Failed to process form data.")
+		http.Redirect(w, r, fmt.Sprintf("/app/servers/%d", serverID), http.StatusSeeOther)
+		return
+	}
+
+	targetServer, err := m.DB.GetServer(serverID)
+	if err != nil {
+		log.Printf("Error retrieving server by ID: %v", err)
+		m.App.Session.Put(r.Context(), "error", "Server not found.")
+		http.Redirect(w, r, "/app/servers", http.StatusSeeOther)
+		return
+	}
+
+	provider, err := server.GetProvider(targetServer.Provider)
+	if err != nil {
+		m.App.Session.Put(r.Context(), "error", "Unknown provider for server.")
+		http.Redirect(w, r, fmt.Sprintf("/app/servers/%d", serverID), http.StatusSeeOther)
+		return
+	}
+
+	snapshotter, ok := provider.(server.Snapshotter)
+	if !ok {
+		m.App.Session.Put(r.Context(), "error", fmt.Sprintf("%s does not support snapshots.", provider.DisplayName()))
+		http.Redirect(w, r, fmt.Sprintf("/app/servers/%d", serverID), http.StatusSeeOther)
+		return
+	}
+
+	name := strings.TrimSpace(r.Form.Get("snapshot_name"))
+	if name == "" {
+		name = fmt.Sprintf("%s-%s", targetServer.Name, time.Now().Format("20060102-1504"))
+	}
+
+	userID := strconv.Itoa(m.App.Session.Get(r.Context(), "user_id").(int))
+	go m.SnapshotServerRoutine(targetServer, provider, snapshotter, name, userID)
+
+	m.App.Session.Put(r.Context(), "flash", fmt.Sprintf("Snapshot %s started, this may take a while.", name))
+	http.Redirect(w, r, fmt.Sprintf("/app/servers/%d", serverID), http.StatusSeeOther)
+}
+
+// SnapshotServerRoutine takes a snapshot in the background and refreshes the provider catalog
+// so the snapshot can be chosen as an image straight away.
+func (m *Repository) SnapshotServerRoutine(targetServer models.Server, provider server.CloudProvider, snapshotter server.Snapshotter, name, userID string) {
+	if err := snapshotter.CreateSnapshot(targetServer, name); err != nil {
+		log.Printf("Error creating snapshot of server %d: %v", targetServer.ID, err)
+		m.SendError(userID, fmt.Sprintf("Error creating snapshot of %s: %v", targetServer.Name, err))
+		return
+	}
+
+	server.InvalidateCatalog(provider)
+	m.SendMessage(userID, fmt.Sprintf("Snapshot %s of %s complete", name, targetServer.Name))
+}
@@ -1,6 +1,7 @@
 package helpers
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 )
@@ -18,6 +19,10 @@ func addTemplateFunctions(r *http.Request) {
 		return DateAfterY1(t)
 	})
 
+	views.AddGlobal("currency", func(amount float64) string {
+		return Currency(amount)
+	})
+
 	views.AddGlobal("isAdmin", func() bool {
 		return IsAdmin(r)
 	})
@@ -43,6 +48,11 @@ func DateAfterY1(t time.Time) bool {
 	return t.After(yearOne)
 }
 
+// Currency formats an amount in US dollars, which all providers price in
+func Currency(amount float64) string {
+	return fmt.Sprintf("$%.2f", amount)
+}
+
 // IsAdmin is used in templateData to check access level of authenticated user
 // and renders certain admin level functions on some pages if true
 func IsAdmin(r *http.Request) bool {
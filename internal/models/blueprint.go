@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Blueprint is the model for a stored infrastructure blueprint,
+// the YAML describing the stack is kept in Spec
+type Blueprint struct {
+	ID          int
+	Name        string
+	Description string
+	Spec        string
+	CreatedBy   string
+	CreatedAt   time.Time
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// BulkDeployment is the model for a set of servers created from a single bulk form submission.
+// Servers keeps what was queued, as servers that fail to deploy are removed from the servers table.
+type BulkDeployment struct {
+	ID        int
+	Project   int
+	CreatedBy string
+	CreatedAt time.Time
+	Servers   []Server
+}
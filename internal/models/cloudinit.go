@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// CloudInitTemplate is the model for a stored cloud-init template, rendered
+// into the user data a server is created with
+type CloudInitTemplate struct {
+	ID          int
+	Name        string
+	Description string
+	Content     string
+	CreatedBy   string
+	CreatedAt   time.Time
+}
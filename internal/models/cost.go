@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// HoursPerMonth is the average number of hours in a month, as used by providers for monthly prices
+const HoursPerMonth = 730
+
+// Resource types that are costed
+const (
+	CostServer     = "server"
+	CostDomain     = "domain"
+	CostRedirector = "redirector"
+)
+
+// Cost is the model for the running cost of a server, domain or redirector.
+// Costs are kept after the resource is removed so past spend can still be reported.
+type Cost struct {
+	ID           int
+	ResourceType string
+	ResourceID   int
+	Name         string
+	Provider     string
+	Project      int
+	PriceHourly  float64
+	StartedAt    time.Time
+	EndedAt      time.Time
+}
+
+// Running reports whether the resource has not been removed yet
+func (c Cost) Running() bool {
+	return c.EndedAt.IsZero()
+}
+
+// Spend returns the cost accumulated from the start of the resource until it was removed, or until now
+func (c Cost) Spend(now time.Time) float64 {
+	end := c.EndedAt
+	if c.Running() {
+		end = now
+	}
+	hours := end.Sub(c.StartedAt).Hours()
+	if hours < 0 {
+		return 0
+	}
+	return hours * c.PriceHourly
+}
+
+// Spend is the model for the accumulated cost of a group of resources,
+// such as everything in a project or at a provider
+type Spend struct {
+	Name        string
+	Running     int
+	PriceHourly float64
+	Total       float64
+}
+
+// PriceMonthly returns the monthly cost of the resources still running
+func (s Spend) PriceMonthly() float64 {
+	return s.PriceHourly * HoursPerMonth
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// ServerCredential is the root SSH key pair and password unique to a server. The private key
+// and password are stored encrypted. Servers without one still trust the shared root key.
+type ServerCredential struct {
+	ServerID   int
+	ServerName string
+	Provider   string
+	Project    int
+	PublicKey  string
+	PrivateKey string
+	Password   string
+	RotatedAt  time.Time
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Kinds of drift found when reconciling the servers table with the server providers
+const (
+	// DriftOrphan is a server running at a provider which is missing from the database
+	DriftOrphan = "orphan"
+	// DriftGhost is a server in the database which no longer exists at its provider
+	DriftGhost = "ghost"
+	// DriftIP is a server whose IP address at the provider differs from the database
+	DriftIP = "ip"
+	// DriftStatus is a server whose power state at the provider differs from the database
+	DriftStatus = "status"
+)
+
+// Drift is a single difference between the servers table and a server provider
+type Drift struct {
+	Kind       string
+	Provider   string
+	ProviderID string
+	ServerID   int
+	Name       string
+	Expected   string
+	Actual     string
+}
+
+// DriftReport is the result of a reconciliation run
+type DriftReport struct {
+	CheckedAt time.Time
+	Findings  []Drift
+	// Errors holds the providers that could not be listed, keyed by provider name
+	Errors map[string]string
+}
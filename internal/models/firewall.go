@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// FirewallRule allows inbound traffic to a port or port range from a list of sources
+type FirewallRule struct {
+	Protocol string
+	// Ports is a single port, a range such as 8000-8100, or empty for every port
+	Ports   string
+	Sources []string
+}
+
+// FirewallPolicy is a named set of inbound rules applied to servers through their
+// provider's cloud firewall, inbound traffic not matched by a rule is dropped
+type FirewallPolicy struct {
+	ID             int
+	Name           string
+	Project        int
+	ProjectDefault bool
+	Rules          []FirewallRule
+	CreatedBy      string
+	CreatedAt      time.Time
+}
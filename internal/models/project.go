@@ -10,6 +10,9 @@ type Project struct {
 	ProjectName   string
 	CreatedBy     string
 	Notes         string
-	CreatedAt     time.Time
-	AssignedTo    []string
+	ServerTTL     int
+	// Infrastructure is the project's declared infrastructure, in the blueprint format
+	Infrastructure string
+	CreatedAt      time.Time
+	AssignedTo     []string
 }
@@ -6,14 +6,25 @@ import "time"
 // within the application
 type Server struct {
 	ID         int
-	ProviderID int
+	ProviderID string
 	OS         string
 	Provider   string
 	Name       string
 	IP         string
+	Region     string
+	Size       string
+	Image      string
 	Status     string
 	Roles      []string
-	Project    int
-	Creator    string
-	CreatedAt  time.Time
+	Firewall   int
+	// CloudInit is the ID of the cloud-init template the server was created with
+	CloudInit int
+	// UserData is the rendered cloud-init template passed to the provider, it is not stored
+	UserData string
+	// HostKey holds the SSH host keys pinned on first contact, one per line in authorized_keys format
+	HostKey   string
+	Project   int
+	Creator   string
+	CreatedAt time.Time
+	ExpiresAt time.Time
 }
@@ -9,4 +9,6 @@ type Services struct {
 	NameCheap    int
 	GoDaddy      int
 	Azure        int
+	// Servers holds the number of servers deployed to each server provider
+	Servers map[string]int
 }
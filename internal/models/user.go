@@ -1,5 +1,7 @@
 package models
 
+import "strings"
+
 // User struct for application users and settings
 type User struct {
 	ID             int
@@ -11,4 +13,15 @@ type User struct {
 	SSHKey         string
 	Preferences    map[string]string
 	Projects       []Project
-}
\ No newline at end of file
+}
+
+// AuthorizedKeys splits the user's SSH key setting into its keys, "0" being stored when no key is set
+func (u User) AuthorizedKeys() []string {
+	var keys []string
+	for _, line := range strings.Split(u.SSHKey, "\n") {
+		if key := strings.TrimSpace(line); key != "" && key != "0" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -248,3 +248,66 @@ func (m *Repository) ResyncDistribution(redirector models.Redirector) {
 	// Use a goroutine to asynchronously wait for the CloudFront distribution to be deployed
 	go m.WaitForCloudfrontDeployedRoutine(svc, getDistributionInput, redirector)
 }
+
+// UpdateCloudfrontOrigin points a Cloudfront distribution at a new origin domain, then waits
+// in the background for the change to deploy before marking the redirector ready again.
+func (m *Repository) UpdateCloudfrontOrigin(redirector models.Redirector, origin string) error {
+	awsAccount, err := m.DB.GetSecret("awsaccount")
+	if err != nil {
+		log.Printf("Failed to get AWS account secret: %v", err)
+		return err
+	}
+
+	awsSecret, err := m.DB.GetSecret("awssecret")
+	if err != nil {
+		log.Printf("Failed to get AWS secret: %v", err)
+		return err
+	}
+
+	creds := credentials.NewStaticCredentials(awsAccount, awsSecret, "")
+	session, err := session.NewSession(&aws.Config{
+		Credentials: creds,
+		MaxRetries:  aws.Int(3),
+	})
+	if err != nil {
+		return err
+	}
+
+	svc := cloudfront.New(session)
+	configOutput, err := svc.GetDistributionConfig(&cloudfront.GetDistributionConfigInput{
+		Id: &redirector.ProviderID,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The origin ID is left as it is, as the cache behaviour refers to the origin by ID
+	for _, item := range configOutput.DistributionConfig.Origins.Items {
+		if aws.StringValue(item.DomainName) == redirector.Domain {
+			item.DomainName = aws.String(origin)
+		}
+	}
+
+	_, err = svc.UpdateDistribution(&cloudfront.UpdateDistributionInput{
+		DistributionConfig: configOutput.DistributionConfig,
+		Id:                 &redirector.ProviderID,
+		IfMatch:            configOutput.ETag,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := m.DB.UpdateRedirectorOrigin(redirector.ID, origin); err != nil {
+		return err
+	}
+
+	redirector.Domain = origin
+	redirector.Status = "Updating"
+	if err := m.DB.UpdateDomainRedirector(redirector); err != nil {
+		log.Printf("Error updating redirector status in database: %v", err)
+	}
+
+	go m.WaitForCloudfrontDeployedRoutine(svc, &cloudfront.GetDistributionInput{Id: &redirector.ProviderID}, redirector)
+
+	return nil
+}
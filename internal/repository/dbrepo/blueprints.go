@@ -0,0 +1,51 @@
+package dbrepo
+
+import (
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
+
+// AddBlueprint inserts a new blueprint into the database.
+func (m *sqliteDBRepo) AddBlueprint(blueprint models.Blueprint) (models.Blueprint, error) {
+	query := `INSERT INTO blueprints (name, description, spec, created_by) VALUES (?, ?, ?, ?) RETURNING id`
+	err := m.DB.QueryRow(query, blueprint.Name, blueprint.Description, blueprint.Spec, blueprint.CreatedBy).Scan(&blueprint.ID)
+	return blueprint, err
+}
+
+// GetBlueprint retrieves a blueprint by its ID.
+func (m *sqliteDBRepo) GetBlueprint(id int) (models.Blueprint, error) {
+	var blueprint models.Blueprint
+	query := `SELECT id, name, description, spec, created_by, created_at FROM blueprints WHERE id = ?`
+	err := m.DB.QueryRow(query, id).Scan(&blueprint.ID, &blueprint.Name, &blueprint.Description, &blueprint.Spec, &blueprint.CreatedBy, &blueprint.CreatedAt)
+	return blueprint, err
+}
+
+// ListBlueprints retrieves all blueprints stored in the database.
+func (m *sqliteDBRepo) ListBlueprints() ([]models.Blueprint, error) {
+	rows, err := m.DB.Query(`SELECT id, name, description, spec, created_by, created_at FROM blueprints ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blueprints []models.Blueprint
+	for rows.Next() {
+		var blueprint models.Blueprint
+		if err := rows.Scan(&blueprint.ID, &blueprint.Name, &blueprint.Description, &blueprint.Spec, &blueprint.CreatedBy, &blueprint.CreatedAt); err != nil {
+			return nil, err
+		}
+		blueprints = append(blueprints, blueprint)
+	}
+	return blueprints, rows.Err()
+}
+
+// UpdateBlueprint modifies the name, description and spec of an existing blueprint.
+func (m *sqliteDBRepo) UpdateBlueprint(blueprint models.Blueprint) error {
+	_, err := m.DB.Exec(`UPDATE blueprints SET name = ?, description = ?, spec = ? WHERE id = ?`, blueprint.Name, blueprint.Description, blueprint.Spec, blueprint.ID)
+	return err
+}
+
+// DeleteBlueprint removes a blueprint from the database.
+func (m *sqliteDBRepo) DeleteBlueprint(id int) error {
+	_, err := m.DB.Exec("DELETE FROM blueprints WHERE id = ?", id)
+	return err
+}
@@ -0,0 +1,55 @@
+package dbrepo
+
+import (
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
+
+// AddBulkDeployment inserts a bulk deployment and the servers queued by it into the database.
+func (m *sqliteDBRepo) AddBulkDeployment(deployment models.BulkDeployment) (models.BulkDeployment, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return deployment, err
+	}
+
+	query := `INSERT INTO bulk_deployments (project, created_by) VALUES (?, ?) RETURNING id, created_at`
+	err = tx.QueryRow(query, deployment.Project, deployment.CreatedBy).Scan(&deployment.ID, &deployment.CreatedAt)
+	if err != nil {
+		tx.Rollback()
+		return deployment, err
+	}
+
+	serverQuery := `INSERT INTO bulk_deployments_servers (bulk_deployment_id, server_id, provider, server_name, server_region, server_project) VALUES (?, ?, ?, ?, ?, ?)`
+	for _, server := range deployment.Servers {
+		if _, err := tx.Exec(serverQuery, deployment.ID, server.ID, server.Provider, server.Name, server.Region, server.Project); err != nil {
+			tx.Rollback()
+			return deployment, err
+		}
+	}
+
+	return deployment, tx.Commit()
+}
+
+// GetBulkDeployment retrieves a bulk deployment and the servers it queued by its ID.
+func (m *sqliteDBRepo) GetBulkDeployment(id int) (models.BulkDeployment, error) {
+	var deployment models.BulkDeployment
+	query := `SELECT id, project, created_by, created_at FROM bulk_deployments WHERE id = ?`
+	err := m.DB.QueryRow(query, id).Scan(&deployment.ID, &deployment.Project, &deployment.CreatedBy, &deployment.CreatedAt)
+	if err != nil {
+		return deployment, err
+	}
+
+	rows, err := m.DB.Query(`SELECT server_id, provider, server_name, server_region, server_project FROM bulk_deployments_servers WHERE bulk_deployment_id = ? ORDER BY id`, id)
+	if err != nil {
+		return deployment, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var server models.Server
+		if err := rows.Scan(&server.ID, &server.Provider, &server.Name, &server.Region, &server.Project); err != nil {
+			return deployment, err
+		}
+		deployment.Servers = append(deployment.Servers, server)
+	}
+	return deployment, rows.Err()
+}
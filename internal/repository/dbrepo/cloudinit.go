@@ -0,0 +1,51 @@
+package dbrepo
+
+import (
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
+
+// AddCloudInitTemplate inserts a new cloud-init template into the database.
+func (m *sqliteDBRepo) AddCloudInitTemplate(template models.CloudInitTemplate) (models.CloudInitTemplate, error) {
+	query := `INSERT INTO cloud_init_templates (name, description, content, created_by) VALUES (?, ?, ?, ?) RETURNING id`
+	err := m.DB.QueryRow(query, template.Name, template.Description, template.Content, template.CreatedBy).Scan(&template.ID)
+	return template, err
+}
+
+// GetCloudInitTemplate retrieves a cloud-init template by its ID.
+func (m *sqliteDBRepo) GetCloudInitTemplate(id int) (models.CloudInitTemplate, error) {
+	var template models.CloudInitTemplate
+	query := `SELECT id, name, description, content, created_by, created_at FROM cloud_init_templates WHERE id = ?`
+	err := m.DB.QueryRow(query, id).Scan(&template.ID, &template.Name, &template.Description, &template.Content, &template.CreatedBy, &template.CreatedAt)
+	return template, err
+}
+
+// ListCloudInitTemplates retrieves all cloud-init templates stored in the database.
+func (m *sqliteDBRepo) ListCloudInitTemplates() ([]models.CloudInitTemplate, error) {
+	rows, err := m.DB.Query(`SELECT id, name, description, content, created_by, created_at FROM cloud_init_templates ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []models.CloudInitTemplate
+	for rows.Next() {
+		var template models.CloudInitTemplate
+		if err := rows.Scan(&template.ID, &template.Name, &template.Description, &template.Content, &template.CreatedBy, &template.CreatedAt); err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	return templates, rows.Err()
+}
+
+// UpdateCloudInitTemplate modifies the name, description and content of an existing cloud-init template.
+func (m *sqliteDBRepo) UpdateCloudInitTemplate(template models.CloudInitTemplate) error {
+	_, err := m.DB.Exec(`UPDATE cloud_init_templates SET name = ?, description = ?, content = ? WHERE id = ?`, template.Name, template.Description, template.Content, template.ID)
+	return err
+}
+
+// DeleteCloudInitTemplate removes a cloud-init template from the database.
+func (m *sqliteDBRepo) DeleteCloudInitTemplate(id int) error {
+	_, err := m.DB.Exec("DELETE FROM cloud_init_templates WHERE id = ?", id)
+	return err
+}
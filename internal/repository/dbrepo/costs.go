@@ -0,0 +1,49 @@
+package dbrepo
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
+
+// AddCost starts recording the hourly cost of a resource.
+func (m *sqliteDBRepo) AddCost(cost models.Cost) error {
+	if cost.StartedAt.IsZero() {
+		cost.StartedAt = time.Now()
+	}
+	query := `INSERT INTO costs (resource_type, resource_id, name, provider, project, price_hourly, started_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err := m.DB.Exec(query, cost.ResourceType, cost.ResourceID, cost.Name, cost.Provider, cost.Project, cost.PriceHourly, cost.StartedAt)
+	return err
+}
+
+// EndCost stops recording the cost of a resource once it has been removed.
+func (m *sqliteDBRepo) EndCost(resourceType string, resourceID int) error {
+	_, err := m.DB.Exec("UPDATE costs SET ended_at = ? WHERE resource_type = ? AND resource_id = ? AND ended_at IS NULL", time.Now(), resourceType, resourceID)
+	return err
+}
+
+// ListCosts retrieves the costs of all resources, including removed ones.
+func (m *sqliteDBRepo) ListCosts() ([]models.Cost, error) {
+	var costs []models.Cost
+
+	rows, err := m.DB.Query(`SELECT id, resource_type, resource_id, name, provider, project, price_hourly, started_at, ended_at FROM costs ORDER BY started_at`)
+	if err != nil {
+		return costs, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cost models.Cost
+		var endedAt sql.NullTime
+		if err := rows.Scan(&cost.ID, &cost.ResourceType, &cost.ResourceID, &cost.Name, &cost.Provider, &cost.Project, &cost.PriceHourly, &cost.StartedAt, &endedAt); err != nil {
+			return costs, err
+		}
+		if endedAt.Valid {
+			cost.EndedAt = endedAt.Time
+		}
+		costs = append(costs, cost)
+	}
+
+	return costs, rows.Err()
+}
@@ -0,0 +1,62 @@
+package dbrepo
+
+import (
+	"database/sql"
+
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
+
+// GetServerCredential retrieves the root credentials of a server, sql.ErrNoRows if it has none.
+func (m *sqliteDBRepo) GetServerCredential(serverID int) (models.ServerCredential, error) {
+	var credential models.ServerCredential
+	query := `SELECT server_id, public_key, private_key, password, rotated_at FROM server_credentials WHERE server_id = ?`
+	err := m.DB.QueryRow(query, serverID).Scan(&credential.ServerID, &credential.PublicKey, &credential.PrivateKey, &credential.Password, &credential.RotatedAt)
+	return credential, err
+}
+
+// UpdateServerCredential stores the root credentials of a server, replacing any it had.
+func (m *sqliteDBRepo) UpdateServerCredential(credential models.ServerCredential) error {
+	_, err := m.DB.Exec(`
+        INSERT INTO server_credentials (server_id, public_key, private_key, password, rotated_at) VALUES (?, ?, ?, ?, ?)
+        ON CONFLICT(server_id) DO UPDATE SET public_key = excluded.public_key, private_key = excluded.private_key,
+            password = excluded.password, rotated_at = excluded.rotated_at`,
+		credential.ServerID, credential.PublicKey, credential.PrivateKey, credential.Password, credential.RotatedAt,
+	)
+	return err
+}
+
+// DeleteServerCredential removes the root credentials of a server, for when it trusts the shared root key again.
+func (m *sqliteDBRepo) DeleteServerCredential(serverID int) error {
+	_, err := m.DB.Exec("DELETE FROM server_credentials WHERE server_id = ?", serverID)
+	return err
+}
+
+// ListServerCredentials retrieves every server with when its root credentials were last rotated, servers
+// which have never been rotated have a zero time. The private keys and passwords are not returned.
+func (m *sqliteDBRepo) ListServerCredentials() ([]models.ServerCredential, error) {
+	rows, err := m.DB.Query(`
+        SELECT s.id, s.server_name, s.provider, s.server_project, c.public_key, c.rotated_at
+        FROM servers s
+        LEFT JOIN server_credentials c ON c.server_id = s.id
+        ORDER BY c.rotated_at IS NOT NULL, c.rotated_at, s.server_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var credentials []models.ServerCredential
+	for rows.Next() {
+		var credential models.ServerCredential
+		var publicKey sql.NullString
+		var rotatedAt sql.NullTime
+		if err := rows.Scan(&credential.ServerID, &credential.ServerName, &credential.Provider, &credential.Project, &publicKey, &rotatedAt); err != nil {
+			return nil, err
+		}
+		credential.PublicKey = publicKey.String
+		if rotatedAt.Valid {
+			credential.RotatedAt = rotatedAt.Time
+		}
+		credentials = append(credentials, credential)
+	}
+	return credentials, rows.Err()
+}
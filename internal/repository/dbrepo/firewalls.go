@@ -0,0 +1,163 @@
+package dbrepo
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
+
+// scanFirewallPolicy reads a firewall policy row, decoding its rules from JSON
+func scanFirewallPolicy(row interface{ Scan(...interface{}) error }) (models.FirewallPolicy, error) {
+	var policy models.FirewallPolicy
+	var rules string
+	if err := row.Scan(&policy.ID, &policy.Name, &policy.Project, &policy.ProjectDefault, &rules, &policy.CreatedBy, &policy.CreatedAt); err != nil {
+		return policy, err
+	}
+	if err := json.Unmarshal([]byte(rules), &policy.Rules); err != nil {
+		return policy, err
+	}
+	return policy, nil
+}
+
+// clearProjectDefault unsets the default firewall policy of a project, as a project has at most one
+func clearProjectDefault(tx *sql.Tx, project int) error {
+	_, err := tx.Exec("UPDATE firewall_policies SET project_default = 0 WHERE project = ?", project)
+	return err
+}
+
+// AddFirewallPolicy inserts a new firewall policy into the database.
+func (m *sqliteDBRepo) AddFirewallPolicy(policy models.FirewallPolicy) (models.FirewallPolicy, error) {
+	rules, err := json.Marshal(policy.Rules)
+	if err != nil {
+		return policy, err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return policy, err
+	}
+
+	if policy.ProjectDefault {
+		if err := clearProjectDefault(tx, policy.Project); err != nil {
+			tx.Rollback()
+			return policy, err
+		}
+	}
+
+	query := `INSERT INTO firewall_policies (name, project, project_default, rules, created_by) VALUES (?, ?, ?, ?, ?) RETURNING id`
+	if err := tx.QueryRow(query, policy.Name, policy.Project, policy.ProjectDefault, string(rules), policy.CreatedBy).Scan(&policy.ID); err != nil {
+		tx.Rollback()
+		return policy, err
+	}
+
+	return policy, tx.Commit()
+}
+
+// GetFirewallPolicy retrieves a firewall policy by its ID.
+func (m *sqliteDBRepo) GetFirewallPolicy(id int) (models.FirewallPolicy, error) {
+	query := `SELECT id, name, project, project_default, rules, created_by, created_at FROM firewall_policies WHERE id = ?`
+	return scanFirewallPolicy(m.DB.QueryRow(query, id))
+}
+
+// GetFirewallPoliciesForProject retrieves the firewall policies of a project along with those shared by every project.
+func (m *sqliteDBRepo) GetFirewallPoliciesForProject(project int) ([]models.FirewallPolicy, error) {
+	query := `SELECT id, name, project, project_default, rules, created_by, created_at FROM firewall_policies WHERE project = ? OR project = 0 ORDER BY name`
+	rows, err := m.DB.Query(query, project)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var policies []models.FirewallPolicy
+	for rows.Next() {
+		policy, err := scanFirewallPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	return policies, rows.Err()
+}
+
+// GetProjectDefaultFirewallPolicy retrieves the firewall policy applied to new servers in a project,
+// returning sql.ErrNoRows if the project has no default.
+func (m *sqliteDBRepo) GetProjectDefaultFirewallPolicy(project int) (models.FirewallPolicy, error) {
+	query := `SELECT id, name, project, project_default, rules, created_by, created_at FROM firewall_policies WHERE project = ? AND project_default = 1`
+	return scanFirewallPolicy(m.DB.QueryRow(query, project))
+}
+
+// UpdateFirewallPolicy updates the name, rules and project default of a firewall policy.
+func (m *sqliteDBRepo) UpdateFirewallPolicy(policy models.FirewallPolicy) error {
+	rules, err := json.Marshal(policy.Rules)
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	if policy.ProjectDefault {
+		if err := clearProjectDefault(tx, policy.Project); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	_, err = tx.Exec("UPDATE firewall_policies SET name = ?, project_default = ?, rules = ? WHERE id = ?",
+		policy.Name, policy.ProjectDefault, string(rules), policy.ID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteFirewallPolicy removes a firewall policy and unassigns it from any servers using it.
+func (m *sqliteDBRepo) DeleteFirewallPolicy(id int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE servers SET server_firewall = 0 WHERE server_firewall = ?", id); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM firewall_policies WHERE id = ?", id); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateServerFirewall sets the firewall policy applied to a server, 0 for none.
+func (m *sqliteDBRepo) UpdateServerFirewall(serverID, policyID int) error {
+	_, err := m.DB.Exec("UPDATE servers SET server_firewall = ? WHERE id = ?", policyID, serverID)
+	return err
+}
+
+// ListServersWithFirewall fetches the servers a firewall policy is applied to.
+func (m *sqliteDBRepo) ListServersWithFirewall(policyID int) ([]models.Server, error) {
+	query := `SELECT id, provider, server_name, provider_id, server_status, server_ip, server_os, server_region, server_size, server_image, server_project, server_firewall FROM servers WHERE server_firewall = ?`
+	rows, err := m.DB.Query(query, policyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var servers []models.Server
+	for rows.Next() {
+		var server models.Server
+		if err := rows.Scan(&server.ID, &server.Provider, &server.Name, &server.ProviderID, &server.Status, &server.IP, &server.OS, &server.Region, &server.Size, &server.Image, &server.Project, &server.Firewall); err != nil {
+			return nil, err
+		}
+		servers = append(servers, server)
+	}
+	return servers, rows.Err()
+}
@@ -20,8 +20,8 @@ func (m *sqliteDBRepo) AddProject(project models.Project, assignTo []string) err
 
 	// Use RETURNING id with QueryRow to get the newly inserted project ID.
 	var projectID int
-	insertProject := `INSERT INTO projects (project_number, project_name, created_by, notes) VALUES (?, ?, ?, ?) RETURNING id`
-	err = m.DB.QueryRow(insertProject, project.ProjectNumber, project.ProjectName, project.CreatedBy, project.Notes).Scan(&projectID)
+	insertProject := `INSERT INTO projects (project_number, project_name, created_by, notes, server_ttl) VALUES (?, ?, ?, ?, ?) RETURNING id`
+	err = m.DB.QueryRow(insertProject, project.ProjectNumber, project.ProjectName, project.CreatedBy, project.Notes, project.ServerTTL).Scan(&projectID)
 	if err != nil {
 		return err
 	}
@@ -102,7 +102,7 @@ func (m *sqliteDBRepo) DeleteProjectByNumber(number int) error {
 // UpdateProject modifies an existing project's details and reassigns its associated users.
 func (m *sqliteDBRepo) UpdateProject(project models.Project, assignTo []string) error {
 	// Update project details
-	_, err := m.DB.Exec(`UPDATE projects SET project_name = ?, notes = ? WHERE id = ?`, project.ProjectName, project.Notes, project.ID)
+	_, err := m.DB.Exec(`UPDATE projects SET project_name = ?, notes = ?, server_ttl = ? WHERE id = ?`, project.ProjectName, project.Notes, project.ServerTTL, project.ID)
 	if err != nil {
 		log.Println("Update project error:", err)
 		return err
@@ -133,16 +133,22 @@ func (m *sqliteDBRepo) UpdateProject(project models.Project, assignTo []string)
 	return nil
 }
 
+// UpdateProjectInfrastructure saves the declared infrastructure of a project.
+func (m *sqliteDBRepo) UpdateProjectInfrastructure(number int, infrastructure string) error {
+	_, err := m.DB.Exec(`UPDATE projects SET infrastructure = ? WHERE project_number = ?`, infrastructure, number)
+	return err
+}
+
 // GetProjectByNumber fetches a project and its assigned users by project number.
 func (m *sqliteDBRepo) GetProjectByNumber(number int) (models.Project, error) {
 	var project models.Project
 
 	// Query project details
 	err := m.DB.QueryRow(`
-        SELECT id, project_number, project_name, created_by, notes
+        SELECT id, project_number, project_name, created_by, notes, server_ttl, infrastructure
         FROM projects
         WHERE project_number = ?
-    `, number).Scan(&project.ID, &project.ProjectNumber, &project.ProjectName, &project.CreatedBy, &project.Notes)
+    `, number).Scan(&project.ID, &project.ProjectNumber, &project.ProjectName, &project.CreatedBy, &project.Notes, &project.ServerTTL, &project.Infrastructure)
 	if err != nil {
 		return project, err
 	}
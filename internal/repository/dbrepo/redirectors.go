@@ -51,6 +51,12 @@ func (m *sqliteDBRepo) UpdateDomainRedirector(redirector models.Redirector) erro
 	return err // Directly return the result of Exec, simplifying error handling
 }
 
+// UpdateRedirectorOrigin sets the origin domain a redirector forwards traffic to.
+func (m *sqliteDBRepo) UpdateRedirectorOrigin(id int, origin string) error {
+	_, err := m.DB.Exec("UPDATE redirectors SET domain = ? WHERE id = ?", origin, id)
+	return err
+}
+
 // GetAllDomainRedirectors retrieves all domain redirectors stored in the database.
 func (m *sqliteDBRepo) GetAllDomainRedirectors() ([]models.Redirector, error) {
 	var redirectors []models.Redirector
@@ -1,7 +1,9 @@
 package dbrepo
 
 import (
+	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/nickzer0/GoBoxer/internal/models"
 )
@@ -13,9 +15,9 @@ func (m *sqliteDBRepo) AddServerToDatabase(server models.Server) (models.Server,
 		return server, err
 	}
 
-	query := `INSERT INTO servers (provider, server_name, provider_id, server_status, server_ip, server_os, server_project, created_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`
+	query := `INSERT INTO servers (provider, server_name, provider_id, server_status, server_ip, server_os, server_region, server_size, server_image, server_firewall, server_cloud_init, server_expires_at, server_project, created_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`
 	var serverID int
-	err = tx.QueryRow(query, server.Provider, server.Name, server.ProviderID, server.Status, server.IP, server.OS, server.Project, server.Creator).Scan(&serverID)
+	err = tx.QueryRow(query, server.Provider, server.Name, server.ProviderID, server.Status, server.IP, server.OS, server.Region, server.Size, server.Image, server.Firewall, server.CloudInit, nullTime(server.ExpiresAt), server.Project, server.Creator).Scan(&serverID)
 	if err != nil {
 		tx.Rollback()
 		return server, err
@@ -49,12 +51,16 @@ func (m *sqliteDBRepo) AddServerToDatabase(server models.Server) (models.Server,
 // GetServer retrieves a server by its ID from the database, including the scripts assigned to it.
 func (m *sqliteDBRepo) GetServer(id int) (models.Server, error) {
 	var server models.Server
-	query := `SELECT provider, server_name, provider_id, server_status, server_ip, server_os, server_project, created_by, created_at FROM servers WHERE id = ?`
-	err := m.DB.QueryRow(query, id).Scan(&server.Provider, &server.Name, &server.ProviderID, &server.Status, &server.IP, &server.OS, &server.Project, &server.Creator, &server.CreatedAt)
+	var expiresAt sql.NullTime
+	query := `SELECT provider, server_name, provider_id, server_status, server_ip, server_os, server_region, server_size, server_image, server_firewall, server_cloud_init, server_host_key, server_expires_at, server_project, created_by, created_at FROM servers WHERE id = ?`
+	err := m.DB.QueryRow(query, id).Scan(&server.Provider, &server.Name, &server.ProviderID, &server.Status, &server.IP, &server.OS, &server.Region, &server.Size, &server.Image, &server.Firewall, &server.CloudInit, &server.HostKey, &expiresAt, &server.Project, &server.Creator, &server.CreatedAt)
 	if err != nil {
 		return server, err
 	}
 	server.ID = id
+	if expiresAt.Valid {
+		server.ExpiresAt = expiresAt.Time
+	}
 
 	scripts, err := m.GetScriptsForServer(id)
 	if err != nil {
@@ -66,7 +72,7 @@ func (m *sqliteDBRepo) GetServer(id int) (models.Server, error) {
 
 // ListAllServers retrieves all servers stored in the database.
 func (m *sqliteDBRepo) ListAllServers() ([]models.Server, error) {
-	query := "SELECT id, provider, server_name, provider_id, server_status, server_ip, server_os, server_project FROM servers"
+	query := "SELECT id, provider, server_name, provider_id, server_status, server_ip, server_os, server_region, server_size, server_image, server_project FROM servers"
 	rows, err := m.DB.Query(query)
 	if err != nil {
 		return nil, err
@@ -76,7 +82,7 @@ func (m *sqliteDBRepo) ListAllServers() ([]models.Server, error) {
 	var servers []models.Server
 	for rows.Next() {
 		var server models.Server
-		if err := rows.Scan(&server.ID, &server.Provider, &server.Name, &server.ProviderID, &server.Status, &server.IP, &server.OS, &server.Project); err != nil {
+		if err := rows.Scan(&server.ID, &server.Provider, &server.Name, &server.ProviderID, &server.Status, &server.IP, &server.OS, &server.Region, &server.Size, &server.Image, &server.Project); err != nil {
 			return nil, err
 		}
 		servers = append(servers, server)
@@ -97,7 +103,7 @@ func (m *sqliteDBRepo) ListAllServersForUser(user string) ([]models.Server, erro
 
 	query := `
 		SELECT
-			id, provider, server_name, provider_id, server_status, server_ip, server_os, server_project
+			id, provider, server_name, provider_id, server_status, server_ip, server_os, server_region, server_size, server_image, server_project
 		FROM
 			servers
 		WHERE
@@ -120,6 +126,9 @@ func (m *sqliteDBRepo) ListAllServersForUser(user string) ([]models.Server, erro
 				&server.Status,
 				&server.IP,
 				&server.OS,
+				&server.Region,
+				&server.Size,
+				&server.Image,
 				&server.Project,
 			)
 			if err != nil {
@@ -153,7 +162,7 @@ func (m *sqliteDBRepo) getProjectIDsForUser(username string) ([]int, error) {
 func (m *sqliteDBRepo) ListAllServersForProject(projectNumber string) ([]models.Server, error) {
 	query := `
     SELECT
-        id, provider, server_name, provider_id, server_status, server_ip, server_os, server_project
+        id, provider, server_name, provider_id, server_status, server_ip, server_os, server_region, server_size, server_image, server_firewall, server_project
     FROM
         servers
     WHERE
@@ -168,7 +177,7 @@ func (m *sqliteDBRepo) ListAllServersForProject(projectNumber string) ([]models.
 	var servers []models.Server
 	for rows.Next() {
 		var server models.Server
-		if err := rows.Scan(&server.ID, &server.Provider, &server.Name, &server.ProviderID, &server.Status, &server.IP, &server.OS, &server.Project); err != nil {
+		if err := rows.Scan(&server.ID, &server.Provider, &server.Name, &server.ProviderID, &server.Status, &server.IP, &server.OS, &server.Region, &server.Size, &server.Image, &server.Firewall, &server.Project); err != nil {
 			return nil, err
 		}
 		servers = append(servers, server)
@@ -180,9 +189,9 @@ func (m *sqliteDBRepo) ListAllServersForProject(projectNumber string) ([]models.
 func (m *sqliteDBRepo) UpdateServer(server models.Server) error {
 	_, err := m.DB.Exec(`
         UPDATE servers
-        SET server_ip = ?, server_status = ?, provider_id = ?
+        SET server_ip = ?, server_status = ?, provider_id = ?, server_region = ?, server_size = ?, server_image = ?
         WHERE id = ?`,
-		server.IP, server.Status, server.ProviderID, server.ID,
+		server.IP, server.Status, server.ProviderID, server.Region, server.Size, server.Image, server.ID,
 	)
 	if err != nil {
 		return err
@@ -200,6 +209,58 @@ func (m *sqliteDBRepo) UpdateServer(server models.Server) error {
 	return nil
 }
 
+// UpdateServerStatus sets only the status of a server, leaving other details untouched.
+func (m *sqliteDBRepo) UpdateServerStatus(serverID int, status string) error {
+	_, err := m.DB.Exec("UPDATE servers SET server_status = ? WHERE id = ?", status, serverID)
+	return err
+}
+
+// UpdateServerOS sets the operating system recorded for a server, used when it is rebuilt with a new image.
+func (m *sqliteDBRepo) UpdateServerOS(serverID int, os string) error {
+	_, err := m.DB.Exec("UPDATE servers SET server_os = ? WHERE id = ?", os, serverID)
+	return err
+}
+
+// UpdateServerExpiry sets when a server is torn down, a zero time means it never expires.
+func (m *sqliteDBRepo) UpdateServerExpiry(serverID int, expiresAt time.Time) error {
+	_, err := m.DB.Exec("UPDATE servers SET server_expires_at = ? WHERE id = ?", nullTime(expiresAt), serverID)
+	return err
+}
+
+// UpdateServerHostKey pins the SSH host keys of a server, an empty key is pinned again on next contact.
+func (m *sqliteDBRepo) UpdateServerHostKey(serverID int, hostKey string) error {
+	_, err := m.DB.Exec("UPDATE servers SET server_host_key = ? WHERE id = ?", hostKey, serverID)
+	return err
+}
+
+// ListExpiringServers retrieves all servers that have an expiry set, soonest first.
+func (m *sqliteDBRepo) ListExpiringServers() ([]models.Server, error) {
+	query := `SELECT id, provider, server_name, provider_id, server_status, server_ip, server_os, server_project, created_by, server_expires_at FROM servers WHERE server_expires_at IS NOT NULL ORDER BY server_expires_at`
+	rows, err := m.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var servers []models.Server
+	for rows.Next() {
+		var server models.Server
+		if err := rows.Scan(&server.ID, &server.Provider, &server.Name, &server.ProviderID, &server.Status, &server.IP, &server.OS, &server.Project, &server.Creator, &server.ExpiresAt); err != nil {
+			return nil, err
+		}
+		servers = append(servers, server)
+	}
+	return servers, rows.Err()
+}
+
+// nullTime stores a zero time as NULL
+func nullTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
 // getScriptIDByName returns the ID of a script given its name.
 func (m *sqliteDBRepo) getScriptIDByName(scriptName string) (int, error) {
 	var scriptID int
@@ -220,23 +281,37 @@ func (m *sqliteDBRepo) DeleteServerFromDatabase(serverID int) error {
 // GetServiceDetails is used to return the number of services for each provider
 // This data is used on the homepage/dashboard to populate the badge fields
 func (m *sqliteDBRepo) GetServiceDetails() (models.Services, error) {
-	var services models.Services
-	query := `
-	select 
-		(select count(id) from servers where provider = 'digitalocean') as digitalocean,
-		(select count(id) from servers where provider = 'linode') as linode,
-		(select count(id) from redirectors where provider = 'AWS') as aws
-	`
-
-	row := m.DB.QueryRow(query)
-	err := row.Scan(
-		&services.DigitalOcean,
-		&services.Linode,
-		&services.AWS,
-	)
+	services := models.Services{
+		Servers: make(map[string]int),
+	}
+
+	rows, err := m.DB.Query("SELECT provider, count(id) FROM servers GROUP BY provider")
 	if err != nil {
 		return services, err
 	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var provider string
+		var count int
+		if err := rows.Scan(&provider, &count); err != nil {
+			return services, err
+		}
+		services.Servers[provider] = count
+	}
+	if err := rows.Err(); err != nil {
+		return services, err
+	}
+
+	services.DigitalOcean = services.Servers["digitalocean"]
+	services.Linode = services.Servers["linode"]
+	services.Azure = services.Servers["azure"]
+
+	query := "SELECT count(id) FROM redirectors WHERE provider = 'AWS'"
+	if err := m.DB.QueryRow(query).Scan(&services.AWS); err != nil {
+		return services, err
+	}
+	services.AWS += services.Servers["aws"]
 
 	return services, nil
 }
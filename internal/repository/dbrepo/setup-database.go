@@ -1,6 +1,8 @@
 package dbrepo
 
 import (
+	"fmt"
+
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -52,6 +54,8 @@ func (m *sqliteDBRepo) SetupDatabase() error {
 		project_name	TEXT,
 		created_by		TEXT,
 		notes			TEXT,
+		server_ttl		INT NOT NULL DEFAULT 0,
+		infrastructure	TEXT NOT NULL DEFAULT '',
 		created_at		timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
 	)`
 
@@ -60,6 +64,14 @@ func (m *sqliteDBRepo) SetupDatabase() error {
 		return err
 	}
 
+	if err = m.addColumnIfMissing("projects", "server_ttl", "INT NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	if err = m.addColumnIfMissing("projects", "infrastructure", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
 	createTableSessions := `CREATE TABLE IF NOT EXISTS sessions (
 		token TEXT PRIMARY KEY,
 		data BLOB NOT NULL,
@@ -96,9 +108,16 @@ func (m *sqliteDBRepo) SetupDatabase() error {
 		provider		TEXT,
 		server_os		TEXT,
 		server_name		TEXT,
-		provider_id		INT,
+		provider_id		TEXT,
 		server_status	TEXT,
 		server_ip		TEXT,
+		server_region	TEXT NOT NULL DEFAULT '',
+		server_size		TEXT NOT NULL DEFAULT '',
+		server_image	TEXT NOT NULL DEFAULT '',
+		server_firewall	INTEGER NOT NULL DEFAULT 0,
+		server_cloud_init	INTEGER NOT NULL DEFAULT 0,
+		server_host_key	TEXT NOT NULL DEFAULT '',
+		server_expires_at	timestamp,
 		server_project	INT,
 		created_by		TEXT,
 		created_at		timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
@@ -110,6 +129,40 @@ func (m *sqliteDBRepo) SetupDatabase() error {
 		return err
 	}
 
+	// Columns added after the first release need adding to existing databases
+	for _, column := range []string{"server_region", "server_size", "server_image"} {
+		if err = m.addColumnIfMissing("servers", column, "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+	}
+	if err = m.addColumnIfMissing("servers", "server_firewall", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err = m.addColumnIfMissing("servers", "server_expires_at", "timestamp"); err != nil {
+		return err
+	}
+	if err = m.addColumnIfMissing("servers", "server_cloud_init", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err = m.addColumnIfMissing("servers", "server_host_key", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	createTableFirewallPolicies := `CREATE TABLE IF NOT EXISTS firewall_policies (
+		id 				INTEGER PRIMARY KEY AUTOINCREMENT,
+		name			TEXT,
+		project			INT NOT NULL DEFAULT 0,
+		project_default	INT NOT NULL DEFAULT 0,
+		rules			TEXT NOT NULL DEFAULT '[]',
+		created_by		TEXT,
+		created_at		timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+
+	_, err = m.DB.Exec(createTableFirewallPolicies)
+	if err != nil {
+		return err
+	}
+
 	createTableScripts := `CREATE TABLE IF NOT EXISTS scripts (
 		id 			INTEGER PRIMARY KEY AUTOINCREMENT,
 		name		TEXT,
@@ -187,6 +240,121 @@ func (m *sqliteDBRepo) SetupDatabase() error {
 		return err
 	}
 
+	createTableBulkDeployments := `CREATE TABLE IF NOT EXISTS bulk_deployments (
+		id 				INTEGER PRIMARY KEY AUTOINCREMENT,
+		project			INT,
+		created_by		TEXT,
+		created_at		timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+
+	_, err = m.DB.Exec(createTableBulkDeployments)
+	if err != nil {
+		return err
+	}
+
+	// Servers are copied rather than referenced, so those that failed and were removed are still listed
+	createTableBulkDeploymentsServers := `CREATE TABLE IF NOT EXISTS bulk_deployments_servers (
+		id 					INTEGER PRIMARY KEY AUTOINCREMENT,
+		bulk_deployment_id	INTEGER,
+		server_id			INTEGER,
+		provider			TEXT,
+		server_name			TEXT,
+		server_region		TEXT NOT NULL DEFAULT '',
+		server_project		INT,
+		FOREIGN KEY (bulk_deployment_id) REFERENCES bulk_deployments (id) ON DELETE CASCADE
+	)`
+
+	_, err = m.DB.Exec(createTableBulkDeploymentsServers)
+	if err != nil {
+		return err
+	}
+
+	createTableBlueprints := `CREATE TABLE IF NOT EXISTS blueprints (
+		id 				INTEGER PRIMARY KEY AUTOINCREMENT,
+		name			TEXT,
+		description		TEXT NOT NULL DEFAULT '',
+		spec			TEXT NOT NULL DEFAULT '',
+		created_by		TEXT,
+		created_at		timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+
+	_, err = m.DB.Exec(createTableBlueprints)
+	if err != nil {
+		return err
+	}
+
+	createTableServerCredentials := `CREATE TABLE IF NOT EXISTS server_credentials (
+		server_id		INTEGER PRIMARY KEY,
+		public_key		TEXT NOT NULL DEFAULT '',
+		private_key		TEXT NOT NULL DEFAULT '',
+		password		TEXT NOT NULL DEFAULT '',
+		rotated_at		timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (server_id) REFERENCES servers (id) ON DELETE CASCADE
+	)`
+
+	_, err = m.DB.Exec(createTableServerCredentials)
+	if err != nil {
+		return err
+	}
+
+	createTableCloudInitTemplates := `CREATE TABLE IF NOT EXISTS cloud_init_templates (
+		id 				INTEGER PRIMARY KEY AUTOINCREMENT,
+		name			TEXT,
+		description		TEXT NOT NULL DEFAULT '',
+		content			TEXT NOT NULL DEFAULT '',
+		created_by		TEXT,
+		created_at		timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+
+	_, err = m.DB.Exec(createTableCloudInitTemplates)
+	if err != nil {
+		return err
+	}
+
+	createTableCosts := `CREATE TABLE IF NOT EXISTS costs (
+		id 				INTEGER PRIMARY KEY AUTOINCREMENT,
+		resource_type	TEXT,
+		resource_id		INTEGER,
+		name			TEXT,
+		provider		TEXT,
+		project			INT NOT NULL DEFAULT 0,
+		price_hourly	REAL NOT NULL DEFAULT 0,
+		started_at		timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		ended_at		timestamp
+	)`
+
+	_, err = m.DB.Exec(createTableCosts)
+	if err != nil {
+		return err
+	}
+
 	return nil
 
 }
+
+// addColumnIfMissing adds a column to a table created by an older version of the application
+func (m *sqliteDBRepo) addColumnIfMissing(table, column, definition string) error {
+	rows, err := m.DB.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, columnType string
+		var defaultValue interface{}
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = m.DB.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
@@ -1,6 +1,10 @@
 package repository
 
-import "github.com/nickzer0/GoBoxer/internal/models"
+import (
+	"time"
+
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
 
 type DatabaseRepo interface {
 	//Secrets
@@ -16,6 +20,7 @@ type DatabaseRepo interface {
 	DeleteProjectByNumber(number int) error
 	GetProjectByNumber(number int) (models.Project, error)
 	UpdateProject(project models.Project, assignTo []string) error
+	UpdateProjectInfrastructure(number int, infrastructure string) error
 	GetAllProjects() ([]models.Project, error)
 
 	// Users
@@ -33,10 +38,52 @@ type DatabaseRepo interface {
 	ListAllServers() ([]models.Server, error)
 	ListAllServersForUser(user string) ([]models.Server, error)
 	UpdateServer(server models.Server) error
+	UpdateServerStatus(serverID int, status string) error
+	UpdateServerOS(serverID int, os string) error
+	UpdateServerExpiry(serverID int, expiresAt time.Time) error
+	UpdateServerHostKey(serverID int, hostKey string) error
+	GetServerCredential(serverID int) (models.ServerCredential, error)
+	UpdateServerCredential(credential models.ServerCredential) error
+	DeleteServerCredential(serverID int) error
+	ListServerCredentials() ([]models.ServerCredential, error)
+	ListExpiringServers() ([]models.Server, error)
 	DeleteServerFromDatabase(serverID int) error
 	ListAllServersForProject(projectName string) ([]models.Server, error)
 	GetServiceDetails() (models.Services, error)
 
+	// Firewalls
+	AddFirewallPolicy(policy models.FirewallPolicy) (models.FirewallPolicy, error)
+	GetFirewallPolicy(id int) (models.FirewallPolicy, error)
+	GetFirewallPoliciesForProject(project int) ([]models.FirewallPolicy, error)
+	GetProjectDefaultFirewallPolicy(project int) (models.FirewallPolicy, error)
+	UpdateFirewallPolicy(policy models.FirewallPolicy) error
+	DeleteFirewallPolicy(id int) error
+	UpdateServerFirewall(serverID, policyID int) error
+	ListServersWithFirewall(policyID int) ([]models.Server, error)
+
+	// Costs
+	AddCost(cost models.Cost) error
+	EndCost(resourceType string, resourceID int) error
+	ListCosts() ([]models.Cost, error)
+
+	// Bulk deployments
+	AddBulkDeployment(deployment models.BulkDeployment) (models.BulkDeployment, error)
+	GetBulkDeployment(id int) (models.BulkDeployment, error)
+
+	// Blueprints
+	AddBlueprint(blueprint models.Blueprint) (models.Blueprint, error)
+	GetBlueprint(id int) (models.Blueprint, error)
+	ListBlueprints() ([]models.Blueprint, error)
+	UpdateBlueprint(blueprint models.Blueprint) error
+	DeleteBlueprint(id int) error
+
+	// Cloud-init
+	AddCloudInitTemplate(template models.CloudInitTemplate) (models.CloudInitTemplate, error)
+	GetCloudInitTemplate(id int) (models.CloudInitTemplate, error)
+	ListCloudInitTemplates() ([]models.CloudInitTemplate, error)
+	UpdateCloudInitTemplate(template models.CloudInitTemplate) error
+	DeleteCloudInitTemplate(id int) error
+
 	// Scripts
 	AddScript(script models.Script) (models.Script, error)
 	RemoveScript(script string) error
@@ -67,6 +114,7 @@ type DatabaseRepo interface {
 	RemoveDomainRedirector(redirector models.Redirector) error
 	GetDomainRedirector(id int) (models.Redirector, error)
 	UpdateDomainRedirector(redirector models.Redirector) error
+	UpdateRedirectorOrigin(id int, origin string) error
 	GetAllDomainRedirectors() ([]models.Redirector, error)
 
 	// Init
@@ -0,0 +1,973 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/nickzer0/GoBoxer/internal/cloudinit"
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
+
+func init() {
+	RegisterProvider(&awsEC2{})
+}
+
+// awsEC2 implements CloudProvider for AWS EC2 instances, reusing the
+// credentials already stored for Route53 and Cloudfront
+type awsEC2 struct{}
+
+func (p *awsEC2) Name() string        { return "aws" }
+func (p *awsEC2) DisplayName() string { return "AWS" }
+
+func (p *awsEC2) Secrets() []Secret {
+	return []Secret{
+		{Name: "awsaccount", Label: "Access Key ID"},
+		{Name: "awssecret", Label: "Secret Access Key"},
+	}
+}
+
+func (p *awsEC2) Capabilities() Capabilities {
+	return Capabilities{UserData: true}
+}
+
+func (p *awsEC2) Catalog() (Catalog, error) {
+	return Repo.AWSCatalog()
+}
+
+func (p *awsEC2) CreateServer(server models.Server) (models.Server, error) {
+	return Repo.AWSCreateServer(server)
+}
+
+func (p *awsEC2) GetServer(server models.Server) (models.Server, error) {
+	return Repo.AWSGetServer(server)
+}
+
+func (p *awsEC2) ListServers() ([]models.Server, error) {
+	return Repo.AWSRefreshVPS()
+}
+
+func (p *awsEC2) DeleteServer(server models.Server) error {
+	return Repo.AWSDeleteServer(server)
+}
+
+func (p *awsEC2) DeleteAll() error {
+	return Repo.AWSDeleteAll()
+}
+
+func (p *awsEC2) Power(server models.Server, action PowerAction) error {
+	return ErrNotSupported
+}
+
+func (p *awsEC2) ListAllServers() ([]models.Server, error) {
+	return Repo.AWSListAll()
+}
+
+func (p *awsEC2) TagServer(server models.Server) error {
+	return Repo.AWSTagServer(server)
+}
+
+func (p *awsEC2) RegisterSSHKey() error {
+	return Repo.AddSSHKeyToAWS()
+}
+
+func (p *awsEC2) ListIPs(region string) ([]ReservedIP, error) {
+	return Repo.AWSListIPs(region)
+}
+
+func (p *awsEC2) AllocateIP(server models.Server) (ReservedIP, error) {
+	return Repo.AWSAllocateIP(server)
+}
+
+func (p *awsEC2) AttachIP(server models.Server, ip ReservedIP) error {
+	return Repo.AWSAttachIP(server.ProviderID, ip)
+}
+
+func (p *awsEC2) DetachIP(ip ReservedIP) error {
+	return Repo.AWSDetachIP(ip)
+}
+
+func (p *awsEC2) ReleaseIP(ip ReservedIP) error {
+	return Repo.AWSReleaseIP(ip)
+}
+
+func (p *awsEC2) ApplyFirewall(server models.Server, rules []models.FirewallRule) error {
+	return Repo.AWSApplyFirewall(server, rules)
+}
+
+func (p *awsEC2) RemoveFirewall(server models.Server) error {
+	return Repo.AWSRemoveFirewall(server)
+}
+
+const (
+	// awsKeyPairName is the name the root SSH key is imported under in each region
+	awsKeyPairName = "goboxer-root"
+	// awsSecurityGroupName is the security group created in each region for servers without a firewall
+	// policy, it only allows SSH
+	awsSecurityGroupName = "goboxer-ssh"
+	// awsDefaultRegion is used for API calls that are not tied to a server
+	awsDefaultRegion = "us-east-1"
+)
+
+// awsImage describes how to find the latest AMI for a distribution in any region
+type awsImage struct {
+	Name  string
+	Owner string
+	Match string
+}
+
+// awsImages are the distributions offered for EC2, keyed by the slug stored on the server
+var awsImages = map[string]awsImage{
+	"ubuntu-22.04": {Name: "Ubuntu 22.04", Owner: "099720109477", Match: "ubuntu/images/hvm-ssd/ubuntu-jammy-22.04-amd64-server-*"},
+	"ubuntu-24.04": {Name: "Ubuntu 24.04", Owner: "099720109477", Match: "ubuntu/images/hvm-ssd-gp3/ubuntu-noble-24.04-amd64-server-*"},
+	"debian-12":    {Name: "Debian 12", Owner: "136693071363", Match: "debian-12-amd64-*"},
+}
+
+// awsInstanceTypes are the instance types offered for EC2 with their us-east-1 on-demand
+// hourly price, as the pricing API is not available in every region
+var awsInstanceTypes = []CatalogItem{
+	{Slug: "t3.micro", Name: "t3.micro - 2 vCPU, 1 GB", PriceHourly: 0.0104},
+	{Slug: "t3.small", Name: "t3.small - 2 vCPU, 2 GB", PriceHourly: 0.0208},
+	{Slug: "t3.medium", Name: "t3.medium - 2 vCPU, 4 GB", PriceHourly: 0.0416},
+	{Slug: "t3.large", Name: "t3.large - 2 vCPU, 8 GB", PriceHourly: 0.0832},
+	{Slug: "t3.xlarge", Name: "t3.xlarge - 4 vCPU, 16 GB", PriceHourly: 0.1664},
+	{Slug: "c5.large", Name: "c5.large - 2 vCPU, 4 GB", PriceHourly: 0.085},
+	{Slug: "c5.xlarge", Name: "c5.xlarge - 4 vCPU, 8 GB", PriceHourly: 0.17},
+}
+
+// awsUserData lets root log in with the imported key pair, which Ansible relies on
+const awsUserData = `#cloud-config
+disable_root: false
+`
+
+// awsClient returns an EC2 client for the given region using the credentials from the secrets table
+func (m *Repository) awsClient(region string) (*ec2.EC2, error) {
+	awsAccount, err := m.DB.GetSecret("awsaccount")
+	if err != nil {
+		return nil, fmt.Errorf("error getting AWS account secret: %v", err)
+	}
+
+	awsSecret, err := m.DB.GetSecret("awssecret")
+	if err != nil {
+		return nil, fmt.Errorf("error getting AWS secret: %v", err)
+	}
+
+	if region == "" {
+		region = awsDefaultRegion
+	}
+
+	sess, err := session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials(awsAccount, awsSecret, ""),
+		Region:      aws.String(region),
+		MaxRetries:  aws.Int(3),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ec2.New(sess), nil
+}
+
+// AWSCreateServer launches an EC2 instance with the root SSH key, the GoBoxer security group
+// and an elastic IP, and returns the server object with the elastic IP address
+func (m *Repository) AWSCreateServer(server models.Server) (models.Server, error) {
+	if server.Region == "" {
+		server.Region = awsDefaultRegion
+	}
+
+	instanceType := server.Size
+	if instanceType == "" {
+		instanceType = "t3.micro"
+	}
+
+	imageSlug := server.Image
+	if imageSlug == "" {
+		imageSlug = "ubuntu-22.04"
+	}
+
+	svc, err := m.awsClient(server.Region)
+	if err != nil {
+		return server, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	imageID, err := awsLatestImage(ctx, svc, imageSlug)
+	if err != nil {
+		return server, err
+	}
+
+	if err := m.awsEnsureKeyPair(ctx, svc); err != nil {
+		return server, err
+	}
+
+	securityGroupID, err := awsEnsureSecurityGroup(ctx, svc)
+	if err != nil {
+		return server, err
+	}
+
+	tags := []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String(server.Name)}}
+	for _, tag := range Tags {
+		tags = append(tags, &ec2.Tag{Key: aws.String(tag), Value: aws.String("")})
+	}
+
+	reservation, err := svc.RunInstancesWithContext(ctx, &ec2.RunInstancesInput{
+		ImageId:          aws.String(imageID),
+		InstanceType:     aws.String(instanceType),
+		MinCount:         aws.Int64(1),
+		MaxCount:         aws.Int64(1),
+		KeyName:          aws.String(awsKeyPairName),
+		SecurityGroupIds: aws.StringSlice([]string{securityGroupID}),
+		UserData:         aws.String(base64.StdEncoding.EncodeToString([]byte(cloudinit.Combine(awsUserData, server.UserData)))),
+		TagSpecifications: []*ec2.TagSpecification{
+			{ResourceType: aws.String(ec2.ResourceTypeInstance), Tags: tags},
+		},
+	})
+	if err != nil {
+		return server, fmt.Errorf("error launching EC2 instance: %v", err)
+	}
+
+	instanceID := aws.StringValue(reservation.Instances[0].InstanceId)
+	server.ProviderID = instanceID
+
+	err = svc.WaitUntilInstanceRunningWithContext(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: aws.StringSlice([]string{instanceID}),
+	})
+	if err != nil {
+		return awsAbandonInstance(svc, server, ""), fmt.Errorf("error waiting for EC2 instance to start: %v", err)
+	}
+
+	address, err := svc.AllocateAddressWithContext(ctx, &ec2.AllocateAddressInput{
+		Domain: aws.String(ec2.DomainTypeVpc),
+		TagSpecifications: []*ec2.TagSpecification{
+			{ResourceType: aws.String(ec2.ResourceTypeElasticIp), Tags: tags},
+		},
+	})
+	if err != nil {
+		return awsAbandonInstance(svc, server, ""), fmt.Errorf("error allocating elastic IP: %v", err)
+	}
+
+	_, err = svc.AssociateAddressWithContext(ctx, &ec2.AssociateAddressInput{
+		AllocationId: address.AllocationId,
+		InstanceId:   aws.String(instanceID),
+	})
+	if err != nil {
+		return awsAbandonInstance(svc, server, aws.StringValue(address.AllocationId)), fmt.Errorf("error associating elastic IP: %v", err)
+	}
+
+	server.IP = aws.StringValue(address.PublicIp)
+	return server, nil
+}
+
+// awsAbandonInstance releases an elastic IP and terminates an instance left behind by a failed create,
+// so neither keeps being billed. The provider ID is only cleared once the instance is terminated, otherwise
+// it is returned so the caller can still find the instance.
+func awsAbandonInstance(svc *ec2.EC2, server models.Server, allocationID string) models.Server {
+	// The create's context may be what expired, so clean up with a fresh one
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if allocationID != "" {
+		if _, err := svc.ReleaseAddressWithContext(ctx, &ec2.ReleaseAddressInput{AllocationId: aws.String(allocationID)}); err != nil {
+			log.Printf("Error releasing elastic IP %s after failed create: %v", allocationID, err)
+		}
+	}
+
+	_, err := svc.TerminateInstancesWithContext(ctx, &ec2.TerminateInstancesInput{
+		InstanceIds: aws.StringSlice([]string{server.ProviderID}),
+	})
+	if err != nil {
+		log.Printf("Error terminating EC2 instance %s after failed create: %v", server.ProviderID, err)
+		return server
+	}
+
+	server.ProviderID = ""
+	return server
+}
+
+// awsLatestImage returns the newest AMI in the client's region for an image slug
+func awsLatestImage(ctx context.Context, svc *ec2.EC2, slug string) (string, error) {
+	image, ok := awsImages[slug]
+	if !ok {
+		return "", fmt.Errorf("unsupported image: %s", slug)
+	}
+
+	output, err := svc.DescribeImagesWithContext(ctx, &ec2.DescribeImagesInput{
+		Owners: aws.StringSlice([]string{image.Owner}),
+		Filters: []*ec2.Filter{
+			{Name: aws.String("name"), Values: aws.StringSlice([]string{image.Match})},
+			{Name: aws.String("state"), Values: aws.StringSlice([]string{"available"})},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("error finding image for %s: %v", slug, err)
+	}
+
+	if len(output.Images) == 0 {
+		return "", fmt.Errorf("no image found for %s", slug)
+	}
+
+	sort.Slice(output.Images, func(i, j int) bool {
+		return aws.StringValue(output.Images[i].CreationDate) > aws.StringValue(output.Images[j].CreationDate)
+	})
+
+	return aws.StringValue(output.Images[0].ImageId), nil
+}
+
+// awsEnsureKeyPair imports the root SSH key into the client's region if it is not already there
+func (m *Repository) awsEnsureKeyPair(ctx context.Context, svc *ec2.EC2) error {
+	output, err := svc.DescribeKeyPairsWithContext(ctx, &ec2.DescribeKeyPairsInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String("key-name"), Values: aws.StringSlice([]string{awsKeyPairName})},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error getting list of key pairs from AWS: %v", err)
+	}
+
+	if len(output.KeyPairs) > 0 {
+		return nil
+	}
+
+	publicKey, err := m.DB.GetSecret("sshkey")
+	if err != nil {
+		return fmt.Errorf("error getting SSH key from database: %v", err)
+	}
+
+	_, err = svc.ImportKeyPairWithContext(ctx, &ec2.ImportKeyPairInput{
+		KeyName:           aws.String(awsKeyPairName),
+		PublicKeyMaterial: []byte(publicKey),
+	})
+	if err != nil {
+		return fmt.Errorf("error adding SSH key to AWS: %v", err)
+	}
+	return nil
+}
+
+// awsEnsureSecurityGroup returns the ID of the GoBoxer security group in the default VPC, creating it
+// if needed. It only allows SSH, which Ansible needs, firewall policies open anything else.
+func awsEnsureSecurityGroup(ctx context.Context, svc *ec2.EC2) (string, error) {
+	output, err := svc.DescribeSecurityGroupsWithContext(ctx, &ec2.DescribeSecurityGroupsInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String("group-name"), Values: aws.StringSlice([]string{awsSecurityGroupName})},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("error getting security groups from AWS: %v", err)
+	}
+
+	if len(output.SecurityGroups) > 0 {
+		return aws.StringValue(output.SecurityGroups[0].GroupId), nil
+	}
+
+	group, err := svc.CreateSecurityGroupWithContext(ctx, &ec2.CreateSecurityGroupInput{
+		GroupName:   aws.String(awsSecurityGroupName),
+		Description: aws.String("GoBoxer managed servers, SSH only"),
+	})
+	if err != nil {
+		return "", fmt.Errorf("error creating security group: %v", err)
+	}
+
+	_, err = svc.AuthorizeSecurityGroupIngressWithContext(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
+		GroupId:       group.GroupId,
+		IpPermissions: awsIPPermissions([]models.FirewallRule{{Protocol: "tcp", Ports: "22", Sources: anywhere}}),
+	})
+	if err != nil {
+		return "", fmt.Errorf("error adding rules to security group: %v", err)
+	}
+
+	return aws.StringValue(group.GroupId), nil
+}
+
+// awsIPPermissions converts firewall rules into security group permissions
+func awsIPPermissions(rules []models.FirewallRule) []*ec2.IpPermission {
+	var permissions []*ec2.IpPermission
+	for _, rule := range rules {
+		permission := &ec2.IpPermission{IpProtocol: aws.String(rule.Protocol)}
+
+		switch {
+		case rule.Protocol == "icmp":
+			permission.FromPort = aws.Int64(-1)
+			permission.ToPort = aws.Int64(-1)
+		case rule.Ports == "":
+			permission.FromPort = aws.Int64(0)
+			permission.ToPort = aws.Int64(65535)
+		default:
+			bounds := strings.SplitN(rule.Ports, "-", 2)
+			from, _ := strconv.ParseInt(bounds[0], 10, 64)
+			to := from
+			if len(bounds) == 2 {
+				to, _ = strconv.ParseInt(bounds[1], 10, 64)
+			}
+			permission.FromPort = aws.Int64(from)
+			permission.ToPort = aws.Int64(to)
+		}
+
+		ipv4, ipv6 := splitSources(rule.Sources)
+		for _, source := range ipv4 {
+			permission.IpRanges = append(permission.IpRanges, &ec2.IpRange{CidrIp: aws.String(source)})
+		}
+		for _, source := range ipv6 {
+			permission.Ipv6Ranges = append(permission.Ipv6Ranges, &ec2.Ipv6Range{CidrIpv6: aws.String(source)})
+		}
+		permissions = append(permissions, permission)
+	}
+	return permissions
+}
+
+// awsFirewallGroup returns the security group holding an instance's firewall policy, or nil if it has none
+func awsFirewallGroup(ctx context.Context, svc *ec2.EC2, server models.Server) (*ec2.SecurityGroup, error) {
+	output, err := svc.DescribeSecurityGroupsWithContext(ctx, &ec2.DescribeSecurityGroupsInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String("group-name"), Values: aws.StringSlice([]string{firewallName(server)})},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting security groups from AWS: %v", err)
+	}
+	if len(output.SecurityGroups) == 0 {
+		return nil, nil
+	}
+	return output.SecurityGroups[0], nil
+}
+
+// AWSApplyFirewall gives an instance a security group of its own holding the rules of its firewall policy,
+// in place of the SSH only group it is created with
+func (m *Repository) AWSApplyFirewall(server models.Server, rules []models.FirewallRule) error {
+	svc, err := m.awsClient(server.Region)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	group, err := awsFirewallGroup(ctx, svc, server)
+	if err != nil {
+		return err
+	}
+
+	var groupID *string
+	if group == nil {
+		instances, err := svc.DescribeInstancesWithContext(ctx, &ec2.DescribeInstancesInput{
+			InstanceIds: aws.StringSlice([]string{server.ProviderID}),
+		})
+		if err != nil || len(instances.Reservations) == 0 || len(instances.Reservations[0].Instances) == 0 {
+			return fmt.Errorf("error getting instance %s from AWS: %v", server.ProviderID, err)
+		}
+
+		created, err := svc.CreateSecurityGroupWithContext(ctx, &ec2.CreateSecurityGroupInput{
+			GroupName:   aws.String(firewallName(server)),
+			Description: aws.String("GoBoxer firewall for " + server.Name),
+			VpcId:       instances.Reservations[0].Instances[0].VpcId,
+		})
+		if err != nil {
+			return fmt.Errorf("error creating security group: %v", err)
+		}
+		groupID = created.GroupId
+	} else {
+		groupID = group.GroupId
+		// Rules are replaced rather than merged, so ones removed from the policy are closed
+		if len(group.IpPermissions) > 0 {
+			_, err := svc.RevokeSecurityGroupIngressWithContext(ctx, &ec2.RevokeSecurityGroupIngressInput{
+				GroupId:       groupID,
+				IpPermissions: group.IpPermissions,
+			})
+			if err != nil {
+				return fmt.Errorf("error removing rules from security group: %v", err)
+			}
+		}
+	}
+
+	if permissions := awsIPPermissions(rules); len(permissions) > 0 {
+		_, err = svc.AuthorizeSecurityGroupIngressWithContext(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
+			GroupId:       groupID,
+			IpPermissions: permissions,
+		})
+		if err != nil {
+			return fmt.Errorf("error adding rules to security group: %v", err)
+		}
+	}
+
+	_, err = svc.ModifyInstanceAttributeWithContext(ctx, &ec2.ModifyInstanceAttributeInput{
+		InstanceId: aws.String(server.ProviderID),
+		Groups:     []*string{groupID},
+	})
+	if err != nil {
+		return fmt.Errorf("error applying security group to instance: %v", err)
+	}
+	return nil
+}
+
+// AWSRemoveFirewall puts an instance back in the SSH only security group and deletes its own group
+func (m *Repository) AWSRemoveFirewall(server models.Server) error {
+	svc, err := m.awsClient(server.Region)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	group, err := awsFirewallGroup(ctx, svc, server)
+	if err != nil || group == nil {
+		return err
+	}
+
+	defaultGroupID, err := awsEnsureSecurityGroup(ctx, svc)
+	if err != nil {
+		return err
+	}
+
+	_, err = svc.ModifyInstanceAttributeWithContext(ctx, &ec2.ModifyInstanceAttributeInput{
+		InstanceId: aws.String(server.ProviderID),
+		Groups:     aws.StringSlice([]string{defaultGroupID}),
+	})
+	if err != nil {
+		return fmt.Errorf("error applying security group to instance: %v", err)
+	}
+
+	if _, err := svc.DeleteSecurityGroupWithContext(ctx, &ec2.DeleteSecurityGroupInput{GroupId: group.GroupId}); err != nil {
+		return fmt.Errorf("error deleting security group: %v", err)
+	}
+	return nil
+}
+
+// awsRegions returns the regions enabled for the AWS account
+func (m *Repository) awsRegions(ctx context.Context) ([]string, error) {
+	svc, err := m.awsClient(awsDefaultRegion)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := svc.DescribeRegionsWithContext(ctx, &ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting list of regions from AWS: %v", err)
+	}
+
+	var regions []string
+	for _, region := range output.Regions {
+		regions = append(regions, aws.StringValue(region.RegionName))
+	}
+	sort.Strings(regions)
+	return regions, nil
+}
+
+// AWSCatalog lists the regions enabled for the account along with the supported instance types and images
+func (m *Repository) AWSCatalog() (Catalog, error) {
+	var catalog Catalog
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	regions, err := m.awsRegions(ctx)
+	if err != nil {
+		return catalog, err
+	}
+	for _, region := range regions {
+		catalog.Regions = append(catalog.Regions, CatalogItem{Slug: region, Name: region})
+	}
+
+	catalog.Sizes = append(catalog.Sizes, awsInstanceTypes...)
+
+	for slug, image := range awsImages {
+		catalog.Images = append(catalog.Images, CatalogItem{Slug: slug, Name: image.Name})
+	}
+	sort.Slice(catalog.Images, func(i, j int) bool {
+		return catalog.Images[i].Slug < catalog.Images[j].Slug
+	})
+
+	return catalog, nil
+}
+
+// awsServerFromInstance converts an EC2 instance into a server
+func awsServerFromInstance(instance *ec2.Instance, region string) models.Server {
+	server := models.Server{
+		Provider:   "aws",
+		ProviderID: aws.StringValue(instance.InstanceId),
+		IP:         aws.StringValue(instance.PublicIpAddress),
+		Region:     region,
+		Size:       aws.StringValue(instance.InstanceType),
+	}
+	if instance.State != nil {
+		server.Status = aws.StringValue(instance.State.Name)
+	}
+	for _, tag := range instance.Tags {
+		if aws.StringValue(tag.Key) == "Name" {
+			server.Name = aws.StringValue(tag.Value)
+		}
+	}
+	return server
+}
+
+// AWSGetServer fetches the current state of an EC2 instance
+func (m *Repository) AWSGetServer(server models.Server) (models.Server, error) {
+	svc, err := m.awsClient(server.Region)
+	if err != nil {
+		return server, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	output, err := svc.DescribeInstancesWithContext(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: aws.StringSlice([]string{server.ProviderID}),
+	})
+	if err != nil {
+		return server, fmt.Errorf("error getting instance from AWS: %v", err)
+	}
+
+	for _, reservation := range output.Reservations {
+		for _, instance := range reservation.Instances {
+			current := awsServerFromInstance(instance, server.Region)
+			if current.IP != "" {
+				server.IP = current.IP
+			}
+			server.Status = current.Status
+			return server, nil
+		}
+	}
+
+	return server, fmt.Errorf("instance %s not found in AWS", server.ProviderID)
+}
+
+// AWSRefreshVPS returns the instances tagged with the application tag across all enabled regions
+func (m *Repository) AWSRefreshVPS() ([]models.Server, error) {
+	return m.awsListInstances(true)
+}
+
+// AWSListAll returns every instance across all enabled regions, tagged or not
+func (m *Repository) AWSListAll() ([]models.Server, error) {
+	return m.awsListInstances(false)
+}
+
+// awsListInstances lists instances in every enabled region, only those with the application tag if tagged is set
+func (m *Repository) awsListInstances(tagged bool) ([]models.Server, error) {
+	servers := []models.Server{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	regions, err := m.awsRegions(ctx)
+	if err != nil {
+		return servers, err
+	}
+
+	for _, region := range regions {
+		svc, err := m.awsClient(region)
+		if err != nil {
+			return servers, err
+		}
+
+		input := &ec2.DescribeInstancesInput{
+			Filters: []*ec2.Filter{
+				{Name: aws.String("instance-state-name"), Values: aws.StringSlice([]string{"pending", "running", "stopping", "stopped"})},
+			},
+		}
+		if tagged {
+			input.Filters = append(input.Filters, &ec2.Filter{Name: aws.String("tag-key"), Values: aws.StringSlice([]string{Tags[0]})})
+		}
+		err = svc.DescribeInstancesPagesWithContext(ctx, input, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+			for _, reservation := range page.Reservations {
+				for _, instance := range reservation.Instances {
+					servers = append(servers, awsServerFromInstance(instance, region))
+				}
+			}
+			return !lastPage
+		})
+		if err != nil {
+			return servers, fmt.Errorf("error getting list of instances from AWS region %s: %v", region, err)
+		}
+	}
+
+	return servers, nil
+}
+
+// AWSTagServer adds the application tag to an existing instance so it is managed by GoBoxer
+func (m *Repository) AWSTagServer(server models.Server) error {
+	svc, err := m.awsClient(server.Region)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	var tags []*ec2.Tag
+	for _, tag := range Tags {
+		tags = append(tags, &ec2.Tag{Key: aws.String(tag), Value: aws.String("")})
+	}
+
+	_, err = svc.CreateTagsWithContext(ctx, &ec2.CreateTagsInput{
+		Resources: aws.StringSlice([]string{server.ProviderID}),
+		Tags:      tags,
+	})
+	if err != nil {
+		return fmt.Errorf("error tagging EC2 instance: %v", err)
+	}
+	return nil
+}
+
+// AWSDeleteServer releases the elastic IP attached to an instance and terminates it
+func (m *Repository) AWSDeleteServer(server models.Server) error {
+	svc, err := m.awsClient(server.Region)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	addresses, err := svc.DescribeAddressesWithContext(ctx, &ec2.DescribeAddressesInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String("instance-id"), Values: aws.StringSlice([]string{server.ProviderID})},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error getting elastic IPs from AWS: %v", err)
+	}
+
+	for _, address := range addresses.Addresses {
+		if _, err := svc.DisassociateAddressWithContext(ctx, &ec2.DisassociateAddressInput{AssociationId: address.AssociationId}); err != nil {
+			return fmt.Errorf("error disassociating elastic IP: %v", err)
+		}
+		if _, err := svc.ReleaseAddressWithContext(ctx, &ec2.ReleaseAddressInput{AllocationId: address.AllocationId}); err != nil {
+			return fmt.Errorf("error releasing elastic IP: %v", err)
+		}
+	}
+
+	firewall, err := awsFirewallGroup(ctx, svc, server)
+	if err != nil {
+		return err
+	}
+
+	_, err = svc.TerminateInstancesWithContext(ctx, &ec2.TerminateInstancesInput{
+		InstanceIds: aws.StringSlice([]string{server.ProviderID}),
+	})
+	// An instance already gone from the account counts as deleted
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == "InvalidInstanceID.NotFound" {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error terminating instance in AWS: %v", err)
+	}
+
+	// The instance's own security group can only be deleted once nothing uses it
+	if firewall != nil {
+		err := svc.WaitUntilInstanceTerminatedWithContext(ctx, &ec2.DescribeInstancesInput{
+			InstanceIds: aws.StringSlice([]string{server.ProviderID}),
+		})
+		if err == nil {
+			_, err = svc.DeleteSecurityGroupWithContext(ctx, &ec2.DeleteSecurityGroupInput{GroupId: firewall.GroupId})
+		}
+		if err != nil {
+			log.Printf("Error deleting security group %s of instance %s: %v", aws.StringValue(firewall.GroupId), server.ProviderID, err)
+		}
+	}
+	return nil
+}
+
+// AWSListIPs lists the elastic IPs with the application tag in a region, or in every enabled region if region is empty
+func (m *Repository) AWSListIPs(region string) ([]ReservedIP, error) {
+	var ips []ReservedIP
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	regions := []string{region}
+	if region == "" {
+		var err error
+		regions, err = m.awsRegions(ctx)
+		if err != nil {
+			return ips, err
+		}
+	}
+
+	for _, region := range regions {
+		svc, err := m.awsClient(region)
+		if err != nil {
+			return ips, err
+		}
+
+		output, err := svc.DescribeAddressesWithContext(ctx, &ec2.DescribeAddressesInput{
+			Filters: []*ec2.Filter{
+				{Name: aws.String("tag-key"), Values: aws.StringSlice([]string{Tags[0]})},
+			},
+		})
+		if err != nil {
+			return ips, fmt.Errorf("error getting elastic IPs from AWS region %s: %v", region, err)
+		}
+
+		for _, address := range output.Addresses {
+			ips = append(ips, ReservedIP{
+				Address:          aws.StringValue(address.PublicIp),
+				ID:               aws.StringValue(address.AllocationId),
+				Region:           region,
+				ServerProviderID: aws.StringValue(address.InstanceId),
+			})
+		}
+	}
+	return ips, nil
+}
+
+// AWSAllocateIP allocates a tagged elastic IP in the server's region and associates it with the instance,
+// replacing any elastic IP already associated with it
+func (m *Repository) AWSAllocateIP(server models.Server) (ReservedIP, error) {
+	svc, err := m.awsClient(server.Region)
+	if err != nil {
+		return ReservedIP{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	tags := []*ec2.Tag{{Key: aws.String("Name"), Value: aws.String(server.Name)}}
+	for _, tag := range Tags {
+		tags = append(tags, &ec2.Tag{Key: aws.String(tag), Value: aws.String("")})
+	}
+
+	address, err := svc.AllocateAddressWithContext(ctx, &ec2.AllocateAddressInput{
+		Domain: aws.String(ec2.DomainTypeVpc),
+		TagSpecifications: []*ec2.TagSpecification{
+			{ResourceType: aws.String(ec2.ResourceTypeElasticIp), Tags: tags},
+		},
+	})
+	if err != nil {
+		return ReservedIP{}, fmt.Errorf("error allocating elastic IP: %v", err)
+	}
+
+	ip := ReservedIP{
+		Address: aws.StringValue(address.PublicIp),
+		ID:      aws.StringValue(address.AllocationId),
+		Region:  server.Region,
+	}
+
+	if err := m.AWSAttachIP(server.ProviderID, ip); err != nil {
+		return ip, err
+	}
+
+	ip.ServerProviderID = server.ProviderID
+	return ip, nil
+}
+
+// AWSAttachIP associates an elastic IP with an instance, moving it from any instance it is associated with
+func (m *Repository) AWSAttachIP(providerID string, ip ReservedIP) error {
+	svc, err := m.awsClient(ip.Region)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	_, err = svc.AssociateAddressWithContext(ctx, &ec2.AssociateAddressInput{
+		AllocationId:       aws.String(ip.ID),
+		InstanceId:         aws.String(providerID),
+		AllowReassociation: aws.Bool(true),
+	})
+	if err != nil {
+		return fmt.Errorf("error associating elastic IP: %v", err)
+	}
+	return nil
+}
+
+// AWSDetachIP disassociates an elastic IP from its instance
+func (m *Repository) AWSDetachIP(ip ReservedIP) error {
+	svc, err := m.awsClient(ip.Region)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	addresses, err := svc.DescribeAddressesWithContext(ctx, &ec2.DescribeAddressesInput{
+		AllocationIds: aws.StringSlice([]string{ip.ID}),
+	})
+	if err != nil {
+		return fmt.Errorf("error getting elastic IP from AWS: %v", err)
+	}
+
+	for _, address := range addresses.Addresses {
+		if address.AssociationId == nil {
+			continue
+		}
+		if _, err := svc.DisassociateAddressWithContext(ctx, &ec2.DisassociateAddressInput{AssociationId: address.AssociationId}); err != nil {
+			return fmt.Errorf("error disassociating elastic IP: %v", err)
+		}
+	}
+	return nil
+}
+
+// AWSReleaseIP releases an elastic IP back to AWS
+func (m *Repository) AWSReleaseIP(ip ReservedIP) error {
+	svc, err := m.awsClient(ip.Region)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	if _, err := svc.ReleaseAddressWithContext(ctx, &ec2.ReleaseAddressInput{AllocationId: aws.String(ip.ID)}); err != nil {
+		return fmt.Errorf("error releasing elastic IP: %v", err)
+	}
+	return nil
+}
+
+// AWSDeleteAll terminates all instances tagged with the application tag in every region
+func (m *Repository) AWSDeleteAll() error {
+	servers, err := m.AWSRefreshVPS()
+	if err != nil {
+		return err
+	}
+
+	for _, vps := range servers {
+		if err := m.AWSDeleteServer(vps); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddSSHKeyToAWS removes the imported root key pair from every region so the
+// current key is imported the next time a server is created there
+func (m *Repository) AddSSHKeyToAWS() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	regions, err := m.awsRegions(ctx)
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, region := range regions {
+		svc, err := m.awsClient(region)
+		if err != nil {
+			return err
+		}
+		if _, err := svc.DeleteKeyPairWithContext(ctx, &ec2.DeleteKeyPairInput{KeyName: aws.String(awsKeyPairName)}); err != nil {
+			log.Printf("Error deleting key pair in AWS region %s: %v", region, err)
+			failed = append(failed, region)
+		}
+	}
+
+	if len(failed) > 0 {
+		return errors.New("failed to remove old key pair from some AWS regions")
+	}
+	return nil
+}
@@ -0,0 +1,730 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nickzer0/GoBoxer/internal/cloudinit"
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
+
+func init() {
+	RegisterProvider(&azure{})
+}
+
+// azure implements CloudProvider for Azure virtual machines. Each project gets its
+// own resource group, which is removed once the last server in it is deleted.
+type azure struct{}
+
+func (p *azure) Name() string        { return "azure" }
+func (p *azure) DisplayName() string { return "Azure" }
+
+func (p *azure) Secrets() []Secret {
+	return []Secret{
+		{Name: "azuretenant", Label: "Tenant ID"},
+		{Name: "azuresubscription", Label: "Subscription ID"},
+		{Name: "azureclient", Label: "Service Principal Client ID"},
+		{Name: "azuresecret", Label: "Service Principal Secret"},
+	}
+}
+
+func (p *azure) Capabilities() Capabilities {
+	return Capabilities{UserData: true}
+}
+
+func (p *azure) Catalog() (Catalog, error) {
+	return Repo.AzureCatalog()
+}
+
+func (p *azure) CreateServer(server models.Server) (models.Server, error) {
+	return Repo.AzureCreateServer(server)
+}
+
+func (p *azure) GetServer(server models.Server) (models.Server, error) {
+	return Repo.AzureGetServer(server)
+}
+
+func (p *azure) ListServers() ([]models.Server, error) {
+	return Repo.AzureRefreshVPS()
+}
+
+func (p *azure) DeleteServer(server models.Server) error {
+	return Repo.AzureDeleteServer(server.ProviderID)
+}
+
+func (p *azure) DeleteAll() error {
+	return Repo.AzureDeleteAll()
+}
+
+func (p *azure) Power(server models.Server, action PowerAction) error {
+	return ErrNotSupported
+}
+
+func (p *azure) ApplyFirewall(server models.Server, rules []models.FirewallRule) error {
+	return Repo.AzureApplyFirewall(server, rules)
+}
+
+func (p *azure) RemoveFirewall(server models.Server) error {
+	return Repo.AzureApplyFirewall(server, azureDefaultRules)
+}
+
+const (
+	azureResourceAPI = "2021-04-01"
+	azureNetworkAPI  = "2023-04-01"
+	azureComputeAPI  = "2023-03-01"
+	azureAdminUser   = "goboxer"
+)
+
+// azureImageReference identifies a marketplace image
+type azureImageReference struct {
+	Publisher string `json:"publisher"`
+	Offer     string `json:"offer"`
+	Sku       string `json:"sku"`
+	Version   string `json:"version"`
+}
+
+// azureImages are the images offered for Azure, keyed by the slug stored on the server
+var azureImages = map[string]azureImageReference{
+	"ubuntu-22.04": {Publisher: "Canonical", Offer: "0001-com-ubuntu-server-jammy", Sku: "22_04-lts-gen2", Version: "latest"},
+	"ubuntu-24.04": {Publisher: "Canonical", Offer: "ubuntu-24_04-lts", Sku: "server", Version: "latest"},
+	"debian-12":    {Publisher: "Debian", Offer: "debian-12", Sku: "12-gen2", Version: "latest"},
+}
+
+// azureSizes are the VM sizes offered for Azure with their approximate East US pay-as-you-go hourly price
+var azureSizes = []CatalogItem{
+	{Slug: "Standard_B1s", Name: "Standard_B1s - 1 vCPU, 1 GB", PriceHourly: 0.0104},
+	{Slug: "Standard_B1ms", Name: "Standard_B1ms - 1 vCPU, 2 GB", PriceHourly: 0.0207},
+	{Slug: "Standard_B2s", Name: "Standard_B2s - 2 vCPU, 4 GB", PriceHourly: 0.0416},
+	{Slug: "Standard_B2ms", Name: "Standard_B2ms - 2 vCPU, 8 GB", PriceHourly: 0.0832},
+	{Slug: "Standard_D2s_v5", Name: "Standard_D2s_v5 - 2 vCPU, 8 GB", PriceHourly: 0.096},
+	{Slug: "Standard_D4s_v5", Name: "Standard_D4s_v5 - 4 vCPU, 16 GB", PriceHourly: 0.192},
+}
+
+// azureDefaultRules are the inbound rules of a VM without a firewall policy, only SSH which Ansible needs
+var azureDefaultRules = []models.FirewallRule{{Protocol: "tcp", Ports: "22", Sources: anywhere}}
+
+// azureUserData lets root log in with the admin user's key, which Ansible relies on
+const azureUserData = `#cloud-config
+disable_root: false
+`
+
+// azureResource is the common shape of ARM resources returned by the API
+type azureResource struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Location   string            `json:"location"`
+	Tags       map[string]string `json:"tags"`
+	Properties json.RawMessage   `json:"properties"`
+}
+
+// azureClient returns an ARM API client authenticated with the service principal, along with
+// the base path for the subscription
+func (m *Repository) azureClient(ctx context.Context) (*restClient, string, error) {
+	secrets, err := m.DB.GetAllSecrets()
+	if err != nil {
+		return nil, "", fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	tenant := secrets["azuretenant"]
+	subscription := secrets["azuresubscription"]
+	if tenant == "" || subscription == "" || secrets["azureclient"] == "" || secrets["azuresecret"] == "" {
+		return nil, "", errors.New("azure service principal credentials not found in secrets")
+	}
+
+	form := url.Values{
+		"client_id":     {secrets["azureclient"]},
+		"client_secret": {secrets["azuresecret"]},
+		"scope":         {"https://management.azure.com/.default"},
+		"grant_type":    {"client_credentials"},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/token", url.PathEscape(tenant)),
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("error authenticating with azure: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		AccessToken      string `json:"access_token"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, "", fmt.Errorf("error decoding azure token: %v", err)
+	}
+	if token.AccessToken == "" {
+		return nil, "", fmt.Errorf("error authenticating with azure: %s", token.ErrorDescription)
+	}
+
+	client := &restClient{
+		BaseURL: "https://management.azure.com",
+		Headers: map[string]string{"Authorization": "Bearer " + token.AccessToken},
+	}
+	return client, "/subscriptions/" + subscription, nil
+}
+
+// azureResourceGroup is the name of the resource group that holds a project's servers
+func azureResourceGroup(project int) string {
+	return fmt.Sprintf("%s-project-%d", Tags[0], project)
+}
+
+// azureTags returns the application tags as an ARM tag map
+func azureTags() map[string]string {
+	tags := make(map[string]string)
+	for _, tag := range Tags {
+		tags[tag] = ""
+	}
+	return tags
+}
+
+// azurePut creates or updates a resource and waits for it to finish provisioning
+func azurePut(ctx context.Context, client *restClient, path, apiVersion string, body interface{}) (azureResource, error) {
+	var resource azureResource
+	if err := client.do(ctx, http.MethodPut, path+"?api-version="+apiVersion, body, &resource); err != nil {
+		return resource, err
+	}
+
+	for {
+		var state struct {
+			ProvisioningState string `json:"provisioningState"`
+		}
+		if len(resource.Properties) > 0 {
+			if err := json.Unmarshal(resource.Properties, &state); err != nil {
+				return resource, err
+			}
+		}
+
+		switch state.ProvisioningState {
+		case "", "Succeeded":
+			return resource, nil
+		case "Failed", "Canceled":
+			return resource, fmt.Errorf("provisioning %s %s", path, strings.ToLower(state.ProvisioningState))
+		}
+
+		select {
+		case <-ctx.Done():
+			return resource, ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+
+		resource = azureResource{}
+		if err := client.do(ctx, http.MethodGet, path+"?api-version="+apiVersion, nil, &resource); err != nil {
+			return resource, err
+		}
+	}
+}
+
+// azureDelete deletes a resource and waits until it no longer exists
+func azureDelete(ctx context.Context, client *restClient, path, apiVersion string) error {
+	if err := client.do(ctx, http.MethodDelete, path+"?api-version="+apiVersion, nil, nil); err != nil {
+		return err
+	}
+
+	for {
+		err := client.do(ctx, http.MethodGet, path+"?api-version="+apiVersion, nil, nil)
+		if isNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// AzureCreateServer creates a VM with a static public IP and network security group in the project's
+// resource group, and returns the server object with the public IP address
+func (m *Repository) AzureCreateServer(server models.Server) (models.Server, error) {
+	location := server.Region
+	if location == "" {
+		location = "eastus"
+		server.Region = location
+	}
+
+	vmSize := server.Size
+	if vmSize == "" {
+		vmSize = "Standard_B1s"
+	}
+
+	imageSlug := server.Image
+	if imageSlug == "" {
+		imageSlug = "ubuntu-22.04"
+	}
+	image, ok := azureImages[imageSlug]
+	if !ok {
+		return server, fmt.Errorf("unsupported image: %s", imageSlug)
+	}
+
+	publicKey, err := m.DB.GetSecret("sshkey")
+	if err != nil {
+		return server, fmt.Errorf("error getting SSH key from database: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Minute)
+	defer cancel()
+
+	client, subscription, err := m.azureClient(ctx)
+	if err != nil {
+		return server, err
+	}
+
+	tags := azureTags()
+	group := subscription + "/resourcegroups/" + azureResourceGroup(server.Project)
+
+	// A resource group's location cannot change, so one created for a server in another region is kept
+	err = client.do(ctx, http.MethodGet, group+"?api-version="+azureResourceAPI, nil, nil)
+	if isNotFound(err) {
+		_, err = azurePut(ctx, client, group, azureResourceAPI, map[string]interface{}{
+			"location": location,
+			"tags":     tags,
+		})
+	}
+	if err != nil {
+		return server, fmt.Errorf("error creating azure resource group: %v", err)
+	}
+
+	network := group + "/providers/Microsoft.Network"
+
+	// Virtual networks are regional, so each region used by a project gets its own
+	vnetName := "goboxer-vnet-" + location
+	if _, err := azurePut(ctx, client, network+"/virtualNetworks/"+vnetName, azureNetworkAPI, map[string]interface{}{
+		"location": location,
+		"tags":     tags,
+		"properties": map[string]interface{}{
+			"addressSpace": map[string]interface{}{"addressPrefixes": []string{"10.0.0.0/16"}},
+			"subnets": []map[string]interface{}{
+				{"name": "default", "properties": map[string]string{"addressPrefix": "10.0.0.0/24"}},
+			},
+		},
+	}); err != nil {
+		return server, fmt.Errorf("error creating azure virtual network: %v", err)
+	}
+
+	publicIP, err := azurePut(ctx, client, network+"/publicIPAddresses/"+server.Name+"-ip", azureNetworkAPI, map[string]interface{}{
+		"location":   location,
+		"tags":       tags,
+		"sku":        map[string]string{"name": "Standard"},
+		"properties": map[string]string{"publicIPAllocationMethod": "Static"},
+	})
+	if err != nil {
+		return server, fmt.Errorf("error creating azure public IP: %v", err)
+	}
+
+	nsg, err := azurePut(ctx, client, network+"/networkSecurityGroups/"+server.Name+"-nsg", azureNetworkAPI, map[string]interface{}{
+		"location": location,
+		"tags":     tags,
+		"properties": map[string]interface{}{
+			"securityRules": azureSecurityRules(azureDefaultRules),
+		},
+	})
+	if err != nil {
+		return server, fmt.Errorf("error creating azure network security group: %v", err)
+	}
+
+	nic, err := azurePut(ctx, client, network+"/networkInterfaces/"+server.Name+"-nic", azureNetworkAPI, map[string]interface{}{
+		"location": location,
+		"tags":     tags,
+		"properties": map[string]interface{}{
+			"networkSecurityGroup": map[string]string{"id": nsg.ID},
+			"ipConfigurations": []map[string]interface{}{
+				{
+					"name": "ipconfig1",
+					"properties": map[string]interface{}{
+						"subnet":                    map[string]string{"id": group + "/providers/Microsoft.Network/virtualNetworks/" + vnetName + "/subnets/default"},
+						"publicIPAddress":           map[string]string{"id": publicIP.ID},
+						"privateIPAllocationMethod": "Dynamic",
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return server, fmt.Errorf("error creating azure network interface: %v", err)
+	}
+
+	vmPath := group + "/providers/Microsoft.Compute/virtualMachines/" + server.Name
+	vm, err := azurePut(ctx, client, vmPath, azureComputeAPI, map[string]interface{}{
+		"location": location,
+		"tags":     tags,
+		"properties": map[string]interface{}{
+			"hardwareProfile": map[string]string{"vmSize": vmSize},
+			"storageProfile": map[string]interface{}{
+				"imageReference": image,
+				"osDisk": map[string]interface{}{
+					"createOption": "FromImage",
+					"deleteOption": "Delete",
+					"managedDisk":  map[string]string{"storageAccountType": "Standard_LRS"},
+				},
+			},
+			"osProfile": map[string]interface{}{
+				"computerName":  server.Name,
+				"adminUsername": azureAdminUser,
+				"customData":    base64.StdEncoding.EncodeToString([]byte(cloudinit.Combine(azureUserData, server.UserData))),
+				"linuxConfiguration": map[string]interface{}{
+					"disablePasswordAuthentication": true,
+					"ssh": map[string]interface{}{
+						"publicKeys": []map[string]string{
+							{"path": "/home/" + azureAdminUser + "/.ssh/authorized_keys", "keyData": strings.TrimSpace(publicKey)},
+						},
+					},
+				},
+			},
+			"networkProfile": map[string]interface{}{
+				"networkInterfaces": []map[string]interface{}{
+					{"id": nic.ID, "properties": map[string]string{"deleteOption": "Delete"}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return server, fmt.Errorf("error creating azure virtual machine: %v", err)
+	}
+
+	server.ProviderID = vm.ID
+
+	ip, err := azurePublicIP(ctx, client, network+"/publicIPAddresses/"+server.Name+"-ip")
+	if err != nil {
+		return server, err
+	}
+	server.IP = ip
+
+	return server, nil
+}
+
+// azureSecurityRules converts firewall rules into network security group rules. Azure does not allow
+// IPv4 and IPv6 sources in one rule, so each address family gets its own.
+func azureSecurityRules(rules []models.FirewallRule) []map[string]interface{} {
+	securityRules := []map[string]interface{}{}
+	for _, rule := range rules {
+		ports := rule.Ports
+		if ports == "" || rule.Protocol == "icmp" {
+			ports = "*"
+		}
+
+		ipv4, ipv6 := splitSources(rule.Sources)
+		for _, sources := range [][]string{ipv4, ipv6} {
+			if len(sources) == 0 {
+				continue
+			}
+			securityRules = append(securityRules, map[string]interface{}{
+				"name": fmt.Sprintf("goboxer-%d", len(securityRules)+1),
+				"properties": map[string]interface{}{
+					"priority":                 100 + len(securityRules),
+					"direction":                "Inbound",
+					"access":                   "Allow",
+					"protocol":                 strings.ToUpper(rule.Protocol[:1]) + rule.Protocol[1:],
+					"sourceAddressPrefixes":    sources,
+					"sourcePortRange":          "*",
+					"destinationAddressPrefix": "*",
+					"destinationPortRange":     ports,
+				},
+			})
+		}
+	}
+	return securityRules
+}
+
+// AzureApplyFirewall replaces the rules of a VM's network security group, anything they do not allow
+// is denied by the group's default rules
+func (m *Repository) AzureApplyFirewall(server models.Server, rules []models.FirewallRule) error {
+	group, name, err := azureSplitID(server.ProviderID)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	client, _, err := m.azureClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	path := group + "/providers/Microsoft.Network/networkSecurityGroups/" + name + "-nsg"
+	var nsg azureResource
+	if err := client.do(ctx, http.MethodGet, path+"?api-version="+azureNetworkAPI, nil, &nsg); err != nil {
+		return fmt.Errorf("error getting azure network security group: %v", err)
+	}
+
+	if _, err := azurePut(ctx, client, path, azureNetworkAPI, map[string]interface{}{
+		"location": nsg.Location,
+		"tags":     nsg.Tags,
+		"properties": map[string]interface{}{
+			"securityRules": azureSecurityRules(rules),
+		},
+	}); err != nil {
+		return fmt.Errorf("error applying firewall on azure: %v", err)
+	}
+	return nil
+}
+
+// azurePublicIP returns the address assigned to a public IP resource
+func azurePublicIP(ctx context.Context, client *restClient, path string) (string, error) {
+	var publicIP struct {
+		Properties struct {
+			IPAddress string `json:"ipAddress"`
+		} `json:"properties"`
+	}
+	if err := client.do(ctx, http.MethodGet, path+"?api-version="+azureNetworkAPI, nil, &publicIP); err != nil {
+		return "", fmt.Errorf("error getting azure public IP: %v", err)
+	}
+	return publicIP.Properties.IPAddress, nil
+}
+
+// azureSplitID returns the resource group path and VM name from a VM resource ID
+func azureSplitID(vmID string) (string, string, error) {
+	index := strings.Index(strings.ToLower(vmID), "/providers/microsoft.compute/virtualmachines/")
+	if index == -1 {
+		return "", "", fmt.Errorf("invalid azure VM ID: %s", vmID)
+	}
+	return vmID[:index], vmID[strings.LastIndex(vmID, "/")+1:], nil
+}
+
+// azureServerFromVM converts a VM resource, fetched with its instance view, into a server
+func azureServerFromVM(ctx context.Context, client *restClient, vm azureResource) (models.Server, error) {
+	server := models.Server{
+		Provider:   "azure",
+		Name:       vm.Name,
+		ProviderID: vm.ID,
+		Region:     vm.Location,
+	}
+
+	var properties struct {
+		HardwareProfile struct {
+			VMSize string `json:"vmSize"`
+		} `json:"hardwareProfile"`
+		InstanceView struct {
+			Statuses []struct {
+				Code string `json:"code"`
+			} `json:"statuses"`
+		} `json:"instanceView"`
+	}
+	if err := json.Unmarshal(vm.Properties, &properties); err != nil {
+		return server, err
+	}
+	server.Size = properties.HardwareProfile.VMSize
+
+	for _, status := range properties.InstanceView.Statuses {
+		if strings.HasPrefix(status.Code, "PowerState/") {
+			server.Status = strings.TrimPrefix(status.Code, "PowerState/")
+		}
+	}
+
+	group, name, err := azureSplitID(vm.ID)
+	if err != nil {
+		return server, err
+	}
+
+	// A VM whose public IP was renamed or removed outside GoBoxer is still listed, without an address
+	ip, err := azurePublicIP(ctx, client, group+"/providers/Microsoft.Network/publicIPAddresses/"+name+"-ip")
+	if err != nil {
+		log.Printf("Error getting public IP of azure VM %s: %v", vm.Name, err)
+	}
+	server.IP = ip
+
+	return server, nil
+}
+
+// AzureCatalog lists the locations available to the subscription along with the supported VM sizes and images
+func (m *Repository) AzureCatalog() (Catalog, error) {
+	var catalog Catalog
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	client, subscription, err := m.azureClient(ctx)
+	if err != nil {
+		return catalog, err
+	}
+
+	var locations struct {
+		Value []struct {
+			Name        string `json:"name"`
+			DisplayName string `json:"displayName"`
+			Metadata    struct {
+				RegionType string `json:"regionType"`
+			} `json:"metadata"`
+		} `json:"value"`
+	}
+	if err := client.do(ctx, http.MethodGet, subscription+"/locations?api-version=2022-12-01", nil, &locations); err != nil {
+		return catalog, fmt.Errorf("error getting list of locations from azure: %v", err)
+	}
+	for _, location := range locations.Value {
+		if location.Metadata.RegionType == "Physical" {
+			catalog.Regions = append(catalog.Regions, CatalogItem{Slug: location.Name, Name: location.DisplayName})
+		}
+	}
+
+	catalog.Sizes = append(catalog.Sizes, azureSizes...)
+
+	for slug, image := range azureImages {
+		catalog.Images = append(catalog.Images, CatalogItem{Slug: slug, Name: fmt.Sprintf("%s %s", image.Offer, image.Sku)})
+	}
+
+	return catalog, nil
+}
+
+// AzureGetServer fetches the current state of a VM from Azure
+func (m *Repository) AzureGetServer(server models.Server) (models.Server, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	client, _, err := m.azureClient(ctx)
+	if err != nil {
+		return server, err
+	}
+
+	var vm azureResource
+	if err := client.do(ctx, http.MethodGet, server.ProviderID+"?$expand=instanceView&api-version="+azureComputeAPI, nil, &vm); err != nil {
+		return server, fmt.Errorf("error getting VM from azure: %v", err)
+	}
+
+	current, err := azureServerFromVM(ctx, client, vm)
+	if err != nil {
+		return server, err
+	}
+
+	if current.IP != "" {
+		server.IP = current.IP
+	}
+	server.Status = current.Status
+	return server, nil
+}
+
+// AzureRefreshVPS returns the VMs in the subscription tagged with the application tag
+func (m *Repository) AzureRefreshVPS() ([]models.Server, error) {
+	servers := []models.Server{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	client, subscription, err := m.azureClient(ctx)
+	if err != nil {
+		return servers, err
+	}
+
+	next := subscription + "/providers/Microsoft.Compute/virtualMachines?statusOnly=true&api-version=" + azureComputeAPI
+	for next != "" {
+		var page struct {
+			Value    []azureResource `json:"value"`
+			NextLink string          `json:"nextLink"`
+		}
+		if err := client.do(ctx, http.MethodGet, next, nil, &page); err != nil {
+			return servers, fmt.Errorf("error getting list of VMs from azure: %v", err)
+		}
+
+		for _, vm := range page.Value {
+			if _, ok := vm.Tags[Tags[0]]; !ok {
+				continue
+			}
+			server, err := azureServerFromVM(ctx, client, vm)
+			if err != nil {
+				return servers, err
+			}
+			servers = append(servers, server)
+		}
+
+		next = strings.TrimPrefix(page.NextLink, client.BaseURL)
+	}
+
+	return servers, nil
+}
+
+// AzureDeleteServer deletes a VM and its network resources, then removes the
+// project's resource group if no other VMs are left in it
+func (m *Repository) AzureDeleteServer(vmID string) error {
+	group, name, err := azureSplitID(vmID)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Minute)
+	defer cancel()
+
+	client, _, err := m.azureClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The OS disk and NIC are removed with the VM as they were created with deleteOption set.
+	// A VM, or resource group, already deleted outside GoBoxer counts as deleted.
+	if err := azureDelete(ctx, client, vmID, azureComputeAPI); err != nil && !isNotFound(err) {
+		return fmt.Errorf("error deleting azure virtual machine: %v", err)
+	}
+
+	network := group + "/providers/Microsoft.Network"
+	for _, path := range []string{
+		network + "/networkInterfaces/" + name + "-nic",
+		network + "/publicIPAddresses/" + name + "-ip",
+		network + "/networkSecurityGroups/" + name + "-nsg",
+	} {
+		if err := azureDelete(ctx, client, path, azureNetworkAPI); err != nil && !isNotFound(err) {
+			return fmt.Errorf("error deleting azure resource %s: %v", path, err)
+		}
+	}
+
+	var remaining struct {
+		Value []azureResource `json:"value"`
+	}
+	err = client.do(ctx, http.MethodGet, group+"/providers/Microsoft.Compute/virtualMachines?api-version="+azureComputeAPI, nil, &remaining)
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error getting list of VMs in resource group: %v", err)
+	}
+
+	if len(remaining.Value) == 0 {
+		if err := azureDelete(ctx, client, group, azureResourceAPI); err != nil {
+			return fmt.Errorf("error deleting azure resource group: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// AzureDeleteAll deletes every resource group tagged with the application tag
+func (m *Repository) AzureDeleteAll() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	client, subscription, err := m.azureClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	var groups struct {
+		Value []azureResource `json:"value"`
+	}
+	filter := url.QueryEscape(fmt.Sprintf("tagName eq '%s'", Tags[0]))
+	if err := client.do(ctx, http.MethodGet, subscription+"/resourcegroups?$filter="+filter+"&api-version="+azureResourceAPI, nil, &groups); err != nil {
+		return fmt.Errorf("error getting list of resource groups from azure: %v", err)
+	}
+
+	for _, group := range groups.Value {
+		if err := azureDelete(ctx, client, group.ID, azureResourceAPI); err != nil {
+			return fmt.Errorf("error deleting azure resource group %s: %v", group.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// catalogTTL is how long a provider's catalog is cached before it is fetched again
+const catalogTTL = 1 * time.Hour
+
+// CatalogItem is a single region, plan size or image offered by a provider
+type CatalogItem struct {
+	Slug        string
+	Name        string
+	PriceHourly float64
+}
+
+// Catalog holds the regions, plan sizes and images a provider can deploy
+type Catalog struct {
+	Regions []CatalogItem
+	Sizes   []CatalogItem
+	Images  []CatalogItem
+}
+
+type cachedCatalog struct {
+	catalog   Catalog
+	fetchedAt time.Time
+}
+
+var catalogCache = struct {
+	sync.Mutex
+	entries map[string]cachedCatalog
+}{entries: make(map[string]cachedCatalog)}
+
+// GetCatalog returns the catalog for a provider, using the cached copy if it is still fresh
+func GetCatalog(p CloudProvider) (Catalog, error) {
+	catalogCache.Lock()
+	entry, ok := catalogCache.entries[p.Name()]
+	catalogCache.Unlock()
+
+	if ok && time.Since(entry.fetchedAt) < catalogTTL {
+		return entry.catalog, nil
+	}
+
+	catalog, err := p.Catalog()
+	if err != nil {
+		return catalog, err
+	}
+
+	catalogCache.Lock()
+	catalogCache.entries[p.Name()] = cachedCatalog{catalog: catalog, fetchedAt: time.Now()}
+	catalogCache.Unlock()
+
+	return catalog, nil
+}
+
+// InvalidateCatalog removes a provider's cached catalog, used when its images change
+func InvalidateCatalog(p CloudProvider) {
+	catalogCache.Lock()
+	delete(catalogCache.entries, p.Name())
+	catalogCache.Unlock()
+}
+
+// Image returns the image with the given slug
+func (c Catalog) Image(slug string) (CatalogItem, bool) {
+	return findItem(c.Images, slug)
+}
+
+// Size returns the plan size with the given slug
+func (c Catalog) Size(slug string) (CatalogItem, bool) {
+	return findItem(c.Sizes, slug)
+}
+
+// findItem returns the catalog item with the given slug
+func findItem(items []CatalogItem, slug string) (CatalogItem, bool) {
+	for _, item := range items {
+		if item.Slug == slug {
+			return item, true
+		}
+	}
+	return CatalogItem{}, false
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/digitalocean/godo"
@@ -11,7 +13,103 @@ import (
 	"golang.org/x/oauth2"
 )
 
+func init() {
+	RegisterProvider(&digitalOcean{})
+}
+
+// digitalOcean implements CloudProvider for Digital Ocean droplets
+type digitalOcean struct{}
 
+func (p *digitalOcean) Name() string        { return "digitalocean" }
+func (p *digitalOcean) DisplayName() string { return "Digital Ocean" }
+
+func (p *digitalOcean) Secrets() []Secret {
+	return []Secret{{Name: "digitalocean", Label: "Digital Ocean"}}
+}
+
+func (p *digitalOcean) Capabilities() Capabilities {
+	return Capabilities{PowerActions: []PowerAction{PowerOn, Shutdown, Reboot, Reset}, UserData: true}
+}
+
+func (p *digitalOcean) Catalog() (Catalog, error) {
+	return Repo.DigitalOceanCatalog()
+}
+
+func (p *digitalOcean) CreateServer(server models.Server) (models.Server, error) {
+	return Repo.DigitalOceanCreateServer(server)
+}
+
+func (p *digitalOcean) GetServer(server models.Server) (models.Server, error) {
+	return Repo.DigitalOceanGetServer(server)
+}
+
+func (p *digitalOcean) ListServers() ([]models.Server, error) {
+	return Repo.DigitalOceanRefreshVPS()
+}
+
+func (p *digitalOcean) DeleteServer(server models.Server) error {
+	return Repo.DigitalOceanDeleteServer(server.ProviderID)
+}
+
+func (p *digitalOcean) DeleteAll() error {
+	return Repo.DigitalOceanDeleteAll()
+}
+
+func (p *digitalOcean) Power(server models.Server, action PowerAction) error {
+	return Repo.DigitalOceanPower(server.ProviderID, action)
+}
+
+func (p *digitalOcean) RegisterSSHKey() error {
+	return Repo.AddSSHKeyToDigitalOcean()
+}
+
+func (p *digitalOcean) ListAllServers() ([]models.Server, error) {
+	return Repo.DigitalOceanListAll()
+}
+
+func (p *digitalOcean) TagServer(server models.Server) error {
+	return Repo.DigitalOceanTagServer(server)
+}
+
+func (p *digitalOcean) CreateSnapshot(server models.Server, name string) error {
+	return Repo.DigitalOceanCreateSnapshot(server.ProviderID, name)
+}
+
+func (p *digitalOcean) ListSnapshots(server models.Server) ([]Snapshot, error) {
+	return Repo.DigitalOceanListSnapshots(server.ProviderID)
+}
+
+func (p *digitalOcean) RebuildServer(server models.Server, image string) error {
+	return Repo.DigitalOceanRebuildServer(server.ProviderID, image)
+}
+
+func (p *digitalOcean) ListIPs(region string) ([]ReservedIP, error) {
+	return Repo.DigitalOceanListIPs(region)
+}
+
+func (p *digitalOcean) AllocateIP(server models.Server) (ReservedIP, error) {
+	return Repo.DigitalOceanAllocateIP(server.ProviderID)
+}
+
+func (p *digitalOcean) AttachIP(server models.Server, ip ReservedIP) error {
+	return Repo.DigitalOceanAttachIP(server.ProviderID, ip.Address)
+}
+
+func (p *digitalOcean) DetachIP(ip ReservedIP) error {
+	return Repo.DigitalOceanDetachIP(ip.Address)
+}
+
+func (p *digitalOcean) ReleaseIP(ip ReservedIP) error {
+	return Repo.DigitalOceanReleaseIP(ip.Address)
+}
+
+func (p *digitalOcean) ApplyFirewall(server models.Server, rules []models.FirewallRule) error {
+	return Repo.DigitalOceanApplyFirewall(server, rules)
+}
+
+func (p *digitalOcean) RemoveFirewall(server models.Server) error {
+	return Repo.DigitalOceanRemoveFirewall(server)
+}
 
 // TokenSource is an oauth2.TokenSource which returns a static access token
 type TokenSource struct {
@@ -28,17 +126,25 @@ func (t *TokenSource) Token() (*oauth2.Token, error) {
 
 // DigitalOceanCreateServer creates a droplet on Digital Ocean and returns the server object with the IP address
 func (m *Repository) DigitalOceanCreateServer(server models.Server) (models.Server, error) {
-	region := "nyc3"
-	size := "s-1vcpu-1gb"
-
-	// Map server.OS to DigitalOcean's slug format
-	var operatingSystem string
-	switch server.OS {
-	case "ubuntu-2204":
-		operatingSystem = "ubuntu-22-04-x64"
-	// Add more cases as needed
-	default:
-		return server, fmt.Errorf("unsupported OS: %s", server.OS)
+	region := server.Region
+	if region == "" {
+		region = "nyc3"
+	}
+
+	size := server.Size
+	if size == "" {
+		size = "s-1vcpu-1gb"
+	}
+
+	// Fall back to mapping server.OS to DigitalOcean's slug format when no image was chosen
+	operatingSystem := server.Image
+	if operatingSystem == "" {
+		switch server.OS {
+		case "ubuntu-2204":
+			operatingSystem = "ubuntu-22-04-x64"
+		default:
+			return server, fmt.Errorf("unsupported OS: %s", server.OS)
+		}
 	}
 
 	// Retrieve API key and SSH fingerprint from secrets
@@ -61,12 +167,18 @@ func (m *Repository) DigitalOceanCreateServer(server models.Server) (models.Serv
 
 	// Prepare droplet creation request
 	createRequest := &godo.DropletCreateRequest{
-		Name:    server.Name,
-		Tags:    Tags,
-		SSHKeys: []godo.DropletCreateSSHKey{{Fingerprint: sshFingerprint}},
-		Region:  region,
-		Size:    size,
-		Image:   godo.DropletCreateImage{Slug: operatingSystem},
+		Name:     server.Name,
+		Tags:     Tags,
+		SSHKeys:  []godo.DropletCreateSSHKey{{Fingerprint: sshFingerprint}},
+		Region:   region,
+		Size:     size,
+		Image:    godo.DropletCreateImage{Slug: operatingSystem},
+		UserData: server.UserData,
+	}
+
+	// Snapshots are referenced by their numeric image ID rather than a slug
+	if imageID, err := strconv.Atoi(operatingSystem); err == nil {
+		createRequest.Image = godo.DropletCreateImage{ID: imageID}
 	}
 
 	// Create droplet
@@ -77,6 +189,8 @@ func (m *Repository) DigitalOceanCreateServer(server models.Server) (models.Serv
 	if err != nil {
 		return server, fmt.Errorf("error creating droplet on DigitalOcean: %v", err)
 	}
+	// Recorded straight away so the droplet can still be deleted if it never gets an address
+	server.ProviderID = strconv.Itoa(droplet.ID)
 
 	// Poll for IP address assignment
 	for attempt := 0; attempt < 12; attempt++ {
@@ -87,7 +201,6 @@ func (m *Repository) DigitalOceanCreateServer(server models.Server) (models.Serv
 		}
 		if vpsIP, err := droplet.PublicIPv4(); err == nil && vpsIP != "" {
 			server.IP = vpsIP
-			server.ProviderID = droplet.ID // Assume droplet.ID is int, adjust if it's not
 			return server, nil
 		}
 	}
@@ -95,8 +208,85 @@ func (m *Repository) DigitalOceanCreateServer(server models.Server) (models.Serv
 	return server, errors.New("failed to obtain public IP address for the droplet")
 }
 
+// DigitalOceanCatalog lists the regions, droplet sizes and distribution images available on Digital Ocean
+func (m *Repository) DigitalOceanCatalog() (Catalog, error) {
+	var catalog Catalog
+
+	apiKey, err := m.DB.GetSecret("digitalocean")
+	if err != nil {
+		return catalog, fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	client := godo.NewFromToken(apiKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	opt := &godo.ListOptions{PerPage: 200}
+
+	regions, _, err := client.Regions.List(ctx, opt)
+	if err != nil {
+		return catalog, fmt.Errorf("error getting list of regions from digital ocean: %v", err)
+	}
+	for _, region := range regions {
+		if region.Available {
+			catalog.Regions = append(catalog.Regions, CatalogItem{Slug: region.Slug, Name: region.Name})
+		}
+	}
+
+	sizes, _, err := client.Sizes.List(ctx, opt)
+	if err != nil {
+		return catalog, fmt.Errorf("error getting list of sizes from digital ocean: %v", err)
+	}
+	for _, size := range sizes {
+		if size.Available {
+			catalog.Sizes = append(catalog.Sizes, CatalogItem{
+				Slug:        size.Slug,
+				Name:        fmt.Sprintf("%s - %d vCPU, %d MB, $%.2f/mo", size.Slug, size.Vcpus, size.Memory, size.PriceMonthly),
+				PriceHourly: size.PriceHourly,
+			})
+		}
+	}
+
+	images, _, err := client.Images.ListDistribution(ctx, opt)
+	if err != nil {
+		return catalog, fmt.Errorf("error getting list of images from digital ocean: %v", err)
+	}
+	for _, image := range images {
+		if image.Slug != "" {
+			catalog.Images = append(catalog.Images, CatalogItem{Slug: image.Slug, Name: fmt.Sprintf("%s %s", image.Distribution, image.Name)})
+		}
+	}
+
+	// Snapshots have no slug and are created from by their numeric ID
+	snapshots, _, err := client.Images.ListUser(ctx, opt)
+	if err != nil {
+		return catalog, fmt.Errorf("error getting list of snapshots from digital ocean: %v", err)
+	}
+	for _, image := range snapshots {
+		if image.Type == "snapshot" {
+			catalog.Images = append(catalog.Images, CatalogItem{
+				Slug: strconv.Itoa(image.ID),
+				Name: fmt.Sprintf("Snapshot: %s (%s)", image.Name, strings.Join(image.Regions, ", ")),
+			})
+		}
+	}
+
+	return catalog, nil
+}
+
 // DigitalOceanRefreshVPS refreshes the list of servers currently deployed on Digital Ocean
 func (m *Repository) DigitalOceanRefreshVPS() ([]models.Server, error) {
+	return m.digitalOceanListDroplets(true)
+}
+
+// DigitalOceanListAll returns every droplet on the Digital Ocean account, tagged or not
+func (m *Repository) DigitalOceanListAll() ([]models.Server, error) {
+	return m.digitalOceanListDroplets(false)
+}
+
+// digitalOceanListDroplets lists droplets on Digital Ocean, only those with the application tag if tagged is set
+func (m *Repository) digitalOceanListDroplets(tagged bool) ([]models.Server, error) {
 	servers := []models.Server{}
 
 	apiKey, err := m.DB.GetSecret("digitalocean")
@@ -117,24 +307,31 @@ func (m *Repository) DigitalOceanRefreshVPS() ([]models.Server, error) {
 		}
 
 		for _, droplet := range droplets {
-			for _, tag := range droplet.Tags {
-				if tag == Tags[0] {
-					ip, err := droplet.PublicIPv4()
-					if err != nil {
-						return servers, fmt.Errorf("error getting IPv4 address from digital ocean: %v", err)
-					}
+			if tagged && !hasTag(droplet.Tags, Tags[0]) {
+				continue
+			}
 
-					server := models.Server{
-						Provider: "digitalocean",
-						Name:     droplet.Name,
-						ID:       droplet.ID,
-						IP:       ip,
-						Status:   droplet.Status,
-					}
-					servers = append(servers, server)
-				}
+			ip, err := droplet.PublicIPv4()
+			if err != nil {
+				return servers, fmt.Errorf("error getting IPv4 address from digital ocean: %v", err)
 			}
 
+			server := models.Server{
+				Provider:   "digitalocean",
+				Name:       droplet.Name,
+				ProviderID: strconv.Itoa(droplet.ID),
+				IP:         ip,
+				Size:       droplet.SizeSlug,
+				Status:     droplet.Status,
+			}
+			if droplet.Region != nil {
+				server.Region = droplet.Region.Slug
+			}
+			if droplet.Image != nil {
+				server.Image = droplet.Image.Slug
+				server.OS = fmt.Sprintf("%s %s", droplet.Image.Distribution, droplet.Image.Name)
+			}
+			servers = append(servers, server)
 		}
 
 		// if we are at the last page, break out the for loop
@@ -153,8 +350,513 @@ func (m *Repository) DigitalOceanRefreshVPS() ([]models.Server, error) {
 	return servers, nil
 }
 
+// DigitalOceanTagServer adds the application tag to an existing droplet so it is managed by GoBoxer
+func (m *Repository) DigitalOceanTagServer(server models.Server) error {
+	apiKey, err := m.DB.GetSecret("digitalocean")
+	if err != nil {
+		return fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	client := godo.NewFromToken(apiKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	// Creating a tag which already exists is not an error
+	if _, _, err := client.Tags.Create(ctx, &godo.TagCreateRequest{Name: Tags[0]}); err != nil {
+		return fmt.Errorf("error creating tag on digital ocean: %v", err)
+	}
+
+	request := &godo.TagResourcesRequest{
+		Resources: []godo.Resource{{ID: server.ProviderID, Type: godo.DropletResourceType}},
+	}
+	if _, err := client.Tags.TagResources(ctx, Tags[0], request); err != nil {
+		return fmt.Errorf("error tagging droplet on digital ocean: %v", err)
+	}
+	return nil
+}
+
+// DigitalOceanPower performs a power action on a droplet and waits for the action to complete
+func (m *Repository) DigitalOceanPower(providerID string, action PowerAction) error {
+	dropletID, err := strconv.Atoi(providerID)
+	if err != nil {
+		return fmt.Errorf("invalid droplet ID %q: %v", providerID, err)
+	}
+
+	apiKey, err := m.DB.GetSecret("digitalocean")
+	if err != nil {
+		return fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	client := godo.NewFromToken(apiKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	var doAction *godo.Action
+	switch action {
+	case PowerOn:
+		doAction, _, err = client.DropletActions.PowerOn(ctx, dropletID)
+	case Shutdown:
+		doAction, _, err = client.DropletActions.Shutdown(ctx, dropletID)
+	case Reboot:
+		doAction, _, err = client.DropletActions.Reboot(ctx, dropletID)
+	case Reset:
+		doAction, _, err = client.DropletActions.PowerCycle(ctx, dropletID)
+	default:
+		return ErrNotSupported
+	}
+	if err != nil {
+		return fmt.Errorf("error performing %s on droplet: %v", action, err)
+	}
+
+	for doAction.Status == godo.ActionInProgress {
+		time.Sleep(5 * time.Second)
+
+		doAction, _, err = client.DropletActions.Get(ctx, dropletID, doAction.ID)
+		if err != nil {
+			return fmt.Errorf("error getting droplet action from digital ocean: %v", err)
+		}
+	}
+
+	if doAction.Status != godo.ActionCompleted {
+		return fmt.Errorf("droplet %s did not complete: %s", action, doAction.Status)
+	}
+	return nil
+}
+
+// DigitalOceanCreateSnapshot snapshots a droplet and waits for the snapshot to finish
+func (m *Repository) DigitalOceanCreateSnapshot(providerID, name string) error {
+	dropletID, err := strconv.Atoi(providerID)
+	if err != nil {
+		return fmt.Errorf("invalid droplet ID %q: %v", providerID, err)
+	}
+
+	apiKey, err := m.DB.GetSecret("digitalocean")
+	if err != nil {
+		return fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	client := godo.NewFromToken(apiKey)
+
+	// Snapshots of large disks can take a long time
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+	defer cancel()
+
+	action, _, err := client.DropletActions.Snapshot(ctx, dropletID, name)
+	if err != nil {
+		return fmt.Errorf("error creating snapshot on digital ocean: %v", err)
+	}
+
+	for action.Status == godo.ActionInProgress {
+		time.Sleep(15 * time.Second)
+
+		action, _, err = client.DropletActions.Get(ctx, dropletID, action.ID)
+		if err != nil {
+			return fmt.Errorf("error getting droplet action from digital ocean: %v", err)
+		}
+	}
+
+	if action.Status != godo.ActionCompleted {
+		return fmt.Errorf("droplet snapshot did not complete: %s", action.Status)
+	}
+	return nil
+}
+
+// DigitalOceanListSnapshots lists the snapshots taken of a droplet
+func (m *Repository) DigitalOceanListSnapshots(providerID string) ([]Snapshot, error) {
+	snapshots := []Snapshot{}
+
+	dropletID, err := strconv.Atoi(providerID)
+	if err != nil {
+		return snapshots, fmt.Errorf("invalid droplet ID %q: %v", providerID, err)
+	}
+
+	apiKey, err := m.DB.GetSecret("digitalocean")
+	if err != nil {
+		return snapshots, fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	client := godo.NewFromToken(apiKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	images, _, err := client.Droplets.Snapshots(ctx, dropletID, &godo.ListOptions{PerPage: 200})
+	if err != nil {
+		return snapshots, fmt.Errorf("error getting list of snapshots from digital ocean: %v", err)
+	}
+
+	for _, image := range images {
+		createdAt, _ := time.Parse(time.RFC3339, image.Created)
+		snapshots = append(snapshots, Snapshot{
+			Image:     strconv.Itoa(image.ID),
+			Name:      image.Name,
+			CreatedAt: createdAt,
+			SizeGB:    image.SizeGigaBytes,
+		})
+	}
+	return snapshots, nil
+}
+
+// DigitalOceanRebuildServer reimages a droplet in place and waits for the rebuild to finish.
+// The droplet keeps the SSH keys it was created with, which includes the root key.
+func (m *Repository) DigitalOceanRebuildServer(providerID, image string) error {
+	dropletID, err := strconv.Atoi(providerID)
+	if err != nil {
+		return fmt.Errorf("invalid droplet ID %q: %v", providerID, err)
+	}
+
+	apiKey, err := m.DB.GetSecret("digitalocean")
+	if err != nil {
+		return fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	client := godo.NewFromToken(apiKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	var action *godo.Action
+	// Snapshots are referenced by their numeric image ID rather than a slug
+	if imageID, convErr := strconv.Atoi(image); convErr == nil {
+		action, _, err = client.DropletActions.RebuildByImageID(ctx, dropletID, imageID)
+	} else {
+		action, _, err = client.DropletActions.RebuildByImageSlug(ctx, dropletID, image)
+	}
+	if err != nil {
+		return fmt.Errorf("error rebuilding droplet on digital ocean: %v", err)
+	}
+
+	for action.Status == godo.ActionInProgress {
+		time.Sleep(10 * time.Second)
+
+		action, _, err = client.DropletActions.Get(ctx, dropletID, action.ID)
+		if err != nil {
+			return fmt.Errorf("error getting droplet action from digital ocean: %v", err)
+		}
+	}
+
+	if action.Status != godo.ActionCompleted {
+		return fmt.Errorf("droplet rebuild did not complete: %s", action.Status)
+	}
+	return nil
+}
+
+// digitalOceanReservedIP converts a Digital Ocean reserved IP into a ReservedIP
+func digitalOceanReservedIP(ip godo.ReservedIP) ReservedIP {
+	reserved := ReservedIP{Address: ip.IP}
+	if ip.Region != nil {
+		reserved.Region = ip.Region.Slug
+	}
+	if ip.Droplet != nil {
+		reserved.ServerProviderID = strconv.Itoa(ip.Droplet.ID)
+	}
+	return reserved
+}
+
+// DigitalOceanListIPs lists the reserved IPs on the Digital Ocean account, only those in region if it is set.
+// Reserved IPs cannot be tagged, so this includes any not created by GoBoxer.
+func (m *Repository) DigitalOceanListIPs(region string) ([]ReservedIP, error) {
+	var ips []ReservedIP
+
+	apiKey, err := m.DB.GetSecret("digitalocean")
+	if err != nil {
+		return ips, fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	client := godo.NewFromToken(apiKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	opt := &godo.ListOptions{PerPage: 200}
+	for {
+		reservedIPs, resp, err := client.ReservedIPs.List(ctx, opt)
+		if err != nil {
+			return ips, fmt.Errorf("error listing reserved IPs from digital ocean: %v", err)
+		}
+
+		for _, ip := range reservedIPs {
+			reserved := digitalOceanReservedIP(ip)
+			if region == "" || reserved.Region == region {
+				ips = append(ips, reserved)
+			}
+		}
+
+		if resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+
+		page, err := resp.Links.CurrentPage()
+		if err != nil {
+			return ips, fmt.Errorf("error with pages listing digital ocean reserved IPs: %v", err)
+		}
+		opt.Page = page + 1
+	}
+	return ips, nil
+}
+
+// DigitalOceanAllocateIP reserves a new IP assigned to a droplet and waits until the assignment is complete
+func (m *Repository) DigitalOceanAllocateIP(providerID string) (ReservedIP, error) {
+	dropletID, err := strconv.Atoi(providerID)
+	if err != nil {
+		return ReservedIP{}, fmt.Errorf("invalid droplet ID %q: %v", providerID, err)
+	}
+
+	apiKey, err := m.DB.GetSecret("digitalocean")
+	if err != nil {
+		return ReservedIP{}, fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	client := godo.NewFromToken(apiKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	ip, _, err := client.ReservedIPs.Create(ctx, &godo.ReservedIPCreateRequest{DropletID: dropletID})
+	if err != nil {
+		return ReservedIP{}, fmt.Errorf("error creating reserved IP on digital ocean: %v", err)
+	}
+
+	// The IP is returned straight away and assigned to the droplet in the background
+	for ip.Droplet == nil {
+		time.Sleep(5 * time.Second)
+
+		ip, _, err = client.ReservedIPs.Get(ctx, ip.IP)
+		if err != nil {
+			return ReservedIP{}, fmt.Errorf("error getting reserved IP from digital ocean: %v", err)
+		}
+	}
+
+	return digitalOceanReservedIP(*ip), nil
+}
+
+// digitalOceanWaitIPAction polls a reserved IP action until it is no longer in progress
+func digitalOceanWaitIPAction(ctx context.Context, client *godo.Client, address string, action *godo.Action) error {
+	var err error
+	for action.Status == godo.ActionInProgress {
+		time.Sleep(5 * time.Second)
+
+		action, _, err = client.ReservedIPActions.Get(ctx, address, action.ID)
+		if err != nil {
+			return fmt.Errorf("error getting reserved IP action from digital ocean: %v", err)
+		}
+	}
+
+	if action.Status != godo.ActionCompleted {
+		return fmt.Errorf("reserved IP %s did not complete: %s", action.Type, action.Status)
+	}
+	return nil
+}
+
+// DigitalOceanAttachIP assigns a reserved IP to a droplet, moving it from any droplet it is assigned to
+func (m *Repository) DigitalOceanAttachIP(providerID, address string) error {
+	dropletID, err := strconv.Atoi(providerID)
+	if err != nil {
+		return fmt.Errorf("invalid droplet ID %q: %v", providerID, err)
+	}
+
+	apiKey, err := m.DB.GetSecret("digitalocean")
+	if err != nil {
+		return fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	client := godo.NewFromToken(apiKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	action, _, err := client.ReservedIPActions.Assign(ctx, address, dropletID)
+	if err != nil {
+		return fmt.Errorf("error assigning reserved IP on digital ocean: %v", err)
+	}
+	return digitalOceanWaitIPAction(ctx, client, address, action)
+}
+
+// DigitalOceanDetachIP unassigns a reserved IP from its droplet
+func (m *Repository) DigitalOceanDetachIP(address string) error {
+	apiKey, err := m.DB.GetSecret("digitalocean")
+	if err != nil {
+		return fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	client := godo.NewFromToken(apiKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	action, _, err := client.ReservedIPActions.Unassign(ctx, address)
+	if err != nil {
+		return fmt.Errorf("error unassigning reserved IP on digital ocean: %v", err)
+	}
+	return digitalOceanWaitIPAction(ctx, client, address, action)
+}
+
+// DigitalOceanReleaseIP deletes a reserved IP from the Digital Ocean account
+func (m *Repository) DigitalOceanReleaseIP(address string) error {
+	apiKey, err := m.DB.GetSecret("digitalocean")
+	if err != nil {
+		return fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	client := godo.NewFromToken(apiKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	if _, err := client.ReservedIPs.Delete(ctx, address); err != nil {
+		return fmt.Errorf("error deleting reserved IP on digital ocean: %v", err)
+	}
+	return nil
+}
+
+// digitalOceanFirewall returns the firewall GoBoxer manages for a droplet, or nil if it has none
+func digitalOceanFirewall(ctx context.Context, client *godo.Client, server models.Server, dropletID int) (*godo.Firewall, error) {
+	firewalls, _, err := client.Firewalls.ListByDroplet(ctx, dropletID, &godo.ListOptions{PerPage: 200})
+	if err != nil {
+		return nil, fmt.Errorf("error listing firewalls from digital ocean: %v", err)
+	}
+
+	for i := range firewalls {
+		if firewalls[i].Name == firewallName(server) {
+			return &firewalls[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// DigitalOceanApplyFirewall creates or updates the cloud firewall for a droplet. Outbound traffic is
+// allowed, as a Digital Ocean firewall without outbound rules blocks it.
+func (m *Repository) DigitalOceanApplyFirewall(server models.Server, rules []models.FirewallRule) error {
+	dropletID, err := strconv.Atoi(server.ProviderID)
+	if err != nil {
+		return fmt.Errorf("invalid droplet ID %q: %v", server.ProviderID, err)
+	}
+
+	apiKey, err := m.DB.GetSecret("digitalocean")
+	if err != nil {
+		return fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	client := godo.NewFromToken(apiKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	request := &godo.FirewallRequest{
+		Name:       firewallName(server),
+		DropletIDs: []int{dropletID},
+		Tags:       []string{},
+	}
+	for _, rule := range rules {
+		ports := rule.Ports
+		if ports == "" && rule.Protocol != "icmp" {
+			ports = "all"
+		}
+		request.InboundRules = append(request.InboundRules, godo.InboundRule{
+			Protocol:  rule.Protocol,
+			PortRange: ports,
+			Sources:   &godo.Sources{Addresses: rule.Sources},
+		})
+	}
+	for _, protocol := range []string{"tcp", "udp", "icmp"} {
+		ports := "all"
+		if protocol == "icmp" {
+			ports = ""
+		}
+		request.OutboundRules = append(request.OutboundRules, godo.OutboundRule{
+			Protocol:     protocol,
+			PortRange:    ports,
+			Destinations: &godo.Destinations{Addresses: anywhere},
+		})
+	}
+
+	existing, err := digitalOceanFirewall(ctx, client, server, dropletID)
+	if err != nil {
+		return err
+	}
+
+	if existing != nil {
+		_, _, err = client.Firewalls.Update(ctx, existing.ID, request)
+	} else {
+		_, _, err = client.Firewalls.Create(ctx, request)
+	}
+	if err != nil {
+		return fmt.Errorf("error applying firewall on digital ocean: %v", err)
+	}
+	return nil
+}
+
+// DigitalOceanRemoveFirewall deletes the cloud firewall for a droplet
+func (m *Repository) DigitalOceanRemoveFirewall(server models.Server) error {
+	dropletID, err := strconv.Atoi(server.ProviderID)
+	if err != nil {
+		return fmt.Errorf("invalid droplet ID %q: %v", server.ProviderID, err)
+	}
+
+	apiKey, err := m.DB.GetSecret("digitalocean")
+	if err != nil {
+		return fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	client := godo.NewFromToken(apiKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	existing, err := digitalOceanFirewall(ctx, client, server, dropletID)
+	if err != nil || existing == nil {
+		return err
+	}
+
+	if _, err := client.Firewalls.Delete(ctx, existing.ID); err != nil {
+		return fmt.Errorf("error deleting firewall on digital ocean: %v", err)
+	}
+	return nil
+}
+
+// DigitalOceanGetServer fetches the current state of a droplet from Digital Ocean
+func (m *Repository) DigitalOceanGetServer(server models.Server) (models.Server, error) {
+	apiKey, err := m.DB.GetSecret("digitalocean")
+	if err != nil {
+		return server, fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	dropletID, err := strconv.Atoi(server.ProviderID)
+	if err != nil {
+		return server, fmt.Errorf("invalid droplet ID %q: %v", server.ProviderID, err)
+	}
+
+	client := godo.NewFromToken(apiKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	droplet, _, err := client.Droplets.Get(ctx, dropletID)
+	if err != nil {
+		return server, fmt.Errorf("error getting droplet from digital ocean: %v", err)
+	}
+
+	if ip, err := droplet.PublicIPv4(); err == nil && ip != "" {
+		server.IP = ip
+	}
+	server.Status = droplet.Status
+	return server, nil
+}
+
 // DigitalOceanDeleteServer deletes a server from digital ocean based on serverID
-func (m *Repository) DigitalOceanDeleteServer(serverID int) error {
+func (m *Repository) DigitalOceanDeleteServer(providerID string) error {
+	// Servers that never reached the provider have nothing to delete
+	if providerID == "" {
+		return nil
+	}
+
+	serverID, err := strconv.Atoi(providerID)
+	if err != nil {
+		return fmt.Errorf("invalid droplet ID %q: %v", providerID, err)
+	}
+
 	apiKey, err := m.DB.GetSecret("digitalocean")
 	if err != nil {
 		return fmt.Errorf("error getting secrets from database: %v", err)
@@ -0,0 +1,375 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
+
+func init() {
+	RegisterProvider(&docker{})
+}
+
+// docker implements CloudProvider using containers running sshd on a local Docker
+// engine, so full builds can be rehearsed without paying for infrastructure.
+// The image is built from docker/sshd in the repository.
+type docker struct{}
+
+func (p *docker) Name() string        { return "docker" }
+func (p *docker) DisplayName() string { return "Docker (local)" }
+
+func (p *docker) Secrets() []Secret {
+	return []Secret{{Name: "dockerhost", Label: "Docker host, e.g. unix:///var/run/docker.sock"}}
+}
+
+func (p *docker) Capabilities() Capabilities {
+	return Capabilities{}
+}
+
+func (p *docker) Catalog() (Catalog, error) {
+	return Repo.DockerCatalog()
+}
+
+func (p *docker) CreateServer(server models.Server) (models.Server, error) {
+	return Repo.DockerCreateServer(server)
+}
+
+func (p *docker) GetServer(server models.Server) (models.Server, error) {
+	return Repo.DockerGetServer(server)
+}
+
+func (p *docker) ListServers() ([]models.Server, error) {
+	return Repo.DockerRefreshVPS()
+}
+
+func (p *docker) DeleteServer(server models.Server) error {
+	return Repo.DockerDeleteServer(server.ProviderID)
+}
+
+func (p *docker) DeleteAll() error {
+	return Repo.DockerDeleteAll()
+}
+
+func (p *docker) Power(server models.Server, action PowerAction) error {
+	return ErrNotSupported
+}
+
+const (
+	// dockerAPIVersion is the oldest engine API version with the features used here
+	dockerAPIVersion = "v1.41"
+	// dockerImageLabel marks images that can be used as servers, its value is the image description
+	dockerImageLabel = "goboxer.sshd"
+	// dockerDefaultImage is the tag used in the build instructions for docker/sshd
+	dockerDefaultImage = "goboxer/sshd"
+)
+
+// dockerSizes are resource limits applied to containers to mimic plan sizes
+var dockerSizes = map[string]struct {
+	Name     string
+	NanoCPUs int64
+	Memory   int64
+}{
+	"small":  {Name: "Small - 1 CPU, 1 GB", NanoCPUs: 1e9, Memory: 1 << 30},
+	"medium": {Name: "Medium - 2 CPU, 2 GB", NanoCPUs: 2e9, Memory: 2 << 30},
+	"large":  {Name: "Large - 4 CPU, 4 GB", NanoCPUs: 4e9, Memory: 4 << 30},
+}
+
+type dockerContainer struct {
+	ID     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	State  string            `json:"State"`
+	Labels map[string]string `json:"Labels"`
+
+	NetworkSettings struct {
+		Networks map[string]struct {
+			IPAddress string `json:"IPAddress"`
+		} `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
+
+// dockerClient returns an engine API client for the Docker host in the secrets table,
+// which can be a unix socket or a tcp address
+func (m *Repository) dockerClient() (*restClient, error) {
+	host, err := m.DB.GetSecret("dockerhost")
+	if err != nil {
+		return nil, fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	if host == "" {
+		return nil, errors.New("docker host not found in secrets")
+	}
+
+	hostURL, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker host %q: %v", host, err)
+	}
+
+	switch hostURL.Scheme {
+	case "unix":
+		socket := hostURL.Path
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		}
+		return &restClient{
+			BaseURL: "http://docker/" + dockerAPIVersion,
+			Client:  &http.Client{Transport: transport, Timeout: 5 * time.Minute},
+		}, nil
+	case "tcp", "http":
+		return &restClient{
+			BaseURL: "http://" + hostURL.Host + "/" + dockerAPIVersion,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported docker host scheme: %s", hostURL.Scheme)
+	}
+}
+
+// dockerLabelFilter returns a filters query parameter matching the given label
+func dockerLabelFilter(label string) string {
+	return url.QueryEscape(fmt.Sprintf(`{"label":["%s"]}`, label))
+}
+
+// dockerServerFromContainer converts a container into a server
+func dockerServerFromContainer(container dockerContainer) models.Server {
+	server := models.Server{
+		Provider:   "docker",
+		ProviderID: container.ID,
+		Status:     container.State,
+	}
+	if len(container.Names) > 0 {
+		server.Name = strings.TrimPrefix(container.Names[0], "/")
+	}
+	for network, settings := range container.NetworkSettings.Networks {
+		server.Region = network
+		server.IP = settings.IPAddress
+		break
+	}
+	return server
+}
+
+// DockerCreateServer starts a container running sshd with the root SSH key and returns
+// the server object with the container's IP address on the chosen network
+func (m *Repository) DockerCreateServer(server models.Server) (models.Server, error) {
+	client, err := m.dockerClient()
+	if err != nil {
+		return server, err
+	}
+
+	publicKey, err := m.DB.GetSecret("sshkey")
+	if err != nil {
+		return server, fmt.Errorf("error getting SSH key from database: %v", err)
+	}
+
+	network := server.Region
+	if network == "" {
+		network = "bridge"
+	}
+
+	image := server.Image
+	if image == "" {
+		image = dockerDefaultImage
+	}
+
+	sizeSlug := server.Size
+	if sizeSlug == "" {
+		sizeSlug = "small"
+	}
+	size, ok := dockerSizes[sizeSlug]
+	if !ok {
+		return server, fmt.Errorf("unsupported size: %s", sizeSlug)
+	}
+
+	labels := make(map[string]string)
+	for _, tag := range Tags {
+		labels[tag] = ""
+	}
+
+	createRequest := map[string]interface{}{
+		"Image":    image,
+		"Hostname": server.Name,
+		"Env":      []string{"SSH_PUBLIC_KEY=" + strings.TrimSpace(publicKey)},
+		"Labels":   labels,
+		"HostConfig": map[string]interface{}{
+			"NetworkMode": network,
+			"NanoCpus":    size.NanoCPUs,
+			"Memory":      size.Memory,
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := client.do(ctx, http.MethodPost, "/containers/create?name="+url.QueryEscape(server.Name), createRequest, &created); err != nil {
+		return server, fmt.Errorf("error creating docker container: %v", err)
+	}
+	server.ProviderID = created.ID
+
+	if err := client.do(ctx, http.MethodPost, "/containers/"+created.ID+"/start", nil, nil); err != nil {
+		return server, fmt.Errorf("error starting docker container: %v", err)
+	}
+
+	for attempt := 0; attempt < 12; attempt++ {
+		current, err := m.DockerGetServer(server)
+		if err != nil {
+			return server, err
+		}
+		if current.Status == "running" && current.IP != "" {
+			return current, nil
+		}
+		time.Sleep(1 * time.Second)
+	}
+
+	return server, errors.New("failed to obtain IP address for the docker container")
+}
+
+// DockerCatalog lists the networks and sshd images available on the Docker host
+func (m *Repository) DockerCatalog() (Catalog, error) {
+	var catalog Catalog
+
+	client, err := m.dockerClient()
+	if err != nil {
+		return catalog, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	var networks []struct {
+		Name   string `json:"Name"`
+		Driver string `json:"Driver"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/networks", nil, &networks); err != nil {
+		return catalog, fmt.Errorf("error getting list of networks from docker: %v", err)
+	}
+	for _, network := range networks {
+		if network.Driver == "bridge" {
+			catalog.Regions = append(catalog.Regions, CatalogItem{Slug: network.Name, Name: network.Name})
+		}
+	}
+
+	for _, slug := range []string{"small", "medium", "large"} {
+		catalog.Sizes = append(catalog.Sizes, CatalogItem{Slug: slug, Name: dockerSizes[slug].Name})
+	}
+
+	var images []struct {
+		RepoTags []string          `json:"RepoTags"`
+		Labels   map[string]string `json:"Labels"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/images/json?filters="+dockerLabelFilter(dockerImageLabel), nil, &images); err != nil {
+		return catalog, fmt.Errorf("error getting list of images from docker: %v", err)
+	}
+	for _, image := range images {
+		for _, tag := range image.RepoTags {
+			catalog.Images = append(catalog.Images, CatalogItem{
+				Slug: tag,
+				Name: fmt.Sprintf("%s (%s)", image.Labels[dockerImageLabel], tag),
+			})
+		}
+	}
+
+	return catalog, nil
+}
+
+// DockerGetServer fetches the current state of a container
+func (m *Repository) DockerGetServer(server models.Server) (models.Server, error) {
+	client, err := m.dockerClient()
+	if err != nil {
+		return server, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	var inspect struct {
+		Name  string `json:"Name"`
+		State struct {
+			Status string `json:"Status"`
+		} `json:"State"`
+		NetworkSettings struct {
+			Networks map[string]struct {
+				IPAddress string `json:"IPAddress"`
+			} `json:"Networks"`
+		} `json:"NetworkSettings"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/containers/"+server.ProviderID+"/json", nil, &inspect); err != nil {
+		return server, fmt.Errorf("error getting container from docker: %v", err)
+	}
+
+	server.Status = inspect.State.Status
+	for network, settings := range inspect.NetworkSettings.Networks {
+		if server.Region == "" || server.Region == network {
+			if settings.IPAddress != "" {
+				server.IP = settings.IPAddress
+			}
+			break
+		}
+	}
+	return server, nil
+}
+
+// DockerRefreshVPS returns the containers on the Docker host labelled with the application tag
+func (m *Repository) DockerRefreshVPS() ([]models.Server, error) {
+	servers := []models.Server{}
+
+	client, err := m.dockerClient()
+	if err != nil {
+		return servers, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	var containers []dockerContainer
+	if err := client.do(ctx, http.MethodGet, "/containers/json?all=true&filters="+dockerLabelFilter(Tags[0]), nil, &containers); err != nil {
+		return servers, fmt.Errorf("error getting list of containers from docker: %v", err)
+	}
+
+	for _, container := range containers {
+		servers = append(servers, dockerServerFromContainer(container))
+	}
+	return servers, nil
+}
+
+// DockerDeleteServer stops and removes a container
+func (m *Repository) DockerDeleteServer(containerID string) error {
+	client, err := m.dockerClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	// A container already removed outside GoBoxer counts as deleted
+	if err := client.do(ctx, http.MethodDelete, "/containers/"+containerID+"?force=true", nil, nil); err != nil && !isNotFound(err) {
+		return fmt.Errorf("error deleting docker container: %v", err)
+	}
+	return nil
+}
+
+// DockerDeleteAll removes all containers labelled with the application tag
+func (m *Repository) DockerDeleteAll() error {
+	servers, err := m.DockerRefreshVPS()
+	if err != nil {
+		return err
+	}
+
+	for _, vps := range servers {
+		if err := m.DockerDeleteServer(vps.ProviderID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
+
+// anywhere are the sources used for a firewall rule which does not list any
+var anywhere = []string{"0.0.0.0/0", "::/0"}
+
+// Firewaller is implemented by providers with a cloud firewall. Each server gets its own
+// provider firewall, named after its provider ID, holding the rules of its firewall policy.
+type Firewaller interface {
+	// ApplyFirewall creates or updates the server's firewall with rules and attaches it to the server
+	ApplyFirewall(server models.Server, rules []models.FirewallRule) error
+	// RemoveFirewall deletes the server's firewall, returning the server to the provider's default inbound rules
+	RemoveFirewall(server models.Server) error
+}
+
+// firewallName is the name of the provider firewall GoBoxer manages for a server
+func firewallName(server models.Server) string {
+	return fmt.Sprintf("%s-%s", Tags[0], server.ProviderID)
+}
+
+// ParseFirewallRules reads firewall rules written one per line as "protocol ports sources", for example
+// "tcp 443 0.0.0.0/0,::/0" or "tcp 50050 203.0.113.10". Ports may be a single port, a range or "all", and
+// are left out for icmp. Sources may be separated by commas or spaces and default to anywhere.
+// Blank lines and lines starting with # are ignored.
+func ParseFirewallRules(text string) ([]models.FirewallRule, error) {
+	var rules []models.FirewallRule
+
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(strings.ReplaceAll(line, ",", " "))
+		rule := models.FirewallRule{Protocol: strings.ToLower(fields[0])}
+		fields = fields[1:]
+
+		switch rule.Protocol {
+		case "tcp", "udp":
+			if len(fields) == 0 {
+				return nil, fmt.Errorf("line %d: %s rule needs a port, a range or all", i+1, rule.Protocol)
+			}
+			ports, err := parsePorts(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", i+1, err)
+			}
+			rule.Ports = ports
+			fields = fields[1:]
+		case "icmp":
+		default:
+			return nil, fmt.Errorf("line %d: unknown protocol %q, use tcp, udp or icmp", i+1, rule.Protocol)
+		}
+
+		for _, source := range fields {
+			cidr, err := parseSource(source)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", i+1, err)
+			}
+			rule.Sources = append(rule.Sources, cidr)
+		}
+		if len(rule.Sources) == 0 {
+			rule.Sources = append(rule.Sources, anywhere...)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// parsePorts validates a port or port range, returning an empty string for all ports
+func parsePorts(ports string) (string, error) {
+	if strings.EqualFold(ports, "all") {
+		return "", nil
+	}
+
+	bounds := strings.SplitN(ports, "-", 2)
+	var previous int
+	for _, bound := range bounds {
+		port, err := strconv.Atoi(bound)
+		if err != nil || port < 1 || port > 65535 || port < previous {
+			return "", fmt.Errorf("invalid port or port range %q", ports)
+		}
+		previous = port
+	}
+	return ports, nil
+}
+
+// parseSource converts an address or CIDR range into a CIDR range, as some providers only accept ranges
+func parseSource(source string) (string, error) {
+	if _, network, err := net.ParseCIDR(source); err == nil {
+		return network.String(), nil
+	}
+
+	ip := net.ParseIP(source)
+	if ip == nil {
+		return "", fmt.Errorf("invalid source address %q", source)
+	}
+	if ip.To4() != nil {
+		return ip.String() + "/32", nil
+	}
+	return ip.String() + "/128", nil
+}
+
+// FormatFirewallRules writes firewall rules in the format read by ParseFirewallRules
+func FormatFirewallRules(rules []models.FirewallRule) string {
+	var lines []string
+	for _, rule := range rules {
+		fields := []string{rule.Protocol}
+		if rule.Protocol != "icmp" {
+			ports := rule.Ports
+			if ports == "" {
+				ports = "all"
+			}
+			fields = append(fields, ports)
+		}
+		fields = append(fields, strings.Join(rule.Sources, ","))
+		lines = append(lines, strings.Join(fields, " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// AllowsSSH reports whether any rule lets traffic reach port 22, which Ansible needs to provision the server
+func AllowsSSH(rules []models.FirewallRule) bool {
+	for _, rule := range rules {
+		if rule.Protocol != "tcp" {
+			continue
+		}
+		if rule.Ports == "" {
+			return true
+		}
+
+		bounds := strings.SplitN(rule.Ports, "-", 2)
+		low, _ := strconv.Atoi(bounds[0])
+		high := low
+		if len(bounds) == 2 {
+			high, _ = strconv.Atoi(bounds[1])
+		}
+		if low <= 22 && high >= 22 {
+			return true
+		}
+	}
+	return false
+}
+
+// splitSources separates IPv4 and IPv6 CIDR ranges
+func splitSources(sources []string) ([]string, []string) {
+	var ipv4, ipv6 []string
+	for _, source := range sources {
+		if strings.Contains(source, ":") {
+			ipv6 = append(ipv6, source)
+		} else {
+			ipv4 = append(ipv4, source)
+		}
+	}
+	return ipv4, ipv6
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
+
+func TestParseFirewallRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    []models.FirewallRule
+		wantErr bool
+	}{
+		{
+			name: "single port from anywhere",
+			text: "tcp 443",
+			want: []models.FirewallRule{{Protocol: "tcp", Ports: "443", Sources: []string{"0.0.0.0/0", "::/0"}}},
+		},
+		{
+			name: "sources separated by commas and spaces",
+			text: "tcp 50050 203.0.113.10,198.51.100.0/24 2001:db8::1",
+			want: []models.FirewallRule{{Protocol: "tcp", Ports: "50050", Sources: []string{"203.0.113.10/32", "198.51.100.0/24", "2001:db8::1/128"}}},
+		},
+		{
+			name: "range, all ports and icmp",
+			text: "UDP 8000-8100 10.0.0.0/8\ntcp all 192.0.2.0/24\nicmp",
+			want: []models.FirewallRule{
+				{Protocol: "udp", Ports: "8000-8100", Sources: []string{"10.0.0.0/8"}},
+				{Protocol: "tcp", Ports: "", Sources: []string{"192.0.2.0/24"}},
+				{Protocol: "icmp", Sources: []string{"0.0.0.0/0", "::/0"}},
+			},
+		},
+		{
+			name: "source normalised to its network",
+			text: "tcp 22 192.0.2.17/24",
+			want: []models.FirewallRule{{Protocol: "tcp", Ports: "22", Sources: []string{"192.0.2.0/24"}}},
+		},
+		{
+			name: "comments and blank lines",
+			text: "# management\n\n  tcp 22  \n",
+			want: []models.FirewallRule{{Protocol: "tcp", Ports: "22", Sources: []string{"0.0.0.0/0", "::/0"}}},
+		},
+		{
+			name: "no rules",
+			text: "",
+		},
+		{name: "missing port", text: "tcp", wantErr: true},
+		{name: "unknown protocol", text: "gre 22", wantErr: true},
+		{name: "port out of range", text: "tcp 65536", wantErr: true},
+		{name: "reversed range", text: "tcp 9000-8000", wantErr: true},
+		{name: "invalid source", text: "tcp 22 example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFirewallRules(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFirewallRules(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFirewallRules(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFirewallRulesRoundTrip(t *testing.T) {
+	text := "tcp 22 203.0.113.10/32\nudp 8000-8100\nicmp 10.0.0.0/8"
+	rules, err := ParseFirewallRules(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := ParseFirewallRules(FormatFirewallRules(rules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again, rules) {
+		t.Errorf("formatted rules parsed as %+v, want %+v", again, rules)
+	}
+}
@@ -0,0 +1,437 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
+
+func init() {
+	RegisterProvider(&hetzner{})
+}
+
+// hetzner implements CloudProvider for Hetzner Cloud servers
+type hetzner struct{}
+
+func (p *hetzner) Name() string        { return "hetzner" }
+func (p *hetzner) DisplayName() string { return "Hetzner" }
+
+func (p *hetzner) Secrets() []Secret {
+	return []Secret{{Name: "hetzner", Label: "Hetzner"}}
+}
+
+func (p *hetzner) Capabilities() Capabilities {
+	return Capabilities{UserData: true}
+}
+
+func (p *hetzner) Catalog() (Catalog, error) {
+	return Repo.HetznerCatalog()
+}
+
+func (p *hetzner) CreateServer(server models.Server) (models.Server, error) {
+	return Repo.HetznerCreateServer(server)
+}
+
+func (p *hetzner) GetServer(server models.Server) (models.Server, error) {
+	return Repo.HetznerGetServer(server)
+}
+
+func (p *hetzner) ListServers() ([]models.Server, error) {
+	return Repo.HetznerRefreshVPS()
+}
+
+func (p *hetzner) DeleteServer(server models.Server) error {
+	return Repo.HetznerDeleteServer(server.ProviderID)
+}
+
+func (p *hetzner) DeleteAll() error {
+	return Repo.HetznerDeleteAll()
+}
+
+func (p *hetzner) Power(server models.Server, action PowerAction) error {
+	return ErrNotSupported
+}
+
+func (p *hetzner) RegisterSSHKey() error {
+	return Repo.AddSSHKeyToHetzner()
+}
+
+func (p *hetzner) ListAllServers() ([]models.Server, error) {
+	return Repo.HetznerListAll()
+}
+
+func (p *hetzner) TagServer(server models.Server) error {
+	return Repo.HetznerTagServer(server)
+}
+
+// hetznerSSHKeyName is the name the root SSH key is registered under in Hetzner
+const hetznerSSHKeyName = "Root Key"
+
+type hetznerServer struct {
+	ID        int64             `json:"id"`
+	Name      string            `json:"name"`
+	Status    string            `json:"status"`
+	Labels    map[string]string `json:"labels"`
+	PublicNet struct {
+		IPv4 struct {
+			IP string `json:"ip"`
+		} `json:"ipv4"`
+	} `json:"public_net"`
+}
+
+type hetznerMeta struct {
+	Pagination struct {
+		NextPage int `json:"next_page"`
+	} `json:"pagination"`
+}
+
+// hetznerClient returns an API client for Hetzner Cloud using the key from the secrets table
+func (m *Repository) hetznerClient() (*restClient, error) {
+	apiKey, err := m.DB.GetSecret("hetzner")
+	if err != nil {
+		return nil, fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	if apiKey == "" {
+		return nil, errors.New("hetzner API key not found in secrets")
+	}
+
+	return &restClient{
+		BaseURL: "https://api.hetzner.cloud/v1",
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+	}, nil
+}
+
+// HetznerCreateServer creates a server on Hetzner Cloud and returns the server object with the IP address
+func (m *Repository) HetznerCreateServer(server models.Server) (models.Server, error) {
+	client, err := m.hetznerClient()
+	if err != nil {
+		return server, err
+	}
+
+	location := server.Region
+	if location == "" {
+		location = "fsn1"
+	}
+
+	serverType := server.Size
+	if serverType == "" {
+		serverType = "cx22"
+	}
+
+	image := server.Image
+	if image == "" {
+		image = "ubuntu-22.04"
+	}
+
+	labels := make(map[string]string)
+	for _, tag := range Tags {
+		labels[tag] = ""
+	}
+
+	createRequest := map[string]interface{}{
+		"name":        server.Name,
+		"server_type": serverType,
+		"image":       image,
+		"location":    location,
+		"ssh_keys":    []string{hetznerSSHKeyName},
+		"labels":      labels,
+	}
+	if server.UserData != "" {
+		createRequest["user_data"] = server.UserData
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	var created struct {
+		Server hetznerServer `json:"server"`
+	}
+	if err := client.do(ctx, http.MethodPost, "/servers", createRequest, &created); err != nil {
+		return server, fmt.Errorf("error creating server on hetzner: %v", err)
+	}
+
+	server.ProviderID = strconv.FormatInt(created.Server.ID, 10)
+
+	// Poll until the server is running
+	for attempt := 0; attempt < 24; attempt++ {
+		var result struct {
+			Server hetznerServer `json:"server"`
+		}
+		if err := client.do(ctx, http.MethodGet, "/servers/"+server.ProviderID, nil, &result); err != nil {
+			return server, fmt.Errorf("error getting server from hetzner: %v", err)
+		}
+		if result.Server.Status == "running" && result.Server.PublicNet.IPv4.IP != "" {
+			server.IP = result.Server.PublicNet.IPv4.IP
+			return server, nil
+		}
+		time.Sleep(5 * time.Second)
+	}
+
+	return server, errors.New("failed to obtain public IP address for the hetzner server")
+}
+
+// HetznerCatalog lists the locations, server types and system images available on Hetzner Cloud
+func (m *Repository) HetznerCatalog() (Catalog, error) {
+	var catalog Catalog
+
+	client, err := m.hetznerClient()
+	if err != nil {
+		return catalog, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	var locations struct {
+		Locations []struct {
+			Name        string `json:"name"`
+			Description string `json:"description"`
+		} `json:"locations"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/locations", nil, &locations); err != nil {
+		return catalog, fmt.Errorf("error getting list of locations from hetzner: %v", err)
+	}
+	for _, location := range locations.Locations {
+		catalog.Regions = append(catalog.Regions, CatalogItem{Slug: location.Name, Name: location.Description})
+	}
+
+	var serverTypes struct {
+		ServerTypes []struct {
+			Name        string  `json:"name"`
+			Description string  `json:"description"`
+			Cores       int     `json:"cores"`
+			Memory      float64 `json:"memory"`
+			Deprecated  bool    `json:"deprecated"`
+			Prices      []struct {
+				PriceHourly struct {
+					Gross string `json:"gross"`
+				} `json:"price_hourly"`
+				PriceMonthly struct {
+					Gross string `json:"gross"`
+				} `json:"price_monthly"`
+			} `json:"prices"`
+		} `json:"server_types"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/server_types?per_page=50", nil, &serverTypes); err != nil {
+		return catalog, fmt.Errorf("error getting list of server types from hetzner: %v", err)
+	}
+	for _, serverType := range serverTypes.ServerTypes {
+		if serverType.Deprecated {
+			continue
+		}
+		item := CatalogItem{
+			Slug: serverType.Name,
+			Name: fmt.Sprintf("%s - %d vCPU, %.0f GB", serverType.Name, serverType.Cores, serverType.Memory),
+		}
+		// Prices vary slightly by location, use the first listed as a guide
+		if len(serverType.Prices) > 0 {
+			item.PriceHourly, _ = strconv.ParseFloat(serverType.Prices[0].PriceHourly.Gross, 64)
+			monthly, _ := strconv.ParseFloat(serverType.Prices[0].PriceMonthly.Gross, 64)
+			item.Name = fmt.Sprintf("%s, €%.2f/mo", item.Name, monthly)
+		}
+		catalog.Sizes = append(catalog.Sizes, item)
+	}
+
+	var images struct {
+		Images []struct {
+			Name        string `json:"name"`
+			Description string `json:"description"`
+		} `json:"images"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/images?type=system&status=available&per_page=50", nil, &images); err != nil {
+		return catalog, fmt.Errorf("error getting list of images from hetzner: %v", err)
+	}
+	for _, image := range images.Images {
+		catalog.Images = append(catalog.Images, CatalogItem{Slug: image.Name, Name: image.Description})
+	}
+
+	return catalog, nil
+}
+
+// HetznerGetServer fetches the current state of a server from Hetzner Cloud
+func (m *Repository) HetznerGetServer(server models.Server) (models.Server, error) {
+	client, err := m.hetznerClient()
+	if err != nil {
+		return server, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	var result struct {
+		Server hetznerServer `json:"server"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/servers/"+server.ProviderID, nil, &result); err != nil {
+		return server, fmt.Errorf("error getting server from hetzner: %v", err)
+	}
+
+	if result.Server.PublicNet.IPv4.IP != "" {
+		server.IP = result.Server.PublicNet.IPv4.IP
+	}
+	server.Status = result.Server.Status
+	return server, nil
+}
+
+// HetznerRefreshVPS returns the list of servers on Hetzner Cloud labelled with the application tag
+func (m *Repository) HetznerRefreshVPS() ([]models.Server, error) {
+	return m.hetznerListServers(true)
+}
+
+// HetznerListAll returns every server on the Hetzner Cloud project, labelled or not
+func (m *Repository) HetznerListAll() ([]models.Server, error) {
+	return m.hetznerListServers(false)
+}
+
+// hetznerListServers lists servers on Hetzner Cloud, only those with the application label if tagged is set
+func (m *Repository) hetznerListServers(tagged bool) ([]models.Server, error) {
+	servers := []models.Server{}
+
+	client, err := m.hetznerClient()
+	if err != nil {
+		return servers, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	page := 1
+	for page != 0 {
+		var result struct {
+			Servers []hetznerServer `json:"servers"`
+			Meta    hetznerMeta     `json:"meta"`
+		}
+		path := fmt.Sprintf("/servers?per_page=50&page=%d", page)
+		if tagged {
+			path += "&label_selector=" + Tags[0]
+		}
+		if err := client.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+			return servers, fmt.Errorf("error getting list of servers from hetzner: %v", err)
+		}
+
+		for _, vps := range result.Servers {
+			servers = append(servers, models.Server{
+				Provider:   "hetzner",
+				Name:       vps.Name,
+				ProviderID: strconv.FormatInt(vps.ID, 10),
+				IP:         vps.PublicNet.IPv4.IP,
+				Status:     vps.Status,
+			})
+		}
+
+		page = result.Meta.Pagination.NextPage
+	}
+
+	return servers, nil
+}
+
+// HetznerTagServer adds the application label to an existing server so it is managed by GoBoxer
+func (m *Repository) HetznerTagServer(server models.Server) error {
+	client, err := m.hetznerClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	var result struct {
+		Server hetznerServer `json:"server"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/servers/"+server.ProviderID, nil, &result); err != nil {
+		return fmt.Errorf("error getting server from hetzner: %v", err)
+	}
+
+	labels := result.Server.Labels
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	for _, tag := range Tags {
+		labels[tag] = ""
+	}
+
+	updateRequest := map[string]interface{}{"labels": labels}
+	if err := client.do(ctx, http.MethodPut, "/servers/"+server.ProviderID, updateRequest, nil); err != nil {
+		return fmt.Errorf("error labelling server on hetzner: %v", err)
+	}
+	return nil
+}
+
+// HetznerDeleteServer deletes a server from Hetzner Cloud based on its provider ID
+func (m *Repository) HetznerDeleteServer(providerID string) error {
+	client, err := m.hetznerClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	// A server already deleted outside GoBoxer counts as deleted
+	if err := client.do(ctx, http.MethodDelete, "/servers/"+providerID, nil, nil); err != nil && !isNotFound(err) {
+		return fmt.Errorf("error deleting server from hetzner: %v", err)
+	}
+	return nil
+}
+
+// HetznerDeleteAll deletes all servers on Hetzner Cloud labelled with the application tag
+func (m *Repository) HetznerDeleteAll() error {
+	servers, err := m.HetznerRefreshVPS()
+	if err != nil {
+		return err
+	}
+
+	for _, vps := range servers {
+		if err := m.HetznerDeleteServer(vps.ProviderID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddSSHKeyToHetzner replaces the root SSH key registered in Hetzner Cloud with the current one
+func (m *Repository) AddSSHKeyToHetzner() error {
+	client, err := m.hetznerClient()
+	if err != nil {
+		return err
+	}
+
+	publicKey, err := m.DB.GetSecret("sshkey")
+	if err != nil {
+		return fmt.Errorf("error getting SSH key from database: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	var keys struct {
+		SSHKeys []struct {
+			ID int64 `json:"id"`
+		} `json:"ssh_keys"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/ssh_keys?name="+url.QueryEscape(hetznerSSHKeyName), nil, &keys); err != nil {
+		return fmt.Errorf("error getting list of keys from hetzner: %v", err)
+	}
+
+	for _, key := range keys.SSHKeys {
+		if err := client.do(ctx, http.MethodDelete, fmt.Sprintf("/ssh_keys/%d", key.ID), nil, nil); err != nil {
+			return fmt.Errorf("error deleting key from hetzner: %v", err)
+		}
+	}
+
+	createRequest := map[string]string{
+		"name":       hetznerSSHKeyName,
+		"public_key": publicKey,
+	}
+	if err := client.do(ctx, http.MethodPost, "/ssh_keys", createRequest, nil); err != nil {
+		return fmt.Errorf("error adding SSH key to hetzner: %v", err)
+	}
+
+	return nil
+}
@@ -2,32 +2,153 @@ package server
 
 import (
 	"context"
+	"encoding/base64"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/linode/linodego"
+	"github.com/nickzer0/GoBoxer/internal/credentials"
 	"github.com/nickzer0/GoBoxer/internal/models"
 	"golang.org/x/oauth2"
 )
 
-// LinodeCreateServer creates a VPS in Linode based on model
-func (m *Repository) LinodeCreateServer(server models.Server) (models.Server, error) {
-	var sshKeys []string
+func init() {
+	RegisterProvider(&linode{})
+}
 
-	apiKey, err := m.DB.GetSecret("linode")
+// linode implements CloudProvider for Linode instances
+type linode struct{}
+
+func (p *linode) Name() string        { return "linode" }
+func (p *linode) DisplayName() string { return "Linode" }
+
+func (p *linode) Secrets() []Secret {
+	return []Secret{{Name: "linode", Label: "Linode"}}
+}
+
+// Linode has no hard reset, a reboot is the closest equivalent
+func (p *linode) Capabilities() Capabilities {
+	return Capabilities{PowerActions: []PowerAction{PowerOn, Shutdown, Reboot}, UserData: true}
+}
+
+func (p *linode) Catalog() (Catalog, error) {
+	return Repo.LinodeCatalog()
+}
+
+func (p *linode) CreateServer(server models.Server) (models.Server, error) {
+	return Repo.LinodeCreateServer(server)
+}
+
+func (p *linode) GetServer(server models.Server) (models.Server, error) {
+	return Repo.LinodeGetServer(server)
+}
+
+func (p *linode) ListServers() ([]models.Server, error) {
+	return Repo.LinodeRefreshVPS()
+}
+
+func (p *linode) DeleteServer(server models.Server) error {
+	return Repo.LinodeDeleteServer(server.ProviderID)
+}
+
+func (p *linode) DeleteAll() error {
+	return Repo.LinodeDeleteAll()
+}
+
+func (p *linode) Power(server models.Server, action PowerAction) error {
+	return Repo.LinodePower(server.ProviderID, action)
+}
+
+func (p *linode) ListAllServers() ([]models.Server, error) {
+	return Repo.LinodeListAll()
+}
+
+func (p *linode) TagServer(server models.Server) error {
+	return Repo.LinodeTagServer(server)
+}
+
+func (p *linode) CreateSnapshot(server models.Server, name string) error {
+	return Repo.LinodeCreateSnapshot(server.ProviderID, name)
+}
+
+func (p *linode) ListSnapshots(server models.Server) ([]Snapshot, error) {
+	return Repo.LinodeListSnapshots(server.ProviderID)
+}
+
+func (p *linode) RebuildServer(server models.Server, image string) error {
+	return Repo.LinodeRebuildServer(server.ProviderID, image)
+}
+
+func (p *linode) ListIPs(region string) ([]ReservedIP, error) {
+	return Repo.LinodeListIPs(region)
+}
+
+func (p *linode) AllocateIP(server models.Server) (ReservedIP, error) {
+	return Repo.LinodeAllocateIP(server.ProviderID)
+}
+
+func (p *linode) AttachIP(server models.Server, ip ReservedIP) error {
+	return Repo.LinodeAttachIP(server, ip.Address)
+}
+
+// DetachIP is not supported as Linode addresses always belong to an instance
+func (p *linode) DetachIP(ip ReservedIP) error {
+	return ErrNotSupported
+}
+
+func (p *linode) ReleaseIP(ip ReservedIP) error {
+	return Repo.LinodeReleaseIP(ip.ServerProviderID, ip.Address)
+}
+
+func (p *linode) ApplyFirewall(server models.Server, rules []models.FirewallRule) error {
+	return Repo.LinodeApplyFirewall(server, rules)
+}
+
+func (p *linode) RemoveFirewall(server models.Server) error {
+	return Repo.LinodeRemoveFirewall(server)
+}
+
+// newLinodeClient returns a Linode API client authenticated with apiKey
+func newLinodeClient(apiKey string) linodego.Client {
+	tokenSource := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: apiKey})
+
+	oauth2Client := &http.Client{
+		Transport: &oauth2.Transport{
+			Source: tokenSource,
+		},
+	}
+
+	return linodego.NewClient(oauth2Client)
+}
+
+// linodeRootAccess returns the root SSH key and password to install on new and rebuilt instances. Linode
+// requires a password, a random one is used until the server's own credentials are rotated in.
+func (m *Repository) linodeRootAccess() ([]string, string, error) {
+	sshKey, err := m.DB.GetSecret("sshkey")
 	if err != nil {
-		return server, err
+		return nil, "", err
 	}
 
-	sshKey, err := m.DB.GetSecret("publicSSHKey")
+	rootPassword, err := credentials.GeneratePassword()
 	if err != nil {
-		return server, err
+		return nil, "", err
 	}
 
-	sshKeys = append(sshKeys, sshKey)
+	return []string{strings.TrimSpace(sshKey)}, rootPassword, nil
+}
+
+// LinodeCreateServer creates a VPS in Linode based on model
+func (m *Repository) LinodeCreateServer(server models.Server) (models.Server, error) {
+	apiKey, err := m.DB.GetSecret("linode")
+	if err != nil {
+		return server, err
+	}
 
-	rootPassword, err := m.DB.GetSecret("root_password")
+	sshKeys, rootPassword, err := m.linodeRootAccess()
 	if err != nil {
 		return server, err
 	}
@@ -47,45 +168,682 @@ func (m *Repository) LinodeCreateServer(server models.Server) (models.Server, er
 
 	poweredOn := true
 	instanceOptions := linodego.InstanceCreateOptions{
-		Region:         "eu-central",
-		Type:           "g6-nanode-1",
+		Region:         server.Region,
+		Type:           server.Size,
+		Image:          server.Image,
+		Label:          server.Name,
 		AuthorizedKeys: sshKeys,
 		RootPass:       rootPassword,
 		Tags:           Tags,
 		Booted:         &poweredOn,
 	}
 
-	if server.OS == "ubuntu-2204" {
+	if instanceOptions.Region == "" {
+		instanceOptions.Region = "eu-central"
+	}
+
+	if instanceOptions.Type == "" {
+		instanceOptions.Type = "g6-nanode-1"
+	}
+
+	if instanceOptions.Image == "" && server.OS == "ubuntu-2204" {
 		instanceOptions.Image = "linode/ubuntu22.04"
 	}
 
-	returnedServer, err := linodeClient.CreateInstance(ctx, instanceOptions)
+	returnedServer, err := linodeCreateInstance(ctx, linodeClient, instanceOptions, server.UserData)
 	if err != nil {
 		log.Println(err)
 		return server, err
 	}
 
-	for {
+	// Recorded straight away so the instance can still be deleted if it never starts
+	server.ProviderID = strconv.Itoa(returnedServer.ID)
+
+	// Poll until the instance is running, for up to five minutes
+	for attempt := 0; attempt < 60; attempt++ {
 		vps, err := linodeClient.GetInstance(ctx, returnedServer.ID)
 		if err != nil {
 			return server, err
 		}
-		if vps.Status == "running" {
-			server.ProviderID = vps.ID
+		if vps.Status == "running" && len(vps.IPv4) > 0 {
 			server.IP = vps.IPv4[0].String()
+			return server, nil
+		}
+		time.Sleep(5 * time.Second)
+	}
 
-			break
-		} else {
-			time.Sleep(5 * time.Second)
+	return server, fmt.Errorf("linode instance %d did not start in time", returnedServer.ID)
+}
+
+// linodeCreateInstance creates an instance, passing user data in the metadata field which the
+// vendored linodego does not know about yet
+func linodeCreateInstance(ctx context.Context, client linodego.Client, options linodego.InstanceCreateOptions, userData string) (*linodego.Instance, error) {
+	if userData == "" {
+		return client.CreateInstance(ctx, options)
+	}
+
+	type metadata struct {
+		UserData string `json:"user_data"`
+	}
+	body := struct {
+		linodego.InstanceCreateOptions
+		Metadata metadata `json:"metadata"`
+	}{options, metadata{UserData: base64.StdEncoding.EncodeToString([]byte(userData))}}
+
+	endpoint, err := client.Instances.Endpoint()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.R(ctx).SetResult(&linodego.Instance{}).SetBody(body).Post(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		if apiError, ok := resp.Error().(*linodego.APIError); ok && len(apiError.Errors) > 0 {
+			return nil, apiError
 		}
+		return nil, fmt.Errorf("error creating Linode instance: %s", resp.Status())
+	}
+	return resp.Result().(*linodego.Instance), nil
+}
+
+// LinodeCatalog lists the regions, instance types and public images available on Linode
+func (m *Repository) LinodeCatalog() (Catalog, error) {
+	var catalog Catalog
+
+	apiKey, err := m.DB.GetSecret("linode")
+	if err != nil {
+		return catalog, err
 	}
 
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	linodeClient := newLinodeClient(apiKey)
+
+	regions, err := linodeClient.ListRegions(ctx, nil)
+	if err != nil {
+		return catalog, fmt.Errorf("error getting list of regions from linode: %v", err)
+	}
+	for _, region := range regions {
+		if region.Status == "ok" {
+			catalog.Regions = append(catalog.Regions, CatalogItem{Slug: region.ID, Name: fmt.Sprintf("%s (%s)", region.ID, region.Country)})
+		}
+	}
+
+	types, err := linodeClient.ListTypes(ctx, nil)
+	if err != nil {
+		return catalog, fmt.Errorf("error getting list of types from linode: %v", err)
+	}
+	for _, linodeType := range types {
+		item := CatalogItem{Slug: linodeType.ID, Name: linodeType.Label}
+		if linodeType.Price != nil {
+			item.PriceHourly = float64(linodeType.Price.Hourly)
+			item.Name = fmt.Sprintf("%s - $%.2f/mo", linodeType.Label, linodeType.Price.Monthly)
+		}
+		catalog.Sizes = append(catalog.Sizes, item)
+	}
+
+	images, err := linodeClient.ListImages(ctx, nil)
+	if err != nil {
+		return catalog, fmt.Errorf("error getting list of images from linode: %v", err)
+	}
+	for _, image := range images {
+		if image.IsPublic && !image.Deprecated {
+			catalog.Images = append(catalog.Images, CatalogItem{Slug: image.ID, Name: image.Label})
+		} else if !image.IsPublic && image.Status == linodego.ImageStatusAvailable {
+			catalog.Images = append(catalog.Images, CatalogItem{Slug: image.ID, Name: "Snapshot: " + image.Label})
+		}
+	}
+
+	return catalog, nil
+}
+
+// LinodeGetServer fetches the current state of an instance from Linode
+func (m *Repository) LinodeGetServer(server models.Server) (models.Server, error) {
+	apiKey, err := m.DB.GetSecret("linode")
+	if err != nil {
+		return server, err
+	}
+
+	instanceID, err := strconv.Atoi(server.ProviderID)
+	if err != nil {
+		return server, fmt.Errorf("invalid linode instance ID %q: %v", server.ProviderID, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	linodeClient := newLinodeClient(apiKey)
+
+	instance, err := linodeClient.GetInstance(ctx, instanceID)
+	if err != nil {
+		return server, fmt.Errorf("error getting instance from linode: %v", err)
+	}
+
+	if len(instance.IPv4) > 0 {
+		server.IP = instance.IPv4[0].String()
+	}
+	server.Status = string(instance.Status)
 	return server, nil
+}
+
+// LinodePower boots, shuts down or reboots an instance and waits for it to settle
+func (m *Repository) LinodePower(providerID string, action PowerAction) error {
+	instanceID, err := strconv.Atoi(providerID)
+	if err != nil {
+		return fmt.Errorf("invalid linode instance ID %q: %v", providerID, err)
+	}
+
+	apiKey, err := m.DB.GetSecret("linode")
+	if err != nil {
+		return fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	linodeClient := newLinodeClient(apiKey)
+
+	var expected linodego.InstanceStatus
+	switch action {
+	case PowerOn:
+		err = linodeClient.BootInstance(ctx, instanceID, 0)
+		expected = linodego.InstanceRunning
+	case Shutdown:
+		err = linodeClient.ShutdownInstance(ctx, instanceID)
+		expected = linodego.InstanceOffline
+	case Reboot:
+		err = linodeClient.RebootInstance(ctx, instanceID, 0)
+		expected = linodego.InstanceRunning
+	default:
+		return ErrNotSupported
+	}
+	if err != nil {
+		return fmt.Errorf("error performing %s on linode instance: %v", action, err)
+	}
+
+	// A reboot starts and ends in the running state, so give the job time to start before waiting
+	if action == Reboot {
+		time.Sleep(10 * time.Second)
+	}
+
+	if _, err := linodeClient.WaitForInstanceStatus(ctx, instanceID, expected, 600); err != nil {
+		return fmt.Errorf("error waiting for linode instance to be %s: %v", expected, err)
+	}
+	return nil
+}
+
+// LinodeRebuildServer reimages an instance in place with the root SSH key and password and waits for it to boot
+func (m *Repository) LinodeRebuildServer(providerID, image string) error {
+	instanceID, err := strconv.Atoi(providerID)
+	if err != nil {
+		return fmt.Errorf("invalid linode instance ID %q: %v", providerID, err)
+	}
+
+	apiKey, err := m.DB.GetSecret("linode")
+	if err != nil {
+		return fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	sshKeys, rootPassword, err := m.linodeRootAccess()
+	if err != nil {
+		return fmt.Errorf("error getting root access from database: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	linodeClient := newLinodeClient(apiKey)
+
+	poweredOn := true
+	_, err = linodeClient.RebuildInstance(ctx, instanceID, linodego.InstanceRebuildOptions{
+		Image:          image,
+		RootPass:       rootPassword,
+		AuthorizedKeys: sshKeys,
+		Booted:         &poweredOn,
+	})
+	if err != nil {
+		return fmt.Errorf("error rebuilding instance on linode: %v", err)
+	}
+
+	// The rebuild job starts and ends in the running state, so give it time to start before waiting
+	time.Sleep(10 * time.Second)
 
+	if _, err := linodeClient.WaitForInstanceStatus(ctx, instanceID, linodego.InstanceRunning, 1800); err != nil {
+		return fmt.Errorf("error waiting for linode instance to be running: %v", err)
+	}
+	return nil
+}
+
+// LinodeListIPs lists the additional public IPv4 addresses on instances with the application tag,
+// only those in region if it is set. The address each instance was created with is not included.
+func (m *Repository) LinodeListIPs(region string) ([]ReservedIP, error) {
+	var ips []ReservedIP
+
+	apiKey, err := m.DB.GetSecret("linode")
+	if err != nil {
+		return ips, fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	linodeClient := newLinodeClient(apiKey)
+
+	instances, err := linodeClient.ListInstances(ctx, nil)
+	if err != nil {
+		return ips, fmt.Errorf("error getting list of instances from linode: %v", err)
+	}
+
+	primary := make(map[int]string)
+	for _, instance := range instances {
+		if !hasTag(instance.Tags, Tags[0]) {
+			continue
+		}
+		primary[instance.ID] = ""
+		if len(instance.IPv4) > 0 {
+			primary[instance.ID] = instance.IPv4[0].String()
+		}
+	}
+
+	addresses, err := linodeClient.ListIPAddresses(ctx, nil)
+	if err != nil {
+		return ips, fmt.Errorf("error getting list of IP addresses from linode: %v", err)
+	}
+
+	for _, address := range addresses {
+		primaryIP, tagged := primary[address.LinodeID]
+		if !tagged || !address.Public || address.Type != linodego.IPTypeIPv4 || address.Address == primaryIP {
+			continue
+		}
+		if region != "" && address.Region != region {
+			continue
+		}
+		ips = append(ips, ReservedIP{
+			Address:          address.Address,
+			Region:           address.Region,
+			ServerProviderID: strconv.Itoa(address.LinodeID),
+		})
+	}
+	return ips, nil
+}
+
+// LinodeAllocateIP adds an additional public IPv4 address to an instance. Network Helper
+// configures the address inside the instance the next time it boots.
+func (m *Repository) LinodeAllocateIP(providerID string) (ReservedIP, error) {
+	instanceID, err := strconv.Atoi(providerID)
+	if err != nil {
+		return ReservedIP{}, fmt.Errorf("invalid linode instance ID %q: %v", providerID, err)
+	}
+
+	apiKey, err := m.DB.GetSecret("linode")
+	if err != nil {
+		return ReservedIP{}, fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	linodeClient := newLinodeClient(apiKey)
+
+	address, err := linodeClient.AddInstanceIPAddress(ctx, instanceID, true)
+	if err != nil {
+		return ReservedIP{}, fmt.Errorf("error adding IP address on linode: %v", err)
+	}
+
+	return ReservedIP{
+		Address:          address.Address,
+		Region:           address.Region,
+		ServerProviderID: providerID,
+	}, nil
+}
+
+// LinodeAttachIP moves an additional IP address from another instance in the same region to this one
+func (m *Repository) LinodeAttachIP(server models.Server, address string) error {
+	instanceID, err := strconv.Atoi(server.ProviderID)
+	if err != nil {
+		return fmt.Errorf("invalid linode instance ID %q: %v", server.ProviderID, err)
+	}
+
+	apiKey, err := m.DB.GetSecret("linode")
+	if err != nil {
+		return fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	linodeClient := newLinodeClient(apiKey)
+
+	err = linodeClient.InstancesAssignIPs(ctx, linodego.LinodesAssignIPsOptions{
+		Region:      server.Region,
+		Assignments: []linodego.LinodeIPAssignment{{Address: address, LinodeID: instanceID}},
+	})
+	if err != nil {
+		return fmt.Errorf("error assigning IP address on linode: %v", err)
+	}
+	return nil
+}
+
+// LinodeReleaseIP removes an additional IP address from an instance
+func (m *Repository) LinodeReleaseIP(providerID, address string) error {
+	instanceID, err := strconv.Atoi(providerID)
+	if err != nil {
+		return fmt.Errorf("invalid linode instance ID %q: %v", providerID, err)
+	}
+
+	apiKey, err := m.DB.GetSecret("linode")
+	if err != nil {
+		return fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	linodeClient := newLinodeClient(apiKey)
+
+	if err := linodeClient.DeleteInstanceIPAddress(ctx, instanceID, address); err != nil {
+		return fmt.Errorf("error removing IP address on linode: %v", err)
+	}
+	return nil
+}
+
+// linodeFirewall returns the ID of the firewall GoBoxer manages for an instance, or 0 if it has none
+func linodeFirewall(ctx context.Context, linodeClient linodego.Client, server models.Server) (int, error) {
+	firewalls, err := linodeClient.ListFirewalls(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error listing firewalls from linode: %v", err)
+	}
+
+	for _, firewall := range firewalls {
+		if firewall.Label == firewallName(server) {
+			return firewall.ID, nil
+		}
+	}
+	return 0, nil
+}
+
+// LinodeApplyFirewall creates or updates the cloud firewall for an instance, dropping inbound
+// traffic not matched by a rule and allowing all outbound traffic
+func (m *Repository) LinodeApplyFirewall(server models.Server, rules []models.FirewallRule) error {
+	instanceID, err := strconv.Atoi(server.ProviderID)
+	if err != nil {
+		return fmt.Errorf("invalid linode instance ID %q: %v", server.ProviderID, err)
+	}
+
+	apiKey, err := m.DB.GetSecret("linode")
+	if err != nil {
+		return fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	linodeClient := newLinodeClient(apiKey)
+
+	ruleSet := linodego.FirewallRuleSet{
+		Inbound:        []linodego.FirewallRule{},
+		InboundPolicy:  "DROP",
+		Outbound:       []linodego.FirewallRule{},
+		OutboundPolicy: "ACCEPT",
+	}
+	for i, rule := range rules {
+		ipv4, ipv6 := splitSources(rule.Sources)
+		addresses := linodego.NetworkAddresses{}
+		if len(ipv4) > 0 {
+			addresses.IPv4 = &ipv4
+		}
+		if len(ipv6) > 0 {
+			addresses.IPv6 = &ipv6
+		}
+		ruleSet.Inbound = append(ruleSet.Inbound, linodego.FirewallRule{
+			Action:    "ACCEPT",
+			Label:     fmt.Sprintf("rule-%d", i+1),
+			Ports:     rule.Ports,
+			Protocol:  linodego.NetworkProtocol(strings.ToUpper(rule.Protocol)),
+			Addresses: addresses,
+		})
+	}
+
+	firewallID, err := linodeFirewall(ctx, linodeClient, server)
+	if err != nil {
+		return err
+	}
+
+	if firewallID != 0 {
+		_, err = linodeClient.UpdateFirewallRules(ctx, firewallID, ruleSet)
+	} else {
+		_, err = linodeClient.CreateFirewall(ctx, linodego.FirewallCreateOptions{
+			Label:   firewallName(server),
+			Rules:   ruleSet,
+			Tags:    Tags,
+			Devices: linodego.DevicesCreationOptions{Linodes: []int{instanceID}},
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("error applying firewall on linode: %v", err)
+	}
+	return nil
+}
+
+// LinodeRemoveFirewall deletes the cloud firewall for an instance
+func (m *Repository) LinodeRemoveFirewall(server models.Server) error {
+	apiKey, err := m.DB.GetSecret("linode")
+	if err != nil {
+		return fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	linodeClient := newLinodeClient(apiKey)
+
+	firewallID, err := linodeFirewall(ctx, linodeClient, server)
+	if err != nil || firewallID == 0 {
+		return err
+	}
+
+	if err := linodeClient.DeleteFirewall(ctx, firewallID); err != nil {
+		return fmt.Errorf("error deleting firewall on linode: %v", err)
+	}
+	return nil
+}
+
+// linodeSnapshotDescription links an image to the instance it was taken from, as Linode does not record it
+func linodeSnapshotDescription(providerID string) string {
+	return fmt.Sprintf("GoBoxer snapshot of linode %s", providerID)
+}
+
+// LinodeCreateSnapshot creates a private image from the main disk of an instance and waits for it to be available
+func (m *Repository) LinodeCreateSnapshot(providerID, name string) error {
+	instanceID, err := strconv.Atoi(providerID)
+	if err != nil {
+		return fmt.Errorf("invalid linode instance ID %q: %v", providerID, err)
+	}
+
+	apiKey, err := m.DB.GetSecret("linode")
+	if err != nil {
+		return fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	// Images of large disks can take a long time
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+	defer cancel()
+
+	linodeClient := newLinodeClient(apiKey)
+
+	disks, err := linodeClient.ListInstanceDisks(ctx, instanceID, nil)
+	if err != nil {
+		return fmt.Errorf("error getting list of disks from linode: %v", err)
+	}
+
+	diskID := 0
+	for _, disk := range disks {
+		if disk.Filesystem != linodego.FilesystemSwap {
+			diskID = disk.ID
+			break
+		}
+	}
+	if diskID == 0 {
+		return fmt.Errorf("no disk found to snapshot on linode instance %s", providerID)
+	}
+
+	image, err := linodeClient.CreateImage(ctx, linodego.ImageCreateOptions{
+		DiskID:      diskID,
+		Label:       name,
+		Description: linodeSnapshotDescription(providerID),
+	})
+	if err != nil {
+		return fmt.Errorf("error creating image on linode: %v", err)
+	}
+
+	for image.Status != linodego.ImageStatusAvailable {
+		time.Sleep(15 * time.Second)
+
+		image, err = linodeClient.GetImage(ctx, image.ID)
+		if err != nil {
+			return fmt.Errorf("error getting image from linode: %v", err)
+		}
+	}
+	return nil
+}
+
+// LinodeListSnapshots lists the private images taken of an instance
+func (m *Repository) LinodeListSnapshots(providerID string) ([]Snapshot, error) {
+	snapshots := []Snapshot{}
+
+	apiKey, err := m.DB.GetSecret("linode")
+	if err != nil {
+		return snapshots, fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	linodeClient := newLinodeClient(apiKey)
+
+	images, err := linodeClient.ListImages(ctx, nil)
+	if err != nil {
+		return snapshots, fmt.Errorf("error getting list of images from linode: %v", err)
+	}
+
+	for _, image := range images {
+		if image.IsPublic || image.Description != linodeSnapshotDescription(providerID) {
+			continue
+		}
+
+		snapshot := Snapshot{
+			Image:  image.ID,
+			Name:   image.Label,
+			SizeGB: float64(image.Size) / 1024,
+		}
+		if image.Created != nil {
+			snapshot.CreatedAt = *image.Created
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+// LinodeRefreshVPS returns the list of servers currently deployed on Linode with the application tag
+func (m *Repository) LinodeRefreshVPS() ([]models.Server, error) {
+	return m.linodeListInstances(true)
+}
+
+// LinodeListAll returns every instance on the Linode account, tagged or not
+func (m *Repository) LinodeListAll() ([]models.Server, error) {
+	return m.linodeListInstances(false)
+}
+
+// linodeListInstances lists instances on Linode, only those with the application tag if tagged is set
+func (m *Repository) linodeListInstances(tagged bool) ([]models.Server, error) {
+	servers := []models.Server{}
+
+	apiKey, err := m.DB.GetSecret("linode")
+	if err != nil {
+		return servers, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	linodeClient := newLinodeClient(apiKey)
+
+	instances, err := linodeClient.ListInstances(ctx, nil)
+	if err != nil {
+		return servers, fmt.Errorf("error getting list of instances from linode: %v", err)
+	}
+
+	for _, instance := range instances {
+		if tagged && !hasTag(instance.Tags, Tags[0]) {
+			continue
+		}
+
+		server := models.Server{
+			Provider:   "linode",
+			Name:       instance.Label,
+			ProviderID: strconv.Itoa(instance.ID),
+			Region:     instance.Region,
+			Size:       instance.Type,
+			Image:      instance.Image,
+			OS:         instance.Image,
+			Status:     string(instance.Status),
+		}
+		if len(instance.IPv4) > 0 {
+			server.IP = instance.IPv4[0].String()
+		}
+		servers = append(servers, server)
+	}
+
+	return servers, nil
+}
+
+// LinodeTagServer adds the application tag to an existing instance so it is managed by GoBoxer
+func (m *Repository) LinodeTagServer(server models.Server) error {
+	instanceID, err := strconv.Atoi(server.ProviderID)
+	if err != nil {
+		return fmt.Errorf("invalid linode instance ID %q: %v", server.ProviderID, err)
+	}
+
+	apiKey, err := m.DB.GetSecret("linode")
+	if err != nil {
+		return fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	linodeClient := newLinodeClient(apiKey)
+
+	instance, err := linodeClient.GetInstance(ctx, instanceID)
+	if err != nil {
+		return fmt.Errorf("error getting instance from linode: %v", err)
+	}
+
+	if hasTag(instance.Tags, Tags[0]) {
+		return nil
+	}
+
+	tags := append(instance.Tags, Tags[0])
+	if _, err := linodeClient.UpdateInstance(ctx, instanceID, linodego.InstanceUpdateOptions{Tags: &tags}); err != nil {
+		return fmt.Errorf("error tagging instance on linode: %v", err)
+	}
+	return nil
 }
 
 // LinodeDeleteServer destroys a VPS based on serverID
-func (m *Repository) LinodeDeleteServer(serverID int) error {
+func (m *Repository) LinodeDeleteServer(providerID string) error {
+	// Servers that never reached the provider have nothing to delete
+	if providerID == "" {
+		return nil
+	}
+
+	serverID, err := strconv.Atoi(providerID)
+	if err != nil {
+		return fmt.Errorf("invalid linode instance ID %q: %v", providerID, err)
+	}
+
 	apiKey, err := m.DB.GetSecret("linode")
 	if err != nil {
 		return err
@@ -120,7 +878,7 @@ func (m *Repository) LinodeDeleteServer(serverID int) error {
 
 	for _, instance := range instances {
 		for _, tag := range instance.Tags {
-			if tag == Tags[0] {
+			if tag == Tags[0] && instance.ID == serverID {
 				err := linodeClient.DeleteInstance(ctx, serverID)
 				log.Println("Deleting Linode Instance ID:", instance.ID)
 				if err != nil {
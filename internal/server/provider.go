@@ -0,0 +1,185 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
+
+// ErrNotSupported is returned when a provider does not implement an operation
+var ErrNotSupported = errors.New("operation not supported by provider")
+
+// PowerAction is a power operation that can be performed on a server
+type PowerAction string
+
+const (
+	PowerOn  PowerAction = "power_on"
+	Shutdown PowerAction = "shutdown"
+	Reboot   PowerAction = "reboot"
+	Reset    PowerAction = "reset"
+)
+
+// Label is the name of the action shown to users
+func (a PowerAction) Label() string {
+	switch a {
+	case PowerOn:
+		return "Power On"
+	case Shutdown:
+		return "Shut Down"
+	case Reboot:
+		return "Reboot"
+	case Reset:
+		return "Hard Reset"
+	}
+	return string(a)
+}
+
+// InProgressStatus is the server status recorded while the action is running
+func (a PowerAction) InProgressStatus() string {
+	switch a {
+	case PowerOn:
+		return "Powering On"
+	case Shutdown:
+		return "Shutting Down"
+	case Reboot:
+		return "Rebooting"
+	case Reset:
+		return "Resetting"
+	}
+	return string(a)
+}
+
+// Capabilities describes the optional features a provider supports,
+// used by handlers and templates to decide which actions to offer
+type Capabilities struct {
+	PowerActions []PowerAction
+	// UserData is set when the provider passes cloud-init user data to new servers
+	UserData bool
+}
+
+// SupportsPower reports whether the provider can perform a power action
+func (c Capabilities) SupportsPower(action PowerAction) bool {
+	for _, supported := range c.PowerActions {
+		if supported == action {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePowerAction returns the power action for its string form
+func ParsePowerAction(action string) (PowerAction, error) {
+	switch PowerAction(action) {
+	case PowerOn, Shutdown, Reboot, Reset:
+		return PowerAction(action), nil
+	}
+	return "", fmt.Errorf("unknown power action: %s", action)
+}
+
+// Secret describes an API key or credential a provider needs from the secrets table
+type Secret struct {
+	Name  string
+	Label string
+	// Optional secrets are not required for the provider to count as configured
+	Optional bool
+}
+
+// CloudProvider is implemented by every VPS vendor GoBoxer can deploy servers to.
+// Adding a new vendor means adding a file to this package that implements the
+// interface and registers it with RegisterProvider in an init function.
+type CloudProvider interface {
+	// Name is the identifier stored in the provider column of the servers table
+	Name() string
+	// DisplayName is the human readable name shown in the UI
+	DisplayName() string
+	// Secrets lists the secrets that must be set for the provider to be usable
+	Secrets() []Secret
+	Capabilities() Capabilities
+	// Catalog lists the regions, sizes and images available, use GetCatalog for a cached copy
+	Catalog() (Catalog, error)
+
+	CreateServer(server models.Server) (models.Server, error)
+	GetServer(server models.Server) (models.Server, error)
+	ListServers() ([]models.Server, error)
+	DeleteServer(server models.Server) error
+	DeleteAll() error
+	Power(server models.Server, action PowerAction) error
+}
+
+// Importer is implemented by providers which can list every instance on the account,
+// including ones built by hand, and tag an instance once it has been adopted so the
+// tag filtered ListServers and DeleteAll include it from then on
+type Importer interface {
+	ListAllServers() ([]models.Server, error)
+	TagServer(server models.Server) error
+}
+
+// KeyRegistrar is implemented by providers that need the root SSH key
+// registered with their API before servers can be created
+type KeyRegistrar interface {
+	RegisterSSHKey() error
+}
+
+var providers = make(map[string]CloudProvider)
+
+// RegisterProvider adds a provider to the registry, it should be called from init
+func RegisterProvider(p CloudProvider) {
+	if _, exists := providers[p.Name()]; exists {
+		panic(fmt.Sprintf("server provider %s registered twice", p.Name()))
+	}
+	providers[p.Name()] = p
+}
+
+// GetProvider returns the registered provider with the given name
+func GetProvider(name string) (CloudProvider, error) {
+	p, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider: %s", name)
+	}
+	return p, nil
+}
+
+// Providers returns all registered providers sorted by name
+func Providers() []CloudProvider {
+	var list []CloudProvider
+	for _, p := range providers {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name() < list[j].Name()
+	})
+	return list
+}
+
+// ConfiguredProviders returns the providers that have all their secrets set
+func ConfiguredProviders(secrets map[string]string) []CloudProvider {
+	var list []CloudProvider
+	for _, p := range Providers() {
+		if IsConfigured(p, secrets) {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
+// IsConfigured checks that every secret needed by the provider has a value
+func IsConfigured(p CloudProvider, secrets map[string]string) bool {
+	for _, secret := range p.Secrets() {
+		if !secret.Optional && secrets[secret.Name] == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// hasTag reports whether tags contains tag
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
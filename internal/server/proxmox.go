@@ -0,0 +1,525 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
+
+func init() {
+	RegisterProvider(&proxmox{})
+}
+
+// proxmox implements CloudProvider for Proxmox VE by cloning cloud-init template VMs
+type proxmox struct{}
+
+func (p *proxmox) Name() string        { return "proxmox" }
+func (p *proxmox) DisplayName() string { return "Proxmox VE" }
+
+func (p *proxmox) Secrets() []Secret {
+	return []Secret{
+		{Name: "proxmoxurl", Label: "Proxmox URL, e.g. https://pve.example.com:8006"},
+		{Name: "proxmoxtoken", Label: "Proxmox API token, e.g. root@pam!goboxer=UUID"},
+		{Name: "proxmoxinsecure", Label: "Proxmox skip TLS verification (true/false)", Optional: true},
+	}
+}
+
+func (p *proxmox) Capabilities() Capabilities {
+	return Capabilities{}
+}
+
+func (p *proxmox) Catalog() (Catalog, error) {
+	return Repo.ProxmoxCatalog()
+}
+
+func (p *proxmox) CreateServer(server models.Server) (models.Server, error) {
+	return Repo.ProxmoxCreateServer(server)
+}
+
+func (p *proxmox) GetServer(server models.Server) (models.Server, error) {
+	return Repo.ProxmoxGetServer(server)
+}
+
+func (p *proxmox) ListServers() ([]models.Server, error) {
+	return Repo.ProxmoxRefreshVPS()
+}
+
+func (p *proxmox) DeleteServer(server models.Server) error {
+	return Repo.ProxmoxDeleteServer(server.ProviderID)
+}
+
+func (p *proxmox) DeleteAll() error {
+	return Repo.ProxmoxDeleteAll()
+}
+
+func (p *proxmox) Power(server models.Server, action PowerAction) error {
+	return ErrNotSupported
+}
+
+func (p *proxmox) ListAllServers() ([]models.Server, error) {
+	return Repo.ProxmoxListAll()
+}
+
+func (p *proxmox) TagServer(server models.Server) error {
+	return Repo.ProxmoxTagServer(server)
+}
+
+// proxmoxSizes are the CPU and memory settings applied to cloned VMs
+var proxmoxSizes = map[string]struct {
+	Name   string
+	Cores  int
+	Memory int
+}{
+	"small":  {Name: "Small - 1 core, 1 GB", Cores: 1, Memory: 1024},
+	"medium": {Name: "Medium - 2 cores, 2 GB", Cores: 2, Memory: 2048},
+	"large":  {Name: "Large - 4 cores, 4 GB", Cores: 4, Memory: 4096},
+}
+
+// proxmoxResource is a VM as returned by the cluster resources endpoint
+type proxmoxResource struct {
+	VMID     int    `json:"vmid"`
+	Node     string `json:"node"`
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Template int    `json:"template"`
+	Tags     string `json:"tags"`
+}
+
+// proxmoxClient returns an API client for Proxmox VE using the URL and token from the secrets table
+func (m *Repository) proxmoxClient() (*restClient, error) {
+	baseURL, err := m.DB.GetSecret("proxmoxurl")
+	if err != nil {
+		return nil, fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	token, err := m.DB.GetSecret("proxmoxtoken")
+	if err != nil {
+		return nil, fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	if baseURL == "" || token == "" {
+		return nil, errors.New("proxmox URL or API token not found in secrets")
+	}
+
+	client := &restClient{
+		BaseURL: strings.TrimSuffix(baseURL, "/") + "/api2/json",
+		Headers: map[string]string{"Authorization": "PVEAPIToken=" + token},
+	}
+
+	// Self-hosted clusters often use the self-signed certificate generated at install
+	insecure, _ := m.DB.GetSecret("proxmoxinsecure")
+	if skip, _ := strconv.ParseBool(insecure); skip {
+		client.Client = &http.Client{
+			Timeout:   1 * time.Minute,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		}
+	}
+
+	return client, nil
+}
+
+// proxmoxSplitID splits a provider ID of the form node/vmid
+func proxmoxSplitID(providerID string) (string, string, error) {
+	parts := strings.Split(providerID, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid proxmox ID: %s", providerID)
+	}
+	return parts[0], parts[1], nil
+}
+
+// proxmoxWaitTask waits for an asynchronous task to finish and returns an error if it failed
+func proxmoxWaitTask(ctx context.Context, client *restClient, node, upid string) error {
+	for {
+		var result struct {
+			Data struct {
+				Status     string `json:"status"`
+				ExitStatus string `json:"exitstatus"`
+			} `json:"data"`
+		}
+		if err := client.do(ctx, http.MethodGet, "/nodes/"+node+"/tasks/"+url.PathEscape(upid)+"/status", nil, &result); err != nil {
+			return err
+		}
+
+		if result.Data.Status == "stopped" {
+			if result.Data.ExitStatus != "OK" {
+				return fmt.Errorf("task %s failed: %s", upid, result.Data.ExitStatus)
+			}
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// proxmoxTask sends a form request which starts a task and waits for the task to finish
+func proxmoxTask(ctx context.Context, client *restClient, method, node, path string, form url.Values) error {
+	var result struct {
+		Data string `json:"data"`
+	}
+	if err := client.doForm(ctx, method, path, form, &result); err != nil {
+		return err
+	}
+	if result.Data == "" {
+		return nil
+	}
+	return proxmoxWaitTask(ctx, client, node, result.Data)
+}
+
+// proxmoxGuestIP asks the QEMU guest agent for the first non-loopback IPv4 address of a VM
+func proxmoxGuestIP(ctx context.Context, client *restClient, node, vmid string) (string, error) {
+	var result struct {
+		Data struct {
+			Result []struct {
+				Name        string `json:"name"`
+				IPAddresses []struct {
+					Type    string `json:"ip-address-type"`
+					Address string `json:"ip-address"`
+				} `json:"ip-addresses"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/nodes/"+node+"/qemu/"+vmid+"/agent/network-get-interfaces", nil, &result); err != nil {
+		return "", err
+	}
+
+	for _, iface := range result.Data.Result {
+		if iface.Name == "lo" {
+			continue
+		}
+		for _, address := range iface.IPAddresses {
+			if address.Type == "ipv4" && !strings.HasPrefix(address.Address, "127.") {
+				return address.Address, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// proxmoxHasTag reports whether a semicolon separated tag list contains tag
+func proxmoxHasTag(tags, tag string) bool {
+	for _, t := range strings.Split(tags, ";") {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ProxmoxCreateServer clones a cloud-init template onto a node, injects the root SSH key and
+// returns the server object with the IP address reported by the guest agent
+func (m *Repository) ProxmoxCreateServer(server models.Server) (models.Server, error) {
+	client, err := m.proxmoxClient()
+	if err != nil {
+		return server, err
+	}
+
+	if server.Image == "" {
+		return server, errors.New("a proxmox template must be chosen")
+	}
+	templateNode, templateID, err := proxmoxSplitID(server.Image)
+	if err != nil {
+		return server, err
+	}
+
+	node := server.Region
+	if node == "" {
+		node = templateNode
+	}
+
+	sizeSlug := server.Size
+	if sizeSlug == "" {
+		sizeSlug = "small"
+	}
+	size, ok := proxmoxSizes[sizeSlug]
+	if !ok {
+		return server, fmt.Errorf("unsupported size: %s", sizeSlug)
+	}
+
+	publicKey, err := m.DB.GetSecret("sshkey")
+	if err != nil {
+		return server, fmt.Errorf("error getting SSH key from database: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
+	defer cancel()
+
+	var nextID struct {
+		Data string `json:"data"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/cluster/nextid", nil, &nextID); err != nil {
+		return server, fmt.Errorf("error getting next VM ID from proxmox: %v", err)
+	}
+	vmid := nextID.Data
+
+	clone := url.Values{
+		"newid":  {vmid},
+		"name":   {server.Name},
+		"target": {node},
+		"full":   {"1"},
+	}
+	if err := proxmoxTask(ctx, client, http.MethodPost, templateNode, "/nodes/"+templateNode+"/qemu/"+templateID+"/clone", clone); err != nil {
+		return server, fmt.Errorf("error cloning template on proxmox: %v", err)
+	}
+	server.ProviderID = node + "/" + vmid
+
+	// Proxmox expects the sshkeys value to be URL encoded a second time, with spaces as %20
+	config := url.Values{
+		"ciuser":    {"root"},
+		"sshkeys":   {url.PathEscape(strings.TrimSpace(publicKey))},
+		"ipconfig0": {"ip=dhcp"},
+		"agent":     {"1"},
+		"cores":     {strconv.Itoa(size.Cores)},
+		"memory":    {strconv.Itoa(size.Memory)},
+		"tags":      {strings.Join(Tags, ";")},
+	}
+	if err := client.doForm(ctx, http.MethodPut, "/nodes/"+node+"/qemu/"+vmid+"/config", config, nil); err != nil {
+		return server, fmt.Errorf("error configuring VM on proxmox: %v", err)
+	}
+
+	if err := proxmoxTask(ctx, client, http.MethodPost, node, "/nodes/"+node+"/qemu/"+vmid+"/status/start", url.Values{}); err != nil {
+		return server, fmt.Errorf("error starting VM on proxmox: %v", err)
+	}
+
+	// The guest agent only answers once the VM has booted and cloud-init has installed it
+	for attempt := 0; attempt < 60; attempt++ {
+		time.Sleep(5 * time.Second)
+
+		ip, err := proxmoxGuestIP(ctx, client, node, vmid)
+		if err == nil && ip != "" {
+			server.IP = ip
+			server.Region = node
+			return server, nil
+		}
+	}
+
+	return server, errors.New("failed to obtain IP address from the proxmox guest agent")
+}
+
+// ProxmoxCatalog lists the nodes and cloud-init templates available on Proxmox VE
+func (m *Repository) ProxmoxCatalog() (Catalog, error) {
+	var catalog Catalog
+
+	client, err := m.proxmoxClient()
+	if err != nil {
+		return catalog, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	var nodes struct {
+		Data []struct {
+			Node   string `json:"node"`
+			Status string `json:"status"`
+		} `json:"data"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/nodes", nil, &nodes); err != nil {
+		return catalog, fmt.Errorf("error getting list of nodes from proxmox: %v", err)
+	}
+	for _, node := range nodes.Data {
+		if node.Status == "online" {
+			catalog.Regions = append(catalog.Regions, CatalogItem{Slug: node.Node, Name: node.Node})
+		}
+	}
+
+	for _, slug := range []string{"small", "medium", "large"} {
+		catalog.Sizes = append(catalog.Sizes, CatalogItem{Slug: slug, Name: proxmoxSizes[slug].Name})
+	}
+
+	var resources struct {
+		Data []proxmoxResource `json:"data"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/cluster/resources?type=vm", nil, &resources); err != nil {
+		return catalog, fmt.Errorf("error getting list of templates from proxmox: %v", err)
+	}
+	for _, vm := range resources.Data {
+		if vm.Template == 1 {
+			catalog.Images = append(catalog.Images, CatalogItem{
+				Slug: fmt.Sprintf("%s/%d", vm.Node, vm.VMID),
+				Name: vm.Name,
+			})
+		}
+	}
+
+	return catalog, nil
+}
+
+// ProxmoxGetServer fetches the current state of a VM from Proxmox VE
+func (m *Repository) ProxmoxGetServer(server models.Server) (models.Server, error) {
+	client, err := m.proxmoxClient()
+	if err != nil {
+		return server, err
+	}
+
+	node, vmid, err := proxmoxSplitID(server.ProviderID)
+	if err != nil {
+		return server, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	var result struct {
+		Data struct {
+			Status string `json:"status"`
+		} `json:"data"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/nodes/"+node+"/qemu/"+vmid+"/status/current", nil, &result); err != nil {
+		return server, fmt.Errorf("error getting VM from proxmox: %v", err)
+	}
+
+	server.Status = result.Data.Status
+	if server.Status == "running" {
+		if ip, err := proxmoxGuestIP(ctx, client, node, vmid); err == nil && ip != "" {
+			server.IP = ip
+		}
+	}
+	return server, nil
+}
+
+// ProxmoxRefreshVPS returns the VMs on the Proxmox cluster tagged with the application tag
+func (m *Repository) ProxmoxRefreshVPS() ([]models.Server, error) {
+	return m.proxmoxListVMs(true)
+}
+
+// ProxmoxListAll returns every VM on the Proxmox cluster except templates, tagged or not
+func (m *Repository) ProxmoxListAll() ([]models.Server, error) {
+	return m.proxmoxListVMs(false)
+}
+
+// proxmoxListVMs lists VMs on the Proxmox cluster, only those with the application tag if tagged is set
+func (m *Repository) proxmoxListVMs(tagged bool) ([]models.Server, error) {
+	servers := []models.Server{}
+
+	client, err := m.proxmoxClient()
+	if err != nil {
+		return servers, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var resources struct {
+		Data []proxmoxResource `json:"data"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/cluster/resources?type=vm", nil, &resources); err != nil {
+		return servers, fmt.Errorf("error getting list of VMs from proxmox: %v", err)
+	}
+
+	for _, vm := range resources.Data {
+		if vm.Template == 1 || (tagged && !proxmoxHasTag(vm.Tags, Tags[0])) {
+			continue
+		}
+
+		vmid := strconv.Itoa(vm.VMID)
+		vps := models.Server{
+			Provider:   "proxmox",
+			Name:       vm.Name,
+			ProviderID: vm.Node + "/" + vmid,
+			Region:     vm.Node,
+			Status:     vm.Status,
+		}
+		if vm.Status == "running" {
+			if ip, err := proxmoxGuestIP(ctx, client, vm.Node, vmid); err == nil {
+				vps.IP = ip
+			}
+		}
+		servers = append(servers, vps)
+	}
+
+	return servers, nil
+}
+
+// ProxmoxTagServer adds the application tag to an existing VM so it is managed by GoBoxer
+func (m *Repository) ProxmoxTagServer(server models.Server) error {
+	client, err := m.proxmoxClient()
+	if err != nil {
+		return err
+	}
+
+	node, vmid, err := proxmoxSplitID(server.ProviderID)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	var result struct {
+		Data struct {
+			Tags string `json:"tags"`
+		} `json:"data"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/nodes/"+node+"/qemu/"+vmid+"/config", nil, &result); err != nil {
+		return fmt.Errorf("error getting VM config from proxmox: %v", err)
+	}
+
+	if proxmoxHasTag(result.Data.Tags, Tags[0]) {
+		return nil
+	}
+
+	tags := Tags[0]
+	if result.Data.Tags != "" {
+		tags = result.Data.Tags + ";" + tags
+	}
+	if err := client.doForm(ctx, http.MethodPut, "/nodes/"+node+"/qemu/"+vmid+"/config", url.Values{"tags": {tags}}, nil); err != nil {
+		return fmt.Errorf("error tagging VM on proxmox: %v", err)
+	}
+	return nil
+}
+
+// ProxmoxDeleteServer stops a VM and destroys it along with its disks
+func (m *Repository) ProxmoxDeleteServer(providerID string) error {
+	client, err := m.proxmoxClient()
+	if err != nil {
+		return err
+	}
+
+	node, vmid, err := proxmoxSplitID(providerID)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	// Proxmox answers with a server error rather than 404 for a VM deleted outside GoBoxer, which counts as deleted
+	if err := proxmoxTask(ctx, client, http.MethodPost, node, "/nodes/"+node+"/qemu/"+vmid+"/status/stop", url.Values{}); err != nil {
+		if isNotFound(err) || strings.Contains(err.Error(), "does not exist") {
+			return nil
+		}
+		return fmt.Errorf("error stopping VM on proxmox: %v", err)
+	}
+
+	if err := proxmoxTask(ctx, client, http.MethodDelete, node, "/nodes/"+node+"/qemu/"+vmid+"?purge=1&destroy-unreferenced-disks=1", nil); err != nil {
+		return fmt.Errorf("error deleting VM from proxmox: %v", err)
+	}
+	return nil
+}
+
+// ProxmoxDeleteAll deletes all VMs on the Proxmox cluster tagged with the application tag
+func (m *Repository) ProxmoxDeleteAll() error {
+	servers, err := m.ProxmoxRefreshVPS()
+	if err != nil {
+		return err
+	}
+
+	for _, vps := range servers {
+		if err := m.ProxmoxDeleteServer(vps.ProviderID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
+
+// StatusMissing is stored as the server status when the server no longer exists at its provider
+const StatusMissing = "Missing"
+
+// StatusRebuilding is stored as the server status while it is being reimaged
+const StatusRebuilding = "Rebuilding"
+
+// StatusExpired is stored as the server status while an expired server is torn down
+const StatusExpired = "Expired"
+
+// IsRunning reports whether a provider status means the server is up
+func IsRunning(status string) bool {
+	switch strings.ToLower(status) {
+	case "active", "running":
+		return true
+	}
+	return false
+}
+
+// isGone reports whether a provider status means the server has been destroyed
+// but is still listed by the provider for a while
+func isGone(status string) bool {
+	switch strings.ToLower(status) {
+	case "terminated", "shutting-down", "deleting", "destroyed":
+		return true
+	}
+	return false
+}
+
+// isInFlight reports whether a database status means a routine is still working on the server
+func isInFlight(status string) bool {
+	switch status {
+	case "Deploying", "Configuring", "Provisioning", StatusRebuilding, StatusExpired:
+		return true
+	}
+	for _, action := range []PowerAction{PowerOn, Shutdown, Reboot, Reset} {
+		if status == action.InProgressStatus() {
+			return true
+		}
+	}
+	return false
+}
+
+// Reconcile compares the servers a provider reports with the servers recorded in the database
+// for that provider. Database servers are matched on provider ID, falling back to the name for
+// rows created before provider IDs were stored.
+func Reconcile(provider string, live, recorded []models.Server) []models.Drift {
+	var findings []models.Drift
+
+	byID := make(map[string]models.Server)
+	byName := make(map[string]models.Server)
+	for _, vps := range live {
+		if isGone(vps.Status) {
+			continue
+		}
+		byID[vps.ProviderID] = vps
+		byName[vps.Name] = vps
+	}
+
+	matched := make(map[string]bool)
+	for _, dbServer := range recorded {
+		if isInFlight(dbServer.Status) {
+			// Still being created, so it may legitimately be missing at the provider
+			if dbServer.ProviderID != "" {
+				matched[dbServer.ProviderID] = true
+			}
+			continue
+		}
+
+		vps, ok := byID[dbServer.ProviderID]
+		if !ok && dbServer.ProviderID == "" {
+			vps, ok = byName[dbServer.Name]
+		}
+
+		if !ok {
+			findings = append(findings, models.Drift{
+				Kind:       models.DriftGhost,
+				Provider:   provider,
+				ProviderID: dbServer.ProviderID,
+				ServerID:   dbServer.ID,
+				Name:       dbServer.Name,
+				Expected:   dbServer.Status,
+				Actual:     StatusMissing,
+			})
+			continue
+		}
+		matched[vps.ProviderID] = true
+
+		if vps.IP != "" && vps.IP != dbServer.IP {
+			findings = append(findings, models.Drift{
+				Kind:       models.DriftIP,
+				Provider:   provider,
+				ProviderID: vps.ProviderID,
+				ServerID:   dbServer.ID,
+				Name:       dbServer.Name,
+				Expected:   dbServer.IP,
+				Actual:     vps.IP,
+			})
+		}
+
+		if status := ReconciledStatus(dbServer.Status, vps.Status); status != dbServer.Status {
+			findings = append(findings, models.Drift{
+				Kind:       models.DriftStatus,
+				Provider:   provider,
+				ProviderID: vps.ProviderID,
+				ServerID:   dbServer.ID,
+				Name:       dbServer.Name,
+				Expected:   dbServer.Status,
+				Actual:     status,
+			})
+		}
+	}
+
+	for _, vps := range live {
+		if isGone(vps.Status) || matched[vps.ProviderID] {
+			continue
+		}
+		findings = append(findings, models.Drift{
+			Kind:       models.DriftOrphan,
+			Provider:   provider,
+			ProviderID: vps.ProviderID,
+			Name:       vps.Name,
+			Actual:     vps.IP,
+		})
+	}
+
+	return findings
+}
+
+// ReconciledStatus returns the status the database should hold for a server given its provider status.
+// Running servers keep their application status (Ready, ERROR), anything else takes the provider status.
+func ReconciledStatus(current, provider string) string {
+	if !IsRunning(provider) {
+		if provider == "" {
+			return current
+		}
+		return provider
+	}
+	if current == "Ready" || current == "ERROR" {
+		return current
+	}
+	return "Ready"
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
+
+// ReservedIP is a public IPv4 address which can be moved between servers
+type ReservedIP struct {
+	Address string
+	// ID is the provider's identifier for the address where it differs from the address itself
+	ID     string
+	Region string
+	// ServerProviderID is the ProviderID of the server the address is attached to, empty if unattached
+	ServerProviderID string
+}
+
+// IPManager is implemented by providers that offer reserved IPs, so an address which
+// has been burned can be swapped for a fresh one without rebuilding the server
+type IPManager interface {
+	// ListIPs lists the reserved IPs in a region, or in every region if region is empty
+	ListIPs(region string) ([]ReservedIP, error)
+	// AllocateIP reserves a new address and attaches it to the server
+	AllocateIP(server models.Server) (ReservedIP, error)
+	AttachIP(server models.Server, ip ReservedIP) error
+	DetachIP(ip ReservedIP) error
+	ReleaseIP(ip ReservedIP) error
+}
+
+// FindIP returns the reserved IP with the given address
+func FindIP(manager IPManager, region, address string) (ReservedIP, error) {
+	ips, err := manager.ListIPs(region)
+	if err != nil {
+		return ReservedIP{}, err
+	}
+
+	for _, ip := range ips {
+		if ip.Address == address {
+			return ip, nil
+		}
+	}
+	return ReservedIP{}, fmt.Errorf("reserved IP %s not found", address)
+}
+
+// ReleaseServerIP detaches a reserved IP if it is attached to a server, then releases it
+func ReleaseServerIP(manager IPManager, ip ReservedIP) error {
+	if ip.ServerProviderID != "" {
+		// Providers which cannot detach addresses release them straight from the server
+		if err := manager.DetachIP(ip); err != nil && !errors.Is(err, ErrNotSupported) {
+			return err
+		}
+	}
+	return manager.ReleaseIP(ip)
+}
+
+// ErrIPNotReattached is returned by RotateIP when the server was left without its reserved IP
+var ErrIPNotReattached = errors.New("server is no longer on its reserved IP")
+
+// RotateIP attaches a newly allocated reserved IP to the server and releases the reserved IP it
+// was using. If the server was using the address it was created with, that address is left as is.
+func RotateIP(manager IPManager, server models.Server) (ReservedIP, error) {
+	ips, err := manager.ListIPs(server.Region)
+	if err != nil {
+		return ReservedIP{}, err
+	}
+
+	var burned *ReservedIP
+	for i, ip := range ips {
+		if ip.Address == server.IP && ip.ServerProviderID == server.ProviderID {
+			burned = &ips[i]
+			break
+		}
+	}
+
+	// Some providers only allow one reserved IP per server, so free the slot first
+	if burned != nil {
+		if err := manager.DetachIP(*burned); err != nil && !errors.Is(err, ErrNotSupported) {
+			return ReservedIP{}, fmt.Errorf("error detaching %s: %v", burned.Address, err)
+		}
+	}
+
+	fresh, err := manager.AllocateIP(server)
+	if err != nil {
+		// Put the server back on its address, which DNS records and redirectors still point at
+		if burned != nil {
+			if attachErr := manager.AttachIP(server, *burned); attachErr != nil {
+				return ReservedIP{}, fmt.Errorf("%w: error allocating new IP: %v, and error re-attaching %s: %v", ErrIPNotReattached, err, burned.Address, attachErr)
+			}
+		}
+		return ReservedIP{}, fmt.Errorf("error allocating new IP: %v", err)
+	}
+
+	if burned != nil {
+		if err := manager.ReleaseIP(*burned); err != nil {
+			return fresh, fmt.Errorf("new IP %s attached, but error releasing %s: %v", fresh.Address, burned.Address, err)
+		}
+	}
+
+	return fresh, nil
+}
+
+// PreferReservedIPs sets the IP of each live server to the address recorded for it in the database
+// when that address is a reserved IP attached to the server, as providers list the address the
+// server was created with and reconciliation would otherwise report the reserved IP as drift
+func PreferReservedIPs(live, recorded []models.Server, ips []ReservedIP) {
+	attached := make(map[string]bool)
+	for _, ip := range ips {
+		if ip.ServerProviderID != "" {
+			attached[ip.ServerProviderID+"/"+ip.Address] = true
+		}
+	}
+
+	recordedIP := make(map[string]string)
+	for _, dbServer := range recorded {
+		if dbServer.ProviderID != "" {
+			recordedIP[dbServer.ProviderID] = dbServer.IP
+		}
+	}
+
+	for i, vps := range live {
+		ip, ok := recordedIP[vps.ProviderID]
+		if ok && attached[vps.ProviderID+"/"+ip] {
+			live[i].IP = ip
+		}
+	}
+}
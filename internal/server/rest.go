@@ -0,0 +1,107 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// apiError is returned by restClient when the API responds with an error status
+type apiError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+// isNotFound reports whether err is an API response saying the resource does not exist
+func isNotFound(err error) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// restClient is a minimal JSON API client for providers without a vendored SDK
+type restClient struct {
+	BaseURL string
+	Headers map[string]string
+	Client  *http.Client
+}
+
+// do sends a request with body encoded as JSON and decodes the response into out if it is not nil
+func (c *restClient) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.send(req, out)
+}
+
+// doForm sends a request with form encoded as application/x-www-form-urlencoded and decodes
+// the response into out if it is not nil
+func (c *restClient) doForm(ctx context.Context, method, path string, form url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.send(req, out)
+}
+
+// send adds the client headers to req, sends it and decodes the response into out if it is not nil
+func (c *restClient) send(req *http.Request, out interface{}) error {
+	for key, value := range c.Headers {
+		req.Header.Set(key, value)
+	}
+
+	client := c.Client
+	if client == nil {
+		client = &http.Client{Timeout: 1 * time.Minute}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &apiError{
+			StatusCode: resp.StatusCode,
+			Message:    fmt.Sprintf("%s %s returned %s: %s", req.Method, req.URL.Path, resp.Status, bytes.TrimSpace(respBody)),
+		}
+	}
+
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(respBody, out)
+}
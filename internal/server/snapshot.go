@@ -0,0 +1,29 @@
+package server
+
+import (
+	"time"
+
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
+
+// Snapshot is a saved disk image of a server
+type Snapshot struct {
+	// Image is the slug to use as the image when creating a server from the snapshot
+	Image     string
+	Name      string
+	CreatedAt time.Time
+	SizeGB    float64
+}
+
+// Snapshotter is implemented by providers that can snapshot servers. Snapshots are also
+// listed as images in the provider catalog, so servers are created from them like any other image.
+type Snapshotter interface {
+	CreateSnapshot(server models.Server, name string) error
+	ListSnapshots(server models.Server) ([]Snapshot, error)
+}
+
+// Rebuilder is implemented by providers that can reimage a server in place, keeping its
+// IP address so DNS records and redirector origins pointing at it still work
+type Rebuilder interface {
+	RebuildServer(server models.Server, image string) error
+}
@@ -0,0 +1,457 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/nickzer0/GoBoxer/internal/models"
+)
+
+func init() {
+	RegisterProvider(&vultr{})
+}
+
+// vultr implements CloudProvider for Vultr instances
+type vultr struct{}
+
+func (p *vultr) Name() string        { return "vultr" }
+func (p *vultr) DisplayName() string { return "Vultr" }
+
+func (p *vultr) Secrets() []Secret {
+	return []Secret{{Name: "vultr", Label: "Vultr"}}
+}
+
+func (p *vultr) Capabilities() Capabilities {
+	return Capabilities{UserData: true}
+}
+
+func (p *vultr) Catalog() (Catalog, error) {
+	return Repo.VultrCatalog()
+}
+
+func (p *vultr) CreateServer(server models.Server) (models.Server, error) {
+	return Repo.VultrCreateServer(server)
+}
+
+func (p *vultr) GetServer(server models.Server) (models.Server, error) {
+	return Repo.VultrGetServer(server)
+}
+
+func (p *vultr) ListServers() ([]models.Server, error) {
+	return Repo.VultrRefreshVPS()
+}
+
+func (p *vultr) DeleteServer(server models.Server) error {
+	return Repo.VultrDeleteServer(server.ProviderID)
+}
+
+func (p *vultr) DeleteAll() error {
+	return Repo.VultrDeleteAll()
+}
+
+func (p *vultr) Power(server models.Server, action PowerAction) error {
+	return ErrNotSupported
+}
+
+func (p *vultr) RegisterSSHKey() error {
+	return Repo.AddSSHKeyToVultr()
+}
+
+func (p *vultr) ListAllServers() ([]models.Server, error) {
+	return Repo.VultrListAll()
+}
+
+func (p *vultr) TagServer(server models.Server) error {
+	return Repo.VultrTagServer(server)
+}
+
+// vultrUbuntu2204 is the Vultr OS ID for Ubuntu 22.04 x64, used when no image is chosen
+const vultrUbuntu2204 = 1743
+
+// vultrHoursPerMonth is the number of hours Vultr uses to cap monthly billing
+const vultrHoursPerMonth = 730
+
+type vultrInstance struct {
+	ID          string   `json:"id"`
+	Label       string   `json:"label"`
+	MainIP      string   `json:"main_ip"`
+	Status      string   `json:"status"`
+	PowerStatus string   `json:"power_status"`
+	Region      string   `json:"region"`
+	Plan        string   `json:"plan"`
+	OS          string   `json:"os"`
+	Tags        []string `json:"tags"`
+}
+
+type vultrMeta struct {
+	Links struct {
+		Next string `json:"next"`
+	} `json:"links"`
+}
+
+// vultrClient returns an API client for Vultr using the key from the secrets table
+func (m *Repository) vultrClient() (*restClient, error) {
+	apiKey, err := m.DB.GetSecret("vultr")
+	if err != nil {
+		return nil, fmt.Errorf("error getting secrets from database: %v", err)
+	}
+
+	if apiKey == "" {
+		return nil, errors.New("vultr API key not found in secrets")
+	}
+
+	return &restClient{
+		BaseURL: "https://api.vultr.com/v2",
+		Headers: map[string]string{"Authorization": "Bearer " + apiKey},
+	}, nil
+}
+
+// VultrCreateServer creates an instance on Vultr and returns the server object with the IP address
+func (m *Repository) VultrCreateServer(server models.Server) (models.Server, error) {
+	client, err := m.vultrClient()
+	if err != nil {
+		return server, err
+	}
+
+	sshKeyID, err := m.DB.GetSecret("vultrsshkey")
+	if err != nil || sshKeyID == "" {
+		return server, errors.New("root SSH key has not been added to vultr, add it from the settings page")
+	}
+
+	region := server.Region
+	if region == "" {
+		region = "ams"
+	}
+
+	plan := server.Size
+	if plan == "" {
+		plan = "vc2-1c-1gb"
+	}
+
+	osID := vultrUbuntu2204
+	if server.Image != "" {
+		osID, err = strconv.Atoi(server.Image)
+		if err != nil {
+			return server, fmt.Errorf("invalid vultr OS ID %q: %v", server.Image, err)
+		}
+	}
+
+	createRequest := map[string]interface{}{
+		"region":    region,
+		"plan":      plan,
+		"os_id":     osID,
+		"label":     server.Name,
+		"hostname":  server.Name,
+		"sshkey_id": []string{sshKeyID},
+		"tags":      Tags,
+	}
+	// Vultr expects user data base64 encoded
+	if server.UserData != "" {
+		createRequest["user_data"] = base64.StdEncoding.EncodeToString([]byte(server.UserData))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	var created struct {
+		Instance vultrInstance `json:"instance"`
+	}
+	if err := client.do(ctx, http.MethodPost, "/instances", createRequest, &created); err != nil {
+		return server, fmt.Errorf("error creating instance on vultr: %v", err)
+	}
+
+	server.ProviderID = created.Instance.ID
+
+	// Poll until the instance is active and has been given an address
+	for attempt := 0; attempt < 36; attempt++ {
+		time.Sleep(5 * time.Second)
+
+		var result struct {
+			Instance vultrInstance `json:"instance"`
+		}
+		if err := client.do(ctx, http.MethodGet, "/instances/"+server.ProviderID, nil, &result); err != nil {
+			return server, fmt.Errorf("error getting instance from vultr: %v", err)
+		}
+		if result.Instance.Status == "active" && result.Instance.MainIP != "" && result.Instance.MainIP != "0.0.0.0" {
+			server.IP = result.Instance.MainIP
+			return server, nil
+		}
+	}
+
+	return server, errors.New("failed to obtain public IP address for the vultr instance")
+}
+
+// VultrCatalog lists the regions, plans and operating systems available on Vultr
+func (m *Repository) VultrCatalog() (Catalog, error) {
+	var catalog Catalog
+
+	client, err := m.vultrClient()
+	if err != nil {
+		return catalog, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	var regions struct {
+		Regions []struct {
+			ID      string `json:"id"`
+			City    string `json:"city"`
+			Country string `json:"country"`
+		} `json:"regions"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/regions?per_page=500", nil, &regions); err != nil {
+		return catalog, fmt.Errorf("error getting list of regions from vultr: %v", err)
+	}
+	for _, region := range regions.Regions {
+		catalog.Regions = append(catalog.Regions, CatalogItem{Slug: region.ID, Name: fmt.Sprintf("%s, %s", region.City, region.Country)})
+	}
+
+	var plans struct {
+		Plans []struct {
+			ID          string  `json:"id"`
+			VCPUCount   int     `json:"vcpu_count"`
+			RAM         int     `json:"ram"`
+			MonthlyCost float64 `json:"monthly_cost"`
+		} `json:"plans"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/plans?type=vc2&per_page=500", nil, &plans); err != nil {
+		return catalog, fmt.Errorf("error getting list of plans from vultr: %v", err)
+	}
+	for _, plan := range plans.Plans {
+		catalog.Sizes = append(catalog.Sizes, CatalogItem{
+			Slug:        plan.ID,
+			Name:        fmt.Sprintf("%s - %d vCPU, %d MB, $%.2f/mo", plan.ID, plan.VCPUCount, plan.RAM, plan.MonthlyCost),
+			PriceHourly: plan.MonthlyCost / vultrHoursPerMonth,
+		})
+	}
+
+	var operatingSystems struct {
+		OS []struct {
+			ID     int    `json:"id"`
+			Name   string `json:"name"`
+			Family string `json:"family"`
+		} `json:"os"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/os?per_page=500", nil, &operatingSystems); err != nil {
+		return catalog, fmt.Errorf("error getting list of operating systems from vultr: %v", err)
+	}
+	for _, os := range operatingSystems.OS {
+		// Skip marketplace apps, snapshots and ISO placeholders which are not plain images
+		if os.Family == "application" || os.Family == "snapshot" || os.Family == "iso" || os.Family == "backup" {
+			continue
+		}
+		catalog.Images = append(catalog.Images, CatalogItem{Slug: strconv.Itoa(os.ID), Name: os.Name})
+	}
+
+	return catalog, nil
+}
+
+// VultrGetServer fetches the current state of an instance from Vultr
+func (m *Repository) VultrGetServer(server models.Server) (models.Server, error) {
+	client, err := m.vultrClient()
+	if err != nil {
+		return server, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	var result struct {
+		Instance vultrInstance `json:"instance"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/instances/"+server.ProviderID, nil, &result); err != nil {
+		return server, fmt.Errorf("error getting instance from vultr: %v", err)
+	}
+
+	if result.Instance.MainIP != "" && result.Instance.MainIP != "0.0.0.0" {
+		server.IP = result.Instance.MainIP
+	}
+	server.Status = vultrStatus(result.Instance)
+	return server, nil
+}
+
+// VultrRefreshVPS returns the list of instances on Vultr tagged with the application tag
+func (m *Repository) VultrRefreshVPS() ([]models.Server, error) {
+	return m.vultrListInstances(true)
+}
+
+// VultrListAll returns every instance on the Vultr account, tagged or not
+func (m *Repository) VultrListAll() ([]models.Server, error) {
+	return m.vultrListInstances(false)
+}
+
+// vultrListInstances lists instances on Vultr, only those with the application tag if tagged is set
+func (m *Repository) vultrListInstances(tagged bool) ([]models.Server, error) {
+	servers := []models.Server{}
+
+	client, err := m.vultrClient()
+	if err != nil {
+		return servers, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	cursor := ""
+	for {
+		var result struct {
+			Instances []vultrInstance `json:"instances"`
+			Meta      vultrMeta       `json:"meta"`
+		}
+		path := fmt.Sprintf("/instances?per_page=100&cursor=%s", url.QueryEscape(cursor))
+		if tagged {
+			path += "&tag=" + url.QueryEscape(Tags[0])
+		}
+		if err := client.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+			return servers, fmt.Errorf("error getting list of instances from vultr: %v", err)
+		}
+
+		for _, instance := range result.Instances {
+			servers = append(servers, models.Server{
+				Provider:   "vultr",
+				Name:       instance.Label,
+				ProviderID: instance.ID,
+				IP:         instance.MainIP,
+				Region:     instance.Region,
+				Size:       instance.Plan,
+				OS:         instance.OS,
+				Status:     vultrStatus(instance),
+			})
+		}
+
+		cursor = result.Meta.Links.Next
+		if cursor == "" {
+			break
+		}
+	}
+
+	return servers, nil
+}
+
+// VultrTagServer adds the application tag to an existing instance so it is managed by GoBoxer
+func (m *Repository) VultrTagServer(server models.Server) error {
+	client, err := m.vultrClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	var result struct {
+		Instance vultrInstance `json:"instance"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/instances/"+server.ProviderID, nil, &result); err != nil {
+		return fmt.Errorf("error getting instance from vultr: %v", err)
+	}
+
+	if hasTag(result.Instance.Tags, Tags[0]) {
+		return nil
+	}
+
+	updateRequest := map[string]interface{}{"tags": append(result.Instance.Tags, Tags[0])}
+	if err := client.do(ctx, http.MethodPatch, "/instances/"+server.ProviderID, updateRequest, nil); err != nil {
+		return fmt.Errorf("error tagging instance on vultr: %v", err)
+	}
+	return nil
+}
+
+// vultrStatus combines the instance and power status into a single status
+func vultrStatus(instance vultrInstance) string {
+	if instance.Status != "active" {
+		return instance.Status
+	}
+	return instance.PowerStatus
+}
+
+// VultrDeleteServer deletes an instance from Vultr based on its provider ID
+func (m *Repository) VultrDeleteServer(providerID string) error {
+	client, err := m.vultrClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	// An instance already deleted outside GoBoxer counts as deleted
+	if err := client.do(ctx, http.MethodDelete, "/instances/"+providerID, nil, nil); err != nil && !isNotFound(err) {
+		return fmt.Errorf("error deleting instance from vultr: %v", err)
+	}
+	return nil
+}
+
+// VultrDeleteAll deletes all instances on Vultr tagged with the application tag
+func (m *Repository) VultrDeleteAll() error {
+	servers, err := m.VultrRefreshVPS()
+	if err != nil {
+		return err
+	}
+
+	for _, vps := range servers {
+		if err := m.VultrDeleteServer(vps.ProviderID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddSSHKeyToVultr removes the old root SSH key from Vultr, adds the current one and stores its ID
+func (m *Repository) AddSSHKeyToVultr() error {
+	client, err := m.vultrClient()
+	if err != nil {
+		return err
+	}
+
+	publicKey, err := m.DB.GetSecret("sshkey")
+	if err != nil {
+		return fmt.Errorf("error getting SSH key from database: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	var keys struct {
+		SSHKeys []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"ssh_keys"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/ssh-keys?per_page=500", nil, &keys); err != nil {
+		return fmt.Errorf("error getting list of keys from vultr: %v", err)
+	}
+
+	for _, key := range keys.SSHKeys {
+		if key.Name == "Root Key" {
+			if err := client.do(ctx, http.MethodDelete, "/ssh-keys/"+key.ID, nil, nil); err != nil {
+				return fmt.Errorf("error deleting key from vultr: %v", err)
+			}
+		}
+	}
+
+	createRequest := map[string]string{
+		"name":    "Root Key",
+		"ssh_key": publicKey,
+	}
+
+	var created struct {
+		SSHKey struct {
+			ID string `json:"id"`
+		} `json:"ssh_key"`
+	}
+	if err := client.do(ctx, http.MethodPost, "/ssh-keys", createRequest, &created); err != nil {
+		return fmt.Errorf("error adding SSH key to vultr: %v", err)
+	}
+
+	if err := m.DB.UpdateSecret("vultrsshkey", created.SSHKey.ID); err != nil {
+		return fmt.Errorf("error adding SSH key ID to database: %v", err)
+	}
+	return nil
+}
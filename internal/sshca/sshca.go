@@ -0,0 +1,115 @@
+package sshca
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// MaxValidity is the longest a certificate may be issued for, access lapses at most this long after
+// someone is removed from a project
+const MaxValidity = 24 * time.Hour
+
+// clockSkew backdates certificates so servers with a slightly slow clock still accept them
+const clockSkew = 5 * time.Minute
+
+// CA is the certificate authority's key pair, in plain text
+type CA struct {
+	PrivateKey string
+	PublicKey  string
+}
+
+// Generate creates a new certificate authority key pair
+func Generate() (CA, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return CA{}, fmt.Errorf("error generating CA key: %v", err)
+	}
+
+	block, err := ssh.MarshalPrivateKey(privateKey, "goboxer-ca")
+	if err != nil {
+		return CA{}, fmt.Errorf("error encoding CA private key: %v", err)
+	}
+
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		return CA{}, fmt.Errorf("error encoding CA public key: %v", err)
+	}
+
+	return CA{
+		PrivateKey: string(pem.EncodeToMemory(block)),
+		PublicKey:  strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPublicKey))) + " goboxer-ca",
+	}, nil
+}
+
+// Principal is the certificate principal which servers in a project accept for root
+func Principal(project int) string {
+	return fmt.Sprintf("project-%d", project)
+}
+
+// Request describes the certificate an operator asked for
+type Request struct {
+	// PublicKey is the operator's key in authorized_keys format
+	PublicKey string
+	// KeyID identifies the operator in the servers' auth logs
+	KeyID    string
+	Project  int
+	Validity time.Duration
+}
+
+// Sign issues a user certificate for the request, valid for root on the project's servers only
+func Sign(caPrivateKey string, request Request) (string, time.Time, error) {
+	if request.Validity <= 0 || request.Validity > MaxValidity {
+		return "", time.Time{}, fmt.Errorf("validity must be between 1 second and %s", MaxValidity)
+	}
+
+	signer, err := ssh.ParsePrivateKey([]byte(caPrivateKey))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("error reading CA private key: %v", err)
+	}
+
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(strings.TrimSpace(request.PublicKey)))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("SSH key is not valid: %v", err)
+	}
+	if _, ok := publicKey.(*ssh.Certificate); ok {
+		return "", time.Time{}, fmt.Errorf("SSH key is already a certificate, use the public key instead")
+	}
+
+	var serial [8]byte
+	if _, err := rand.Read(serial[:]); err != nil {
+		return "", time.Time{}, fmt.Errorf("error generating serial: %v", err)
+	}
+
+	now := time.Now()
+	validBefore := now.Add(request.Validity)
+	certificate := &ssh.Certificate{
+		Key:             publicKey,
+		Serial:          binary.BigEndian.Uint64(serial[:]),
+		CertType:        ssh.UserCert,
+		KeyId:           request.KeyID,
+		ValidPrincipals: []string{Principal(request.Project)},
+		ValidAfter:      uint64(now.Add(-clockSkew).Unix()),
+		ValidBefore:     uint64(validBefore.Unix()),
+		Permissions: ssh.Permissions{
+			Extensions: map[string]string{
+				"permit-pty":              "",
+				"permit-port-forwarding":  "",
+				"permit-agent-forwarding": "",
+				"permit-X11-forwarding":   "",
+				"permit-user-rc":          "",
+			},
+		},
+	}
+	if err := certificate.SignCert(rand.Reader, signer); err != nil {
+		return "", time.Time{}, fmt.Errorf("error signing certificate: %v", err)
+	}
+
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(certificate))) + " " + request.KeyID, validBefore, nil
+}